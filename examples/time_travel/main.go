@@ -4,7 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"time"
+	"maps"
+	"slices"
 
 	"github.com/smallnest/langgraphgo/graph"
 )
@@ -16,16 +17,16 @@ func main() {
 	// 1. Initial State Node
 	g.AddNode("A", "A", func(ctx context.Context, state map[string]any) (map[string]any, error) {
 		fmt.Println("Executing Node A")
-		state["trace"] = []string{"A"}
-		return state, nil
+		// Return a new map: checkpoints keep the state a node returned
+		return map[string]any{"input": state["input"], "trace": []string{"A"}}, nil
 	})
 
 	// 2. Second Node
 	g.AddNode("B", "B", func(ctx context.Context, state map[string]any) (map[string]any, error) {
 		fmt.Println("Executing Node B")
-		trace := state["trace"].([]string)
-		state["trace"] = append(trace, "B")
-		return state, nil
+		next := maps.Clone(state)
+		next["trace"] = append(slices.Clone(state["trace"].([]string)), "B")
+		return next, nil
 	})
 
 	g.SetEntryPoint("A")
@@ -45,69 +46,62 @@ func main() {
 	}
 
 	ctx := context.Background()
+	threadID := "time-travel-demo"
 
 	// Run first time
 	fmt.Println("--- First Run ---")
-	res, err := runnable.Invoke(ctx, map[string]any{"input": "start"})
+	res, err := runnable.InvokeWithConfig(ctx, map[string]any{"input": "start"}, graph.WithThreadID(threadID))
 	if err != nil {
 		log.Fatal(err)
 	}
 	fmt.Printf("Result 1: %v\n", res)
 
-	// Wait for async saves
-	time.Sleep(100 * time.Millisecond)
-
-	// List checkpoints
-	checkpoints, _ := runnable.ListCheckpoints(ctx)
-	if len(checkpoints) == 0 {
-		log.Fatal("No checkpoints found")
+	// Walk the history of the thread, newest first
+	history, err := runnable.GetStateHistory(ctx, graph.WithThreadID(threadID), nil, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("\n--- History ---")
+	for _, snapshot := range history {
+		fmt.Printf("%v after %v (parent %q): %v\n",
+			snapshot.Config.Configurable["checkpoint_id"], snapshot.Next, snapshot.ParentID, snapshot.Values)
 	}
 
-	// "Time Travel": Load a previous checkpoint (e.g. after Node A)
-	// We want to find the checkpoint where NodeName is "A"
-	var targetCP *graph.Checkpoint
-	for _, cp := range checkpoints {
-		if cp.NodeName == "A" {
-			targetCP = cp
+	// "Time Travel": find the checkpoint saved after Node A
+	var target *graph.StateSnapshot
+	for _, snapshot := range history {
+		if len(snapshot.Next) > 0 && snapshot.Next[0] == "A" {
+			target = snapshot
 			break
 		}
 	}
+	if target == nil {
+		log.Fatal("No checkpoint found after Node A")
+	}
 
-	if targetCP != nil {
-		fmt.Println("\n--- Time Travel (Resuming from Node A) ---")
-		// Resume execution from this checkpoint
-		// ResumeFromCheckpoint loads the state.
-		// To continue execution, we usually need to know where to go next.
-		// Or if we just want to inspect state.
-		// If we want to branch off:
-		// We use InvokeWithConfig with ResumeFrom (this logic is app specific usually)
-
-		// But here let's just inspect
-		loadedState, err := runnable.LoadCheckpoint(ctx, targetCP.ID)
-		if err != nil {
-			log.Fatal(err)
-		}
-		fmt.Printf("Traveled back to state after Node A: %v\n", loadedState.State)
-
-		// Branch off: Run Node B again but with modified state?
-		// Or just re-run B.
-		config := &graph.Config{
-			Configurable: map[string]any{
-				"thread_id":     runnable.GetExecutionID(),
-				"checkpoint_id": targetCP.ID,
-			},
-			ResumeFrom: []string{"B"},
-		}
-
-		// Let's modify state "in place" conceptually (forking)
-		// by passing modified state to Invoke
-		forkedState := loadedState.State.(map[string]any)
-		forkedState["forked"] = true
+	fmt.Println("\n--- Time Travel (Forking after Node A) ---")
+	fmt.Printf("Traveled back to state after Node A: %v\n", target.Values)
+
+	// Fork: invoking with the checkpoint_id starts a new branch from that
+	// checkpoint, with a modified state, and keeps the original history
+	forkedState := maps.Clone(target.Values.(map[string]any))
+	forkedState["forked"] = true
+	config := &graph.Config{
+		Configurable: map[string]any{
+			"thread_id":     threadID,
+			"checkpoint_id": target.Config.Configurable["checkpoint_id"],
+		},
+		ResumeFrom: []string{"B"},
+	}
+	resFork, err := runnable.InvokeWithConfig(ctx, forkedState, config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Forked Result: %v\n", resFork)
 
-		resFork, err := runnable.InvokeWithConfig(ctx, forkedState, config)
-		if err != nil {
-			log.Fatal(err)
-		}
-		fmt.Printf("Forked Result: %v\n", resFork)
+	history, err = runnable.GetStateHistory(ctx, graph.WithThreadID(threadID), nil, 0)
+	if err != nil {
+		log.Fatal(err)
 	}
+	fmt.Printf("The thread now has %d checkpoints: both branches are kept\n", len(history))
 }
@@ -36,6 +36,63 @@ type GraphCallbackHandler interface {
 	OnGraphStep(ctx context.Context, stepNode string, state any)
 }
 
+// Superstep describes a completed superstep of a run
+type Superstep struct {
+	// Step is the number of the superstep in the run, starting at 1
+	Step int
+	// Nodes are the nodes that ran
+	Nodes []string
+	// Next are the nodes that run next, after conditional routing and Commands,
+	// or none once the graph has finished
+	Next []string
+	// Sends are the Sends that run next, alongside Next
+	Sends []Send
+	// Updates are the updates merged into the state, in the order they were
+	// merged: what the nodes returned, or the Update of the Commands they returned
+	Updates []any
+	// Joins are the sources of join edges that finished since their target last
+	// ran, by target, see AddJoinEdge
+	Joins map[string][]string
+}
+
+// SuperstepCallbackHandler extends GraphCallbackHandler with the superstep number
+// and the nodes that run next. Handlers implementing it are notified with
+// OnSuperstep instead of OnGraphStep.
+type SuperstepCallbackHandler interface {
+	GraphCallbackHandler
+	// OnSuperstep is called after a superstep is completed, with the merged state
+	OnSuperstep(ctx context.Context, step Superstep, state any)
+}
+
+// RetryCallbackHandler extends CallbackHandler with node retry notifications
+type RetryCallbackHandler interface {
+	CallbackHandler
+	// OnNodeRetry is called after attempt failed with err, before the node is retried after delay
+	OnNodeRetry(ctx context.Context, nodeName string, attempt int, err error, delay time.Duration)
+}
+
+// PendingWrite is the output of a node that succeeded in a superstep where
+// another node failed.
+type PendingWrite struct {
+	// Node is the name of the node
+	Node string
+	// Value is what the node returned: a state update or a *Command
+	Value any
+}
+
+// PendingWritesCallbackHandler extends CallbackHandler with the outputs of the
+// nodes of a failed superstep that succeeded
+type PendingWritesCallbackHandler interface {
+	CallbackHandler
+	// OnPendingWrites is called when a superstep failed with err. step.Nodes are
+	// its nodes, and step.Next and step.Sends what a resumed run starts with: those
+	// nodes and the deferred nodes waiting for them, and the Sends it ran. state is
+	// the state the superstep started from, and writes the outputs of the nodes
+	// that succeeded, which a resumed run reuses instead of running those nodes
+	// again.
+	OnPendingWrites(ctx context.Context, step Superstep, state any, writes []PendingWrite, err error)
+}
+
 // Config represents configuration for graph invocation
 // This matches Python's config dict pattern
 type Config struct {
@@ -68,6 +125,10 @@ type Config struct {
 
 	// ResumeValue provides the value to return from an Interrupt() call when resuming
 	ResumeValue any `json:"resume_value"`
+
+	// RecursionLimit is the maximum number of supersteps before execution is aborted
+	// with a GraphRecursionError. Zero means DefaultRecursionLimit.
+	RecursionLimit int `json:"recursion_limit"`
 }
 
 // NoOpCallbackHandler provides a no-op implementation of CallbackHandler
@@ -0,0 +1,208 @@
+package graph
+
+import (
+	"reflect"
+	"slices"
+)
+
+// ChannelSchema is a StateSchema that tracks the state as a set of channels:
+// the keys of a map state or the fields of a struct state.
+//
+// With a ChannelSchema, nodes may return partial updates containing only the
+// channels they touched. The runtime keeps a version counter per channel and
+// bumps it whenever an update changes the channel's value, which lets nodes
+// registered with WithTriggers run again only when their inputs changed.
+//
+// MapSchema, StructSchema and FieldMerger implement ChannelSchema.
+type ChannelSchema[S any] interface {
+	StateSchema[S]
+
+	// UpdateChannels merges the update into the current state and returns the
+	// names of the channels whose values changed.
+	UpdateChannels(current, update S) (S, []string, error)
+}
+
+// WithTriggers subscribes a node to the given channels. Once the node has run,
+// it is only scheduled again when at least one of these channels was changed
+// since its previous run; otherwise the branch that routed to it stops there.
+// Triggers require a ChannelSchema on the graph and are ignored otherwise.
+//
+// Example:
+//
+//	schema := graph.NewMapSchema()
+//	schema.RegisterReducer("docs", graph.AppendReducer)
+//	g.SetSchema(schema)
+//
+//	// "summarize" only reruns when new documents arrive
+//	g.AddNode("summarize", "Summarize documents", summarize, graph.WithTriggers("docs"))
+func WithTriggers(channels ...string) NodeOption {
+	return func(o *NodeOptions) {
+		o.Triggers = channels
+	}
+}
+
+// channelVersions tracks the version counter of every channel during a run.
+type channelVersions map[string]int
+
+// bump increments the version of every channel in channels.
+func (cv channelVersions) bump(channels []string) {
+	for _, ch := range channels {
+		cv[ch]++
+	}
+}
+
+// snapshot returns the current versions of the given channels.
+func (cv channelVersions) snapshot(channels []string) map[string]int {
+	seen := make(map[string]int, len(channels))
+	for _, ch := range channels {
+		seen[ch] = cv[ch]
+	}
+	return seen
+}
+
+// changedSince reports whether any channel has a newer version than in seen.
+func (cv channelVersions) changedSince(seen map[string]int) bool {
+	for ch, version := range seen {
+		if cv[ch] > version {
+			return true
+		}
+	}
+	return false
+}
+
+// diffChannels returns the names of the channels that differ between two states.
+// Map states are compared key by key and struct states field by field.
+func diffChannels(before, after any) []string {
+	beforeVal := reflect.ValueOf(before)
+	afterVal := reflect.ValueOf(after)
+
+	if !beforeVal.IsValid() || !afterVal.IsValid() || beforeVal.Type() != afterVal.Type() {
+		return nil
+	}
+
+	var changed []string
+	switch afterVal.Kind() {
+	case reflect.Map:
+		if afterVal.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		keys := make(map[string]bool)
+		for _, k := range beforeVal.MapKeys() {
+			keys[k.String()] = true
+		}
+		for _, k := range afterVal.MapKeys() {
+			keys[k.String()] = true
+		}
+		for k := range keys {
+			key := reflect.ValueOf(k).Convert(afterVal.Type().Key())
+			b := beforeVal.MapIndex(key)
+			a := afterVal.MapIndex(key)
+			if b.IsValid() != a.IsValid() || (a.IsValid() && !reflect.DeepEqual(b.Interface(), a.Interface())) {
+				changed = append(changed, k)
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < afterVal.NumField(); i++ {
+			field := afterVal.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if !reflect.DeepEqual(beforeVal.Field(i).Interface(), afterVal.Field(i).Interface()) {
+				changed = append(changed, field.Name)
+			}
+		}
+	}
+
+	slices.Sort(changed)
+	return changed
+}
+
+// copyState returns a shallow copy of a map state, so that the channels that
+// nodes change in place can be found once they ran. Other states are returned
+// as they are: nodes receive a copy of struct states.
+func copyState[S any](state S) S {
+	v := reflect.ValueOf(state)
+	if v.Kind() != reflect.Map || v.IsNil() {
+		return state
+	}
+	c := reflect.MakeMapWithSize(v.Type(), v.Len())
+	for iter := v.MapRange(); iter.Next(); {
+		c.SetMapIndex(iter.Key(), iter.Value())
+	}
+	return c.Interface().(S)
+}
+
+// changedInPlace reports whether a node returned the map state it received,
+// after changing it in place.
+func changedInPlace(state, update any) bool {
+	return reflect.ValueOf(state).Kind() == reflect.Map && sameValue(state, update)
+}
+
+// writtenChannels returns the names of the channels an update writes relative to
+// the state it was computed from. Map keys missing from the update and zero-valued
+// struct fields are treated as untouched, matching how partial updates are merged.
+func writtenChannels(before, update any) []string {
+	beforeVal := reflect.ValueOf(before)
+	updateVal := reflect.ValueOf(update)
+
+	if !updateVal.IsValid() || (beforeVal.IsValid() && beforeVal.Type() != updateVal.Type()) {
+		return nil
+	}
+
+	var written []string
+	switch updateVal.Kind() {
+	case reflect.Map:
+		if updateVal.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		for _, k := range updateVal.MapKeys() {
+			a := updateVal.MapIndex(k)
+			var b reflect.Value
+			if beforeVal.IsValid() && !beforeVal.IsNil() {
+				b = beforeVal.MapIndex(k)
+			}
+			if !b.IsValid() || !reflect.DeepEqual(b.Interface(), a.Interface()) {
+				written = append(written, k.String())
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < updateVal.NumField(); i++ {
+			field := updateVal.Type().Field(i)
+			if !field.IsExported() || updateVal.Field(i).IsZero() {
+				continue
+			}
+			if !beforeVal.IsValid() || !reflect.DeepEqual(beforeVal.Field(i).Interface(), updateVal.Field(i).Interface()) {
+				written = append(written, field.Name)
+			}
+		}
+	}
+
+	slices.Sort(written)
+	return written
+}
+
+// channelValue returns the value of a single channel of a map or struct state.
+func channelValue(state any, channel string) any {
+	v := reflect.ValueOf(state)
+	switch v.Kind() {
+	case reflect.Map:
+		if val := v.MapIndex(reflect.ValueOf(channel).Convert(v.Type().Key())); val.IsValid() {
+			return val.Interface()
+		}
+	case reflect.Struct:
+		if f := v.FieldByName(channel); f.IsValid() {
+			return f.Interface()
+		}
+	}
+	return nil
+}
+
+// stateDelta returns the channels an update writes relative to the state it was
+// computed from, mapped to their new values.
+func stateDelta(before, update any) map[string]any {
+	delta := make(map[string]any)
+	for _, ch := range writtenChannels(before, update) {
+		delta[ch] = channelValue(update, ch)
+	}
+	return delta
+}
@@ -0,0 +1,121 @@
+package graph
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapSchema_UpdateChannels(t *testing.T) {
+	schema := NewMapSchema()
+	schema.RegisterReducer("logs", AppendReducer)
+
+	current := map[string]any{"count": 1, "name": "a", "logs": []string{"x"}}
+
+	// Partial update touching only some keys
+	result, changed, err := schema.UpdateChannels(current, map[string]any{
+		"count": 2,
+		"name":  "a",
+		"logs":  []string{"y"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"count", "logs"}, changed)
+	assert.Equal(t, 2, result["count"])
+	assert.Equal(t, "a", result["name"])
+	assert.Equal(t, []string{"x", "y"}, result["logs"])
+
+	// The original state is untouched
+	assert.Equal(t, 1, current["count"])
+}
+
+func TestFieldMerger_UpdateChannels(t *testing.T) {
+	fm := NewFieldMerger(SchemaTestState{})
+	fm.RegisterFieldMerge("Logs", AppendSliceMerge)
+
+	current := SchemaTestState{Count: 1, Name: "a", Logs: []string{"x"}}
+	result, changed, err := fm.UpdateChannels(current, SchemaTestState{Logs: []string{"y"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Logs"}, changed)
+	assert.Equal(t, 1, result.Count)
+	assert.Equal(t, []string{"x", "y"}, result.Logs)
+}
+
+func TestStateGraph_ChannelTriggers(t *testing.T) {
+	schema := NewMapSchema()
+	schema.RegisterReducer("docs", AppendReducer)
+
+	g := NewStateGraph[map[string]any]()
+	g.SetSchema(schema)
+
+	fetches := 0
+	summaries := 0
+
+	// fetch produces a document on its first two runs only
+	g.AddNode("fetch", "Fetch documents", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		fetches++
+		if fetches <= 2 {
+			return map[string]any{"docs": []string{"doc"}}, nil
+		}
+		return map[string]any{"polls": fetches}, nil
+	})
+	g.AddNode("summarize", "Summarize documents", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		summaries++
+		return map[string]any{"summary": len(state["docs"].([]string))}, nil
+	}, WithTriggers("docs"))
+
+	g.SetEntryPoint("fetch")
+	g.AddEdge("fetch", "summarize")
+	g.AddEdge("summarize", "fetch")
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	result, err := runnable.Invoke(context.Background(), map[string]any{})
+	assert.NoError(t, err)
+
+	// The third fetch wrote no document, so summarize was not re-triggered and the loop ended
+	assert.Equal(t, 3, fetches)
+	assert.Equal(t, 2, summaries)
+	assert.Equal(t, 2, result["summary"])
+	assert.True(t, reflect.DeepEqual([]string{"doc", "doc"}, result["docs"]))
+}
+
+func TestStateGraph_ChannelTriggersInPlace(t *testing.T) {
+	g := NewStateGraph[map[string]any]()
+	g.SetSchema(NewMapSchema())
+
+	fetches := 0
+	summaries := 0
+
+	// fetch changes its input in place and returns it
+	g.AddNode("fetch", "Fetch documents", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		fetches++
+		if fetches <= 2 {
+			docs, _ := state["docs"].([]string)
+			state["docs"] = append(docs, "doc")
+		}
+		state["polls"] = fetches
+		return state, nil
+	})
+	g.AddNode("summarize", "Summarize documents", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		summaries++
+		return map[string]any{"summary": len(state["docs"].([]string))}, nil
+	}, WithTriggers("docs"))
+
+	g.SetEntryPoint("fetch")
+	g.AddEdge("fetch", "summarize")
+	g.AddEdge("summarize", "fetch")
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	result, err := runnable.Invoke(context.Background(), map[string]any{})
+	assert.NoError(t, err)
+
+	// Only the keys fetch changed bump their versions, so the third fetch did not trigger summarize
+	assert.Equal(t, 3, fetches)
+	assert.Equal(t, 2, summaries)
+	assert.Equal(t, 2, result["summary"])
+}
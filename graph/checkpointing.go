@@ -1,8 +1,13 @@
 package graph
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
 	"fmt"
+	"maps"
+	"reflect"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -23,8 +28,8 @@ func NewMemoryCheckpointStore() store.CheckpointStore {
 }
 
 // NewFileCheckpointStore creates a new file-based checkpoint store
-func NewFileCheckpointStore(path string) (store.CheckpointStore, error) {
-	return file.NewFileCheckpointStore(path)
+func NewFileCheckpointStore(path string, opts ...file.Option) (store.CheckpointStore, error) {
+	return file.NewFileCheckpointStore(path, opts...)
 }
 
 // CheckpointConfig configures checkpointing behavior
@@ -38,10 +43,25 @@ type CheckpointConfig struct {
 	// SaveInterval specifies how often to save (when AutoSave is false)
 	SaveInterval time.Duration
 
-	// MaxCheckpoints limits the number of checkpoints to keep
+	// MaxCheckpoints limits the number of checkpoints to keep. Full snapshots
+	// that kept delta checkpoints build on are kept as well.
 	MaxCheckpoints int
+
+	// Incremental saves the checkpoints of supersteps as deltas: the updates
+	// merged into the state during the superstep instead of the full state.
+	// Loading a delta checkpoint rebuilds its state by merging the updates of
+	// the deltas since the last full snapshot into the state of the snapshot.
+	Incremental bool
+
+	// SnapshotInterval is the number of checkpoints after which an incremental
+	// run saves a full snapshot again, 10 when zero. The first checkpoint of
+	// each run is always a full snapshot.
+	SnapshotInterval int
 }
 
+// defaultSnapshotInterval is the SnapshotInterval used when none is set
+const defaultSnapshotInterval = 10
+
 // DefaultCheckpointConfig returns a default checkpoint configuration
 func DefaultCheckpointConfig() CheckpointConfig {
 	return CheckpointConfig{
@@ -52,21 +72,134 @@ func DefaultCheckpointConfig() CheckpointConfig {
 	}
 }
 
-// CheckpointListener automatically creates checkpoints during execution
+// CheckpointListener automatically creates checkpoints during execution.
+// It keeps the state of a single run, so each invocation gets its own.
 type CheckpointListener[S any] struct {
 	store          store.CheckpointStore
 	executionID    string
 	threadID       string
 	autoSave       bool
 	maxCheckpoints int
+
+	// forkFrom is the checkpoint a forked run branches from, which becomes
+	// the parent of the first checkpoint it saves
+	forkFrom string
+
+	// stepOffset is the superstep of the checkpoint a run resumed from
+	stepOffset int
+
+	// incremental saves supersteps as delta checkpoints, with a full snapshot
+	// every snapshotInterval checkpoints
+	incremental      bool
+	snapshotInterval int
+
+	// lastSaved is the checkpoint the run saved last, which the next delta
+	// checkpoint builds on, and deltas the number of deltas saved since the last
+	// full snapshot
+	lastSaved string
+	deltas    int
 }
 
 // OnGraphStep is called after a step in the graph has completed and the state has been merged.
+// Steps of subgraphs are saved under SubgraphThreadID, tagged with their namespace.
 func (cl *CheckpointListener[S]) OnGraphStep(ctx context.Context, nodeName string, state any) {
+	cl.saveStep(ctx, nodeName, state, nil, nil, nil)
+}
+
+// OnSuperstep saves the checkpoint of a superstep like OnGraphStep, recording
+// the nodes and Sends that run next, where a resumed run continues, the sources
+// that arrived at join edges, and the superstep number, which counts the
+// supersteps of the thread across resumed runs. Incremental listeners save the
+// updates of the superstep instead of the state, unless Sends run next.
+func (cl *CheckpointListener[S]) OnSuperstep(ctx context.Context, step Superstep, state any) {
+	cl.saveStep(ctx, stepName(step.Nodes), state, step.Updates, step.Sends, cl.stepMetadata(ctx, step, step.Step))
+}
+
+// stepMetadata returns the metadata recording where a run resumes after step,
+// counting number supersteps in the run.
+func (cl *CheckpointListener[S]) stepMetadata(ctx context.Context, step Superstep, number int) map[string]any {
+	next := slices.Clone(step.Next)
+	if next == nil {
+		next = []string{}
+	}
+	if GetNamespace(ctx) == "" {
+		number += cl.stepOffset
+	}
+	metadata := map[string]any{
+		"step": number,
+		"next": next,
+	}
+	if len(step.Joins) > 0 {
+		metadata["joins"] = step.Joins
+	}
+	return metadata
+}
+
+func (cl *CheckpointListener[S]) saveStep(ctx context.Context, nodeName string, state any, updates []any, sends []Send, extra map[string]any) {
+	if !cl.autoSave {
+		return
+	}
+	namespace := GetNamespace(ctx)
+	if _, ok := state.(S); !ok && namespace == "" {
+		return
+	}
+	// A delta cannot hold the Sends, which are saved with the state instead
+	if len(sends) > 0 {
+		state = savedState(state, nil, sends, extra)
+		updates = nil
+	}
+	if namespace != "" {
+		updates = nil
+	}
+	cl.saveCheckpoint(ctx, namespace, nodeName, state, updates, extra)
+}
+
+// OnPendingWrites saves the state a failed superstep started from, with the
+// outputs of its nodes that succeeded and the Sends it ran, so that resuming
+// the thread only reruns the nodes that failed and the Sends. Supersteps of
+// subgraphs are not saved.
+func (cl *CheckpointListener[S]) OnPendingWrites(ctx context.Context, step Superstep, state any, writes []PendingWrite, err error) {
+	if !cl.autoSave || GetNamespace(ctx) != "" {
+		return
+	}
+	if _, ok := state.(S); !ok {
+		return
+	}
+
+	// The failed superstep runs again when the thread resumes
+	metadata := cl.stepMetadata(ctx, step, step.Step-1)
+	metadata["event"] = "error"
+	metadata["error"] = err.Error()
+	cl.saveCheckpoint(ctx, "", stepName(step.Nodes), savedState(state, writes, step.Sends, metadata), nil, metadata)
+}
+
+// resumeCheckpoint returns the checkpoint a subgraph running in namespace should
+// resume from, or nil if its last run completed or it never ran on this thread.
+func (cl *CheckpointListener[S]) resumeCheckpoint(ctx context.Context, namespace string) *store.Checkpoint {
+	checkpoints, err := cl.listCheckpoints(ctx, namespace)
+	if err != nil || len(checkpoints) == 0 {
+		return nil
+	}
+	latest := checkpoints[len(checkpoints)-1]
+	if len(resumeNodes(latest)) == 0 && len(metadataStrings(latest.Metadata["pending_sends"])) == 0 {
+		return nil
+	}
+	latest, err = migrateCheckpoint(latest)
+	if err != nil {
+		return nil
+	}
+	latest, err = splitSavedState(latest)
+	if err != nil {
+		return nil
+	}
+	return latest
+}
+
+// finishNamespace records that the subgraph running in namespace completed, so
+// that its next run starts over.
+func (cl *CheckpointListener[S]) finishNamespace(ctx context.Context, namespace string, state any) {
 	if cl.autoSave {
-		if s, ok := state.(S); ok {
-			cl.saveCheckpoint(ctx, nodeName, s)
-		}
+		cl.saveCheckpoint(ctx, namespace, END, state, nil, map[string]any{"next": []string{}})
 	}
 }
 
@@ -88,26 +221,57 @@ func (cl *CheckpointListener[S]) OnRetrieverStart(context.Context, map[string]an
 func (cl *CheckpointListener[S]) OnRetrieverEnd(context.Context, []any, string)   {}
 func (cl *CheckpointListener[S]) OnRetrieverError(context.Context, error, string) {}
 
-func (cl *CheckpointListener[S]) saveCheckpoint(ctx context.Context, nodeName string, state S) {
-	// Get current version from existing checkpoints
-	var checkpoints []*store.Checkpoint
-	var err error
+// rootID returns the thread ID, or the execution ID when there is none.
+func (cl *CheckpointListener[S]) rootID() string {
 	if cl.threadID != "" {
-		checkpoints, err = cl.store.ListByThread(ctx, cl.threadID)
-	} else {
-		checkpoints, err = cl.store.List(ctx, cl.executionID)
+		return cl.threadID
 	}
+	return cl.executionID
+}
+
+// listCheckpoints lists the checkpoints of the thread/execution, or of the
+// subgraph running in namespace, sorted by version ascending.
+func (cl *CheckpointListener[S]) listCheckpoints(ctx context.Context, namespace string) ([]*store.Checkpoint, error) {
+	if namespace != "" {
+		return cl.store.ListByThread(ctx, SubgraphThreadID(cl.rootID(), namespace))
+	}
+	if cl.threadID != "" {
+		return cl.store.ListByThread(ctx, cl.threadID)
+	}
+	return cl.store.List(ctx, cl.executionID)
+}
+
+// saveCheckpoint saves a checkpoint of state. Incremental listeners save the
+// updates that produced state instead, when they build on the checkpoint the
+// run saved last; see saveDelta.
+func (cl *CheckpointListener[S]) saveCheckpoint(ctx context.Context, namespace, nodeName string, state any, updates []any, extra map[string]any) {
+	// Get current version from existing checkpoints
+	checkpoints, err := cl.listCheckpoints(ctx, namespace)
 	version := 1
+	var parentID string
 	if err == nil && len(checkpoints) > 0 {
 		// Get the latest version
 		latest := checkpoints[len(checkpoints)-1]
 		version = latest.Version + 1
+		parentID = latest.ID
+	}
+	if namespace == "" && cl.forkFrom != "" {
+		parentID = cl.forkFrom
+		cl.forkFrom = ""
 	}
 
 	metadata := map[string]any{
 		"event": "step",
 	}
-	if cl.threadID != "" {
+	if parentID != "" {
+		metadata["parent_checkpoint_id"] = parentID
+	}
+	maps.Copy(metadata, extra)
+	if namespace != "" {
+		metadata["thread_id"] = SubgraphThreadID(cl.rootID(), namespace)
+		metadata["parent_thread_id"] = cl.rootID()
+		metadata["checkpoint_ns"] = namespace
+	} else if cl.threadID != "" {
 		metadata["thread_id"] = cl.threadID
 	} else {
 		metadata["execution_id"] = cl.executionID
@@ -122,35 +286,90 @@ func (cl *CheckpointListener[S]) saveCheckpoint(ctx context.Context, nodeName st
 		Metadata:  metadata,
 	}
 
+	delta := namespace == "" && cl.saveDelta(parentID, state, updates)
+	if delta {
+		checkpoint.State = updates
+		metadata["delta"] = true
+	}
+
 	// Save checkpoint synchronously
-	_ = cl.store.Save(ctx, checkpoint)
+	store.GlobalTypeRegistry().StampCheckpoint(checkpoint)
+	err = cl.store.Save(ctx, checkpoint)
+
+	// A checkpoint that failed to save cannot be built on
+	if namespace == "" {
+		switch {
+		case err != nil:
+			cl.lastSaved = ""
+		case delta:
+			cl.lastSaved = checkpoint.ID
+			cl.deltas++
+		default:
+			cl.lastSaved = checkpoint.ID
+			cl.deltas = 0
+		}
+	}
 
 	// Cleanup old checkpoints if MaxCheckpoints is set
 	if cl.maxCheckpoints > 0 {
-		cl.cleanupOldCheckpoints(ctx)
+		cl.cleanupOldCheckpoints(ctx, namespace)
 	}
 }
 
-// cleanupOldCheckpoints removes oldest checkpoints exceeding the max limit
-func (cl *CheckpointListener[S]) cleanupOldCheckpoints(ctx context.Context) {
-	// List checkpoints for this thread/execution
-	var checkpoints []*store.Checkpoint
-	var err error
-	if cl.threadID != "" {
-		checkpoints, err = cl.store.ListByThread(ctx, cl.threadID)
-	} else {
-		checkpoints, err = cl.store.List(ctx, cl.executionID)
+// saveDelta reports whether the superstep that merged updates into state is
+// saved as a delta of the checkpoint parentID.
+func (cl *CheckpointListener[S]) saveDelta(parentID string, state any, updates []any) bool {
+	if !cl.incremental || updates == nil || parentID == "" || parentID != cl.lastSaved {
+		return false
+	}
+	interval := cl.snapshotInterval
+	if interval <= 0 {
+		interval = defaultSnapshotInterval
 	}
+	if cl.deltas+1 >= interval {
+		return false
+	}
+	// A node that updated its input in place and returned it replaced the
+	// state, which replaying its update would merge twice
+	for _, update := range updates {
+		if sameValue(update, state) {
+			return false
+		}
+	}
+	return true
+}
+
+// cleanupOldCheckpoints removes oldest checkpoints exceeding the max limit,
+// except the checkpoints that the delta checkpoints kept build on.
+func (cl *CheckpointListener[S]) cleanupOldCheckpoints(ctx context.Context, namespace string) {
+	// List checkpoints for this thread/execution
+	checkpoints, err := cl.listCheckpoints(ctx, namespace)
 	if err != nil || len(checkpoints) <= cl.maxCheckpoints {
 		return
 	}
 
-	// Sort by version ascending (oldest first) and delete excess
 	// Checkpoints returned by List are already sorted by version ascending
 	excessCount := len(checkpoints) - cl.maxCheckpoints
-	for i := range excessCount {
+	byID := make(map[string]*store.Checkpoint, len(checkpoints))
+	for _, cp := range checkpoints {
+		byID[cp.ID] = cp
+	}
+	needed := make(map[string]bool)
+	for _, cp := range checkpoints[excessCount:] {
+		for isDelta(cp) {
+			parentID := parentCheckpointID(cp)
+			needed[parentID] = true
+			if cp = byID[parentID]; cp == nil {
+				break
+			}
+		}
+	}
+
+	for _, cp := range checkpoints[:excessCount] {
 		// Delete the oldest checkpoints
-		_ = cl.store.Delete(ctx, checkpoints[i].ID)
+		if !needed[cp.ID] {
+			_ = cl.store.Delete(ctx, cp.ID)
+		}
 	}
 }
 
@@ -206,30 +425,29 @@ type CheckpointableRunnable[S any] struct {
 	runnable    *ListenableRunnable[S]
 	config      CheckpointConfig
 	executionID string
-	listener    *CheckpointListener[S]
 }
 
 // NewCheckpointableRunnable creates a new checkpointable runnable from a listenable runnable
 func NewCheckpointableRunnable[S any](runnable *ListenableRunnable[S], config CheckpointConfig) *CheckpointableRunnable[S] {
-	executionID := generateExecutionID()
-	cr := &CheckpointableRunnable[S]{
+	return &CheckpointableRunnable[S]{
 		runnable:    runnable,
 		config:      config,
-		executionID: executionID,
+		executionID: generateExecutionID(),
 	}
+}
 
-	// Create checkpoint listener
-	cr.listener = &CheckpointListener[S]{
-		store:          cr.config.Store,
-		executionID:    executionID,
-		threadID:       "",
-		autoSave:       true,
-		maxCheckpoints: cr.config.MaxCheckpoints,
+// newListener returns the checkpoint listener of a run on threadID, which
+// concurrent runs do not share.
+func (cr *CheckpointableRunnable[S]) newListener(threadID string) *CheckpointListener[S] {
+	return &CheckpointListener[S]{
+		store:            cr.config.Store,
+		executionID:      cr.executionID,
+		threadID:         threadID,
+		autoSave:         cr.config.AutoSave,
+		maxCheckpoints:   cr.config.MaxCheckpoints,
+		incremental:      cr.config.Incremental,
+		snapshotInterval: cr.config.SnapshotInterval,
 	}
-
-	// The listener will be added to config callbacks during invocation.
-
-	return cr
 }
 
 // Invoke executes the graph with checkpointing support
@@ -237,8 +455,20 @@ func (cr *CheckpointableRunnable[S]) Invoke(ctx context.Context, initialState S)
 	return cr.InvokeWithConfig(ctx, initialState, nil)
 }
 
-// InvokeWithConfig executes the graph with checkpointing support and config
+// InvokeWithConfig executes the graph with checkpointing support and config.
+//
+// With a "thread_id" in config.Configurable, the run resumes from the latest
+// checkpoint of the thread. With a "checkpoint_id" as well, it forks the thread
+// at that checkpoint instead: the run starts from its state, and the checkpoints
+// it saves branch off it while the ones after it are kept.
 func (cr *CheckpointableRunnable[S]) InvokeWithConfig(ctx context.Context, initialState S, config *Config) (S, error) {
+	// The run updates its own copy of the config, which concurrent runs may share
+	if config != nil {
+		runConfig := *config
+		runConfig.Callbacks = slices.Clip(config.Callbacks)
+		config = &runConfig
+	}
+
 	// Extract thread_id from config if present
 	var threadID string
 	if config != nil && config.Configurable != nil {
@@ -247,48 +477,80 @@ func (cr *CheckpointableRunnable[S]) InvokeWithConfig(ctx context.Context, initi
 		}
 	}
 
+	// Fork: if checkpoint_id is provided as well, the run starts from that
+	// checkpoint and its checkpoints branch off it, leaving the history intact
+	var forkFrom *store.Checkpoint
+	if threadID != "" {
+		if checkpointID, ok := config.Configurable["checkpoint_id"].(string); ok && checkpointID != "" {
+			checkpoint, err := cr.loadThreadCheckpoint(ctx, threadID, checkpointID)
+			if err != nil {
+				var zero S
+				return zero, err
+			}
+			forkFrom = checkpoint
+		}
+	}
+
 	// Auto-resume: if thread_id is provided, try to load the latest checkpoint
 	// and merge its state with the provided initialState (which may be just new input)
+	var stepOffset int
 	if threadID != "" {
+		resumeCP := forkFrom
 		// Only auto-resume if ResumeFrom is not explicitly set (manual control takes precedence)
-		if config == nil || config.ResumeFrom == nil {
-			if latestCP, err := cr.getLatestCheckpoint(ctx, threadID); err == nil && latestCP != nil {
-				// Found existing checkpoint - this is a resume
-				checkpointState, ok := latestCP.State.(S)
-				if ok {
-					// Merge checkpoint state with new input using Schema
-					initialState = cr.mergeStates(ctx, checkpointState, initialState)
-
-					// Check if the checkpoint is at END (completed execution)
-					// Note: NodeName is empty when checkpoint is created at END or via other means
-					if latestCP.NodeName == "" || latestCP.NodeName == END {
-						// Graph has completed - just return the merged state
-						// No need to re-execute anything
-						return initialState, nil
+		if resumeCP == nil && config.ResumeFrom == nil {
+			if latestCP, err := cr.getLatestCheckpoint(ctx, threadID); err == nil {
+				resumeCP = latestCP
+			}
+		}
+		if resumeCP != nil {
+			// Found existing checkpoint - this is a resume
+			checkpointState, ok := stateAs[S](resumeCP.State)
+			if ok {
+				// Merge checkpoint state with new input using Schema
+				initialState = cr.mergeStates(ctx, checkpointState, initialState)
+
+				if config.ResumeFrom == nil {
+					stepOffset = metadataInt(resumeCP.Metadata["step"])
+
+					// For incomplete checkpoints (interrupted or crashed), continue
+					// with the nodes the checkpoint recorded as next. Once the graph
+					// has completed, a new run starts at the entry point.
+					if next := resumeNodes(resumeCP); len(next) > 0 {
+						config.ResumeFrom = next
+					}
+
+					// A failed superstep is rerun, reusing the outputs of its nodes
+					// that succeeded
+					if writes := pendingWritesOf[S](resumeCP); len(writes) > 0 {
+						ctx = context.WithValue(ctx, pendingWritesKey{}, writes)
+					}
+
+					// Joins keep the sources that arrived before the checkpoint
+					if arrivals := joinArrivalsOf(resumeCP.Metadata["joins"]); len(arrivals) > 0 {
+						ctx = context.WithValue(ctx, joinArrivalsKey{}, arrivals)
 					}
 
-					// For incomplete checkpoints (interrupted), set ResumeFrom to continue
-					// The graph will continue execution from the checkpoint node
-					if config == nil {
-						config = &Config{}
+					// Sends that were pending run alongside the next nodes
+					if sends := pendingSendsOf[S](resumeCP.Metadata); len(sends) > 0 {
+						ctx = context.WithValue(ctx, pendingSendsKey{}, sends)
 					}
-					config.ResumeFrom = []string{latestCP.NodeName}
 				}
 			}
 		}
 	}
 
-	// Update checkpoint listener with thread_id
-	if cr.listener != nil {
-		cr.listener.threadID = threadID
-		cr.listener.autoSave = cr.config.AutoSave
+	// Checkpoint the run with a listener of its own
+	listener := cr.newListener(threadID)
+	if forkFrom != nil {
+		listener.forkFrom = forkFrom.ID
 	}
+	listener.stepOffset = stepOffset
 
 	// Add the listener to config callbacks
 	if config == nil {
 		config = &Config{}
 	}
-	config.Callbacks = append(config.Callbacks, cr.listener)
+	config.Callbacks = append(config.Callbacks, listener)
 
 	return cr.runnable.InvokeWithConfig(ctx, initialState, config)
 }
@@ -305,7 +567,39 @@ type StateSnapshot struct {
 	Config    Config
 	Metadata  map[string]any
 	CreatedAt time.Time
-	ParentID  string
+	// ParentID is the ID of the checkpoint this one was saved after, which is
+	// the checkpoint a fork branched from for the first checkpoint of a fork
+	ParentID string
+}
+
+// newStateSnapshot returns the snapshot of a checkpoint of the thread threadID,
+// with the values of the checkpoint as S when they can be converted. Next
+// includes the nodes of pending Sends.
+func newStateSnapshot[S any](threadID string, checkpoint *store.Checkpoint) *StateSnapshot {
+	next := resumeNodes(checkpoint)
+	for _, send := range pendingSendsOf[S](checkpoint.Metadata) {
+		next = mergeNodeLists(next, []string{send.Node})
+	}
+	if next == nil {
+		next = []string{}
+	}
+	var values any = checkpoint.State
+	if state, ok := stateAs[S](checkpoint.State); ok {
+		values = state
+	}
+	return &StateSnapshot{
+		Values: values,
+		Next:   next,
+		Config: Config{
+			Configurable: map[string]any{
+				"thread_id":     threadID,
+				"checkpoint_id": checkpoint.ID,
+			},
+		},
+		Metadata:  checkpoint.Metadata,
+		CreatedAt: checkpoint.Timestamp,
+		ParentID:  parentCheckpointID(checkpoint),
+	}
 }
 
 // getLatestCheckpoint retrieves the latest checkpoint for a given thread_id.
@@ -316,7 +610,11 @@ func (cr *CheckpointableRunnable[S]) getLatestCheckpoint(ctx context.Context, th
 	if latestGetter, ok := cr.config.Store.(interface {
 		GetLatestByThread(ctx context.Context, threadID string) (*store.Checkpoint, error)
 	}); ok {
-		return latestGetter.GetLatestByThread(ctx, threadID)
+		latest, err := latestGetter.GetLatestByThread(ctx, threadID)
+		if err != nil {
+			return nil, err
+		}
+		return cr.rebuildCheckpoint(ctx, latest, nil)
 	}
 
 	// Fallback to List method for stores that don't implement GetLatestByThread
@@ -337,7 +635,7 @@ func (cr *CheckpointableRunnable[S]) getLatestCheckpoint(ctx context.Context, th
 		}
 	}
 
-	return latest, nil
+	return cr.rebuildCheckpoint(ctx, latest, nil)
 }
 
 // mergeStates merges the checkpoint state with new input using the graph's Schema.
@@ -364,6 +662,8 @@ func (cr *CheckpointableRunnable[S]) GetState(ctx context.Context, config *Confi
 	var threadID string
 	var checkpointID string
 
+	var namespace string
+
 	if config != nil && config.Configurable != nil {
 		if tid, ok := config.Configurable["thread_id"].(string); ok {
 			threadID = tid
@@ -371,6 +671,9 @@ func (cr *CheckpointableRunnable[S]) GetState(ctx context.Context, config *Confi
 		if cid, ok := config.Configurable["checkpoint_id"].(string); ok {
 			checkpointID = cid
 		}
+		if ns, ok := config.Configurable["checkpoint_ns"].(string); ok {
+			namespace = ns
+		}
 	}
 
 	// Default to current execution ID if thread_id not provided
@@ -378,6 +681,9 @@ func (cr *CheckpointableRunnable[S]) GetState(ctx context.Context, config *Confi
 		threadID = cr.executionID
 	}
 
+	// Read the checkpoints of a subgraph of the thread
+	threadID = SubgraphThreadID(threadID, namespace)
+
 	var checkpoint *store.Checkpoint
 	var err error
 
@@ -421,23 +727,416 @@ func (cr *CheckpointableRunnable[S]) GetState(ctx context.Context, config *Confi
 		return nil, fmt.Errorf("checkpoint not found")
 	}
 
+	// Rebuild delta checkpoints and bring states saved by an older deploy to
+	// the current schema version
+	checkpoint, err = cr.rebuildCheckpoint(ctx, checkpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
 	// Return state snapshot
-	next := []string{checkpoint.NodeName}
-	if checkpoint.NodeName == "" {
-		next = []string{}
+	return newStateSnapshot[S](threadID, checkpoint), nil
+}
+
+// GetStateHistory returns the snapshots of the checkpoints of a thread, newest
+// first, like GetState reading "thread_id" and "checkpoint_ns" from
+// config.Configurable. Their ParentID links rebuild the tree of the thread's
+// forks.
+//
+// With a "checkpoint_id", only that checkpoint and its ancestors are returned,
+// following the parent links: the history of the branch that led to it.
+// Snapshots whose metadata does not hold every key/value pair of filter are
+// skipped, and at most limit snapshots are returned when limit is positive.
+func (cr *CheckpointableRunnable[S]) GetStateHistory(ctx context.Context, config *Config, filter map[string]any, limit int) ([]*StateSnapshot, error) {
+	var threadID, checkpointID, namespace string
+	if config != nil && config.Configurable != nil {
+		threadID, _ = config.Configurable["thread_id"].(string)
+		checkpointID, _ = config.Configurable["checkpoint_id"].(string)
+		namespace, _ = config.Configurable["checkpoint_ns"].(string)
 	}
-	return &StateSnapshot{
-		Values: checkpoint.State,
-		Next:   next,
-		Config: Config{
-			Configurable: map[string]any{
-				"thread_id":     threadID,
-				"checkpoint_id": checkpoint.ID,
-			},
-		},
-		Metadata:  checkpoint.Metadata,
-		CreatedAt: checkpoint.Timestamp,
-	}, nil
+	if threadID == "" {
+		threadID = cr.executionID
+	}
+	threadID = SubgraphThreadID(threadID, namespace)
+
+	checkpoints, err := cr.threadCheckpoints(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	if checkpointID != "" {
+		// Walk up the branch of the checkpoint
+		byID := make(map[string]*store.Checkpoint, len(checkpoints))
+		for _, cp := range checkpoints {
+			byID[cp.ID] = cp
+		}
+		if _, ok := byID[checkpointID]; !ok {
+			return nil, fmt.Errorf("checkpoint %s not found in thread %s", checkpointID, threadID)
+		}
+		var branch []*store.Checkpoint
+		// A parent removed by MaxCheckpoints ends the walk
+		for cp, ok := byID[checkpointID]; ok; cp, ok = byID[parentCheckpointID(cp)] {
+			branch = append(branch, cp)
+		}
+		checkpoints = branch
+	} else {
+		slices.SortStableFunc(checkpoints, func(a, b *store.Checkpoint) int {
+			if a.Version != b.Version {
+				return cmp.Compare(b.Version, a.Version)
+			}
+			return b.Timestamp.Compare(a.Timestamp)
+		})
+	}
+
+	history := make([]*StateSnapshot, 0, len(checkpoints))
+	for _, cp := range checkpoints {
+		if !metadataMatches(cp.Metadata, filter) {
+			continue
+		}
+		history = append(history, newStateSnapshot[S](threadID, cp))
+		if limit > 0 && len(history) == limit {
+			break
+		}
+	}
+	return history, nil
+}
+
+// threadCheckpoints lists the checkpoints of a thread, or of an execution for
+// checkpoints saved without a thread_id.
+func (cr *CheckpointableRunnable[S]) threadCheckpoints(ctx context.Context, threadID string) ([]*store.Checkpoint, error) {
+	checkpoints, err := cr.config.Store.ListByThread(ctx, threadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints: %w", err)
+	}
+	if len(checkpoints) == 0 {
+		checkpoints, err = cr.config.Store.List(ctx, threadID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list checkpoints: %w", err)
+		}
+	}
+	byID := make(map[string]*store.Checkpoint, len(checkpoints))
+	for _, cp := range checkpoints {
+		byID[cp.ID] = cp
+	}
+	rebuilt := make([]*store.Checkpoint, len(checkpoints))
+	for i, cp := range checkpoints {
+		if rebuilt[i], err = cr.rebuildCheckpoint(ctx, cp, byID); err != nil {
+			return nil, err
+		}
+	}
+	return rebuilt, nil
+}
+
+// loadThreadCheckpoint loads the checkpoint checkpointID, which must belong to
+// the thread threadID.
+func (cr *CheckpointableRunnable[S]) loadThreadCheckpoint(ctx context.Context, threadID, checkpointID string) (*store.Checkpoint, error) {
+	checkpoint, err := cr.config.Store.Load(ctx, checkpointID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+	tid, _ := checkpoint.Metadata["thread_id"].(string)
+	eid, _ := checkpoint.Metadata["execution_id"].(string)
+	if tid != threadID && eid != threadID {
+		return nil, fmt.Errorf("checkpoint %s does not belong to thread %s", checkpointID, threadID)
+	}
+	return cr.rebuildCheckpoint(ctx, checkpoint, nil)
+}
+
+// pendingWrites maps nodes to the outputs that a failed run saved for them.
+type pendingWrites map[string]any
+
+type pendingWritesKey struct{}
+
+// pendingWritesOf returns the outputs saved with a checkpoint of a failed
+// superstep, once splitSavedState moved them to its metadata. Outputs that a
+// store returns decoded from JSON are converted back to S.
+func pendingWritesOf[S any](checkpoint *store.Checkpoint) pendingWrites {
+	saved, ok := checkpoint.Metadata["pending_writes"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	writes := make(pendingWrites, len(saved))
+	for node, value := range saved {
+		if res, ok := stateAs[S](value); ok {
+			writes[node] = res
+		}
+	}
+	return writes
+}
+
+type pendingSendsKey struct{}
+
+// pendingSendsOf returns the Sends saved with a checkpoint, once
+// splitSavedState moved them to its metadata. Inputs that a store returns
+// decoded from JSON are converted back to S.
+func pendingSendsOf[S any](metadata map[string]any) []Send {
+	saved, _ := metadata["pending_sends"].([]Send)
+	var sends []Send
+	for _, send := range saved {
+		if arg, ok := stateAs[S](send.Arg); ok {
+			send.Arg = arg
+		}
+		sends = append(sends, send)
+	}
+	return sends
+}
+
+// savedState returns the state a checkpoint saves: state, followed by the
+// outputs of writes and the inputs of sends if there are any. They are saved
+// in the state rather than the metadata so that stores serialize, encode and
+// migrate them like states, and metadata lists their nodes under
+// "pending_writes" and "pending_sends".
+func savedState(state any, writes []PendingWrite, sends []Send, metadata map[string]any) any {
+	if len(writes) == 0 && len(sends) == 0 {
+		return state
+	}
+	saved := make([]any, 0, 1+len(writes)+len(sends))
+	saved = append(saved, state)
+	if len(writes) > 0 {
+		written := make([]string, len(writes))
+		for i, w := range writes {
+			written[i] = w.Node
+			saved = append(saved, w.Value)
+		}
+		metadata["pending_writes"] = written
+	}
+	if len(sends) > 0 {
+		sent := make([]string, len(sends))
+		for i, send := range sends {
+			sent[i] = send.Node
+			saved = append(saved, send.Arg)
+		}
+		metadata["pending_sends"] = sent
+	}
+	return saved
+}
+
+// splitSavedState undoes savedState: it returns a checkpoint with the state
+// saved first, and the outputs and Sends saved after it in its metadata, as a
+// map of outputs by node under "pending_writes" and as []Send under
+// "pending_sends". Other checkpoints are returned unchanged.
+func splitSavedState(checkpoint *store.Checkpoint) (*store.Checkpoint, error) {
+	written := metadataStrings(checkpoint.Metadata["pending_writes"])
+	sent := metadataStrings(checkpoint.Metadata["pending_sends"])
+	saved, ok := checkpoint.State.([]any)
+	if len(written) == 0 && len(sent) == 0 || !ok {
+		return checkpoint, nil
+	}
+	if len(saved) != 1+len(written)+len(sent) {
+		return nil, fmt.Errorf("invalid state of checkpoint %s: %d values for %d pending writes and %d pending sends",
+			checkpoint.ID, len(saved)-1, len(written), len(sent))
+	}
+
+	split := *checkpoint
+	split.State = saved[0]
+	split.Metadata = maps.Clone(checkpoint.Metadata)
+	if len(written) > 0 {
+		writes := make(map[string]any, len(written))
+		for i, node := range written {
+			writes[node] = saved[1+i]
+		}
+		split.Metadata["pending_writes"] = writes
+	}
+	if len(sent) > 0 {
+		sends := make([]Send, len(sent))
+		for i, node := range sent {
+			sends[i] = Send{Node: node, Arg: saved[1+len(written)+i]}
+		}
+		split.Metadata["pending_sends"] = sends
+	}
+	return &split, nil
+}
+
+// rebuildCheckpoint returns checkpoint with its full state, migrated like
+// migrateCheckpoint, and the outputs and Sends saved with it split from its
+// state, see splitSavedState. The state of a delta checkpoint is
+// rebuilt by merging the updates of the deltas since the last full snapshot
+// into the state of the snapshot, once both are migrated. known holds
+// checkpoints already listed, by ID; the others are loaded from the store.
+func (cr *CheckpointableRunnable[S]) rebuildCheckpoint(ctx context.Context, checkpoint *store.Checkpoint, known map[string]*store.Checkpoint) (*store.Checkpoint, error) {
+	if !isDelta(checkpoint) {
+		migrated, err := migrateCheckpoint(checkpoint)
+		if err != nil {
+			return nil, err
+		}
+		return splitSavedState(migrated)
+	}
+
+	// Walk back to the snapshot the deltas build on
+	var deltas []*store.Checkpoint
+	snapshot := checkpoint
+	for isDelta(snapshot) {
+		deltas = append(deltas, snapshot)
+		parentID := parentCheckpointID(snapshot)
+		if parent, ok := known[parentID]; ok {
+			snapshot = parent
+			continue
+		}
+		if parentID == "" {
+			return nil, fmt.Errorf("delta checkpoint %s has no parent", snapshot.ID)
+		}
+		parent, err := cr.config.Store.Load(ctx, parentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load checkpoint %s that checkpoint %s builds on: %w", parentID, checkpoint.ID, err)
+		}
+		snapshot = parent
+	}
+
+	snapshot, err := migrateCheckpoint(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot, err = splitSavedState(snapshot); err != nil {
+		return nil, err
+	}
+	state, ok := stateAs[S](snapshot.State)
+	if !ok {
+		return nil, fmt.Errorf("failed to rebuild checkpoint %s: invalid state in snapshot %s", checkpoint.ID, snapshot.ID)
+	}
+	for i := len(deltas) - 1; i >= 0; i-- {
+		// Updates saved with an older schema version are migrated like states
+		delta, err := migrateCheckpoint(deltas[i])
+		if err != nil {
+			return nil, err
+		}
+		updates, err := deltaUpdates[S](delta)
+		if err != nil {
+			return nil, err
+		}
+		if state, err = cr.runnable.runnable.mergeState(ctx, state, state, updates, nil); err != nil {
+			return nil, fmt.Errorf("failed to rebuild checkpoint %s: %w", checkpoint.ID, err)
+		}
+	}
+
+	rebuilt := *checkpoint
+	rebuilt.State = state
+	return &rebuilt, nil
+}
+
+// isDelta reports whether checkpoint holds the updates of a superstep instead
+// of the full state, see CheckpointConfig.Incremental.
+func isDelta(checkpoint *store.Checkpoint) bool {
+	delta, _ := checkpoint.Metadata["delta"].(bool)
+	return delta
+}
+
+// deltaUpdates returns the updates saved in a delta checkpoint. Updates that a
+// store returns decoded from JSON are converted back to S.
+func deltaUpdates[S any](checkpoint *store.Checkpoint) ([]S, error) {
+	saved, ok := checkpoint.State.([]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid updates in delta checkpoint %s: %T", checkpoint.ID, checkpoint.State)
+	}
+	updates := make([]S, len(saved))
+	for i, value := range saved {
+		if value == nil {
+			continue
+		}
+		update, ok := stateAs[S](value)
+		if !ok {
+			return nil, fmt.Errorf("invalid update in delta checkpoint %s: %T", checkpoint.ID, value)
+		}
+		updates[i] = update
+	}
+	return updates, nil
+}
+
+// migrateCheckpoint returns checkpoint with its state migrated to the current
+// schema version of its type, see store.TypeRegistry.MigrateCheckpoint. The
+// checkpoint returned by the store is left unchanged.
+func migrateCheckpoint(checkpoint *store.Checkpoint) (*store.Checkpoint, error) {
+	migrated := *checkpoint
+	migrated.Metadata = maps.Clone(checkpoint.Metadata)
+	changed, err := store.GlobalTypeRegistry().MigrateCheckpoint(&migrated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate checkpoint %s: %w", checkpoint.ID, err)
+	}
+	if !changed {
+		return checkpoint, nil
+	}
+	return &migrated, nil
+}
+
+// stateAs returns value as S. Values that a store returns decoded as plain
+// JSON values, such as the states of types not registered with its
+// serializer, are converted with a JSON round trip.
+func stateAs[S any](value any) (S, bool) {
+	if state, ok := value.(S); ok {
+		return state, true
+	}
+	var state S
+	if value == nil {
+		return state, false
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return state, false
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, false
+	}
+	return state, true
+}
+
+// resumeNodes returns the nodes a run resuming from checkpoint starts with, or
+// none if the run that saved it completed. Checkpoints saved without the next
+// nodes resume at the node that saved them.
+func resumeNodes(checkpoint *store.Checkpoint) []string {
+	if next, ok := checkpoint.Metadata["next"]; ok {
+		return metadataStrings(next)
+	}
+	if checkpoint.NodeName == "" || checkpoint.NodeName == END {
+		return nil
+	}
+	return []string{checkpoint.NodeName}
+}
+
+// metadataInt returns a number of checkpoint metadata, which stores that decode
+// metadata from JSON return as float64.
+func metadataInt(value any) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// metadataStrings returns a list of strings of checkpoint metadata, which stores
+// that decode metadata from JSON return as []any.
+func metadataStrings(value any) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []any:
+		strs := make([]string, 0, len(v))
+		for _, item := range v {
+			if str, ok := item.(string); ok {
+				strs = append(strs, str)
+			}
+		}
+		return strs
+	}
+	return nil
+}
+
+// parentCheckpointID returns the ID of the checkpoint saved before checkpoint on
+// its branch.
+func parentCheckpointID(checkpoint *store.Checkpoint) string {
+	id, _ := checkpoint.Metadata["parent_checkpoint_id"].(string)
+	return id
+}
+
+// metadataMatches reports whether metadata holds every key/value pair of filter.
+func metadataMatches(metadata, filter map[string]any) bool {
+	for key, want := range filter {
+		got, ok := metadata[key]
+		if !ok || !reflect.DeepEqual(got, want) {
+			return false
+		}
+	}
+	return true
 }
 
 // SaveCheckpoint manually saves a checkpoint at the current state
@@ -466,6 +1165,7 @@ func (cr *CheckpointableRunnable[S]) SaveCheckpoint(ctx context.Context, nodeNam
 		},
 	}
 
+	store.GlobalTypeRegistry().StampCheckpoint(checkpoint)
 	return cr.config.Store.Save(ctx, checkpoint)
 }
 
@@ -484,7 +1184,9 @@ func (cr *CheckpointableRunnable[S]) ClearCheckpoints(ctx context.Context) error
 	return cr.config.Store.Clear(ctx, cr.executionID)
 }
 
-// UpdateState updates the state and saves a checkpoint.
+// UpdateState updates the state and saves a checkpoint, as if asNode had
+// returned values. Resuming the thread continues with the successors of asNode
+// rather than running it again.
 func (cr *CheckpointableRunnable[S]) UpdateState(ctx context.Context, config *Config, asNode string, values S) (*Config, error) {
 	var threadID string
 
@@ -500,6 +1202,11 @@ func (cr *CheckpointableRunnable[S]) UpdateState(ctx context.Context, config *Co
 
 	// Get current state from config if available
 	var currentState S
+	var parentID string
+	var pending []string
+	var sends []Send
+	var step int
+	arrivals := make(joinArrivals)
 
 	if config != nil {
 		snapshot, err := cr.GetState(ctx, config)
@@ -507,6 +1214,15 @@ func (cr *CheckpointableRunnable[S]) UpdateState(ctx context.Context, config *Co
 			if s, ok := snapshot.Values.(S); ok {
 				currentState = s
 			}
+			parentID, _ = snapshot.Config.Configurable["checkpoint_id"].(string)
+			pending = snapshot.Next
+			step = metadataInt(snapshot.Metadata["step"])
+			arrivals = joinArrivalsOf(snapshot.Metadata["joins"])
+			sends = pendingSendsOf[S](snapshot.Metadata)
+			// The next nodes of the snapshot include the nodes of the Sends
+			if next, ok := snapshot.Metadata["next"]; ok {
+				pending = metadataStrings(next)
+			}
 		}
 	}
 
@@ -528,6 +1244,13 @@ func (cr *CheckpointableRunnable[S]) UpdateState(ctx context.Context, config *Co
 		newState = values
 	}
 
+	// A resumed run continues after asNode, as if it had returned values
+	next, routed, err := cr.nextAfterUpdate(ctx, asNode, newState, pending, arrivals)
+	if err != nil {
+		return nil, err
+	}
+	sends = append(sends, routed...)
+
 	// Get max version
 	checkpoints, _ := cr.config.Store.List(ctx, threadID)
 	version := 1
@@ -546,11 +1269,23 @@ func (cr *CheckpointableRunnable[S]) UpdateState(ctx context.Context, config *Co
 		Version:   version,
 		Metadata: map[string]any{
 			"execution_id": threadID,
+			"thread_id":    threadID,
 			"source":       "update_state",
 			"updated_by":   asNode,
+			"step":         step + 1,
+			"next":         next,
 		},
 	}
+	if parentID != "" {
+		checkpoint.Metadata["parent_checkpoint_id"] = parentID
+	}
+	if joins := arrivals.sources(); len(joins) > 0 {
+		checkpoint.Metadata["joins"] = joins
+	}
+	// Pending Sends still run when the thread resumes
+	checkpoint.State = savedState(newState, nil, sends, checkpoint.Metadata)
 
+	store.GlobalTypeRegistry().StampCheckpoint(checkpoint)
 	if err := cr.config.Store.Save(ctx, checkpoint); err != nil {
 		return nil, err
 	}
@@ -563,6 +1298,30 @@ func (cr *CheckpointableRunnable[S]) UpdateState(ctx context.Context, config *Co
 	}, nil
 }
 
+// nextAfterUpdate returns the nodes a run resumes with after UpdateState wrote
+// state as asNode: the nodes pending before the update other than asNode, and
+// the successors of asNode along its static, conditional and join edges, with
+// the Sends its router returns. The arrival of asNode at its joins is recorded
+// in arrivals. An asNode that is not a node of the graph, such as a human
+// reviewer, leaves the pending nodes as they are.
+func (cr *CheckpointableRunnable[S]) nextAfterUpdate(ctx context.Context, asNode string, state S, pending []string, arrivals joinArrivals) ([]string, []Send, error) {
+	next := slices.DeleteFunc(slices.Clone(pending), func(n string) bool { return n == asNode })
+	var sends []Send
+	if _, ok := cr.runnable.graph.nodes[asNode]; ok {
+		successors, routed, _, err := cr.runnable.runnable.determineNextNodes(ctx, []string{asNode}, state, nil, arrivals)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to route after %s: %w", asNode, err)
+		}
+		next = mergeNodeLists(next, successors)
+		sends = slices.DeleteFunc(routed, func(s Send) bool { return s.Node == END })
+	}
+	next = runnableNodes(next)
+	if next == nil {
+		next = []string{}
+	}
+	return next, sends, nil
+}
+
 // GetExecutionID returns the current execution ID
 func (cr *CheckpointableRunnable[S]) GetExecutionID() string {
 	return cr.executionID
@@ -571,9 +1330,6 @@ func (cr *CheckpointableRunnable[S]) GetExecutionID() string {
 // SetExecutionID sets a new execution ID
 func (cr *CheckpointableRunnable[S]) SetExecutionID(executionID string) {
 	cr.executionID = executionID
-	if cr.listener != nil {
-		cr.listener.executionID = executionID
-	}
 }
 
 // GetTracer returns the tracer from the underlying runnable
@@ -593,7 +1349,6 @@ func (cr *CheckpointableRunnable[S]) WithTracer(tracer *Tracer) *CheckpointableR
 		runnable:    newRunnable,
 		config:      cr.config,
 		executionID: cr.executionID,
-		listener:    cr.listener,
 	}
 }
 
@@ -2,14 +2,19 @@ package graph_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"slices"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/smallnest/langgraphgo/graph"
 	st "github.com/smallnest/langgraphgo/store"
+	"github.com/smallnest/langgraphgo/store/codec"
+	"github.com/smallnest/langgraphgo/store/file"
 	"github.com/smallnest/langgraphgo/store/redis"
 )
 
@@ -1386,3 +1391,805 @@ func TestMaxCheckpoints_AutoCleanup_RedisStore(t *testing.T) {
 		t.Errorf("Expected latest checkpoint by thread to be step5")
 	}
 }
+
+func TestGetStateHistory_AndFork(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewCheckpointableStateGraph[map[string]any]()
+	for _, name := range []string{"step1", "step2", "step3"} {
+		g.AddNode(name, name, func(ctx context.Context, state map[string]any) (map[string]any, error) {
+			next := map[string]any{}
+			for k, v := range state {
+				next[k] = v
+			}
+			next[name] = fmt.Sprintf("done with %v", state["input"])
+			return next, nil
+		})
+	}
+	g.AddEdge("step1", "step2")
+	g.AddEdge("step2", "step3")
+	g.AddEdge("step3", graph.END)
+	g.SetEntryPoint("step1")
+
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+
+	ctx := context.Background()
+	config := graph.WithThreadID("history-thread")
+	if _, err := runnable.InvokeWithConfig(ctx, map[string]any{"input": "a"}, config); err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+
+	// Newest first, each linked to the previous checkpoint
+	history, err := runnable.GetStateHistory(ctx, graph.WithThreadID("history-thread"), nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 snapshots, got %d", len(history))
+	}
+	if len(history[0].Next) != 0 || history[2].Next[0] != "step2" {
+		t.Errorf("Expected newest-first order, got %v ... %v", history[0].Next, history[2].Next)
+	}
+	if history[2].ParentID != "" {
+		t.Errorf("The first checkpoint should have no parent, got %s", history[2].ParentID)
+	}
+	for i := range 2 {
+		if history[i].ParentID != history[i+1].Config.Configurable["checkpoint_id"] {
+			t.Errorf("Snapshot %d should be linked to the previous checkpoint", i)
+		}
+	}
+
+	snapshot, err := runnable.GetState(ctx, graph.WithThreadID("history-thread"))
+	if err != nil {
+		t.Fatalf("Failed to get state: %v", err)
+	}
+	if snapshot.ParentID != history[1].Config.Configurable["checkpoint_id"] {
+		t.Error("GetState should fill ParentID")
+	}
+
+	limited, _ := runnable.GetStateHistory(ctx, graph.WithThreadID("history-thread"), nil, 2)
+	if len(limited) != 2 {
+		t.Errorf("Expected 2 snapshots with limit, got %d", len(limited))
+	}
+	filtered, _ := runnable.GetStateHistory(ctx, graph.WithThreadID("history-thread"), map[string]any{"event": "missing"}, 0)
+	if len(filtered) != 0 {
+		t.Errorf("Expected the filter to skip every snapshot, got %d", len(filtered))
+	}
+
+	// Fork after step1 with a different input, which continues at step2
+	forkPoint := history[2].Config.Configurable["checkpoint_id"].(string)
+	forkConfig := &graph.Config{
+		Configurable: map[string]any{"thread_id": "history-thread", "checkpoint_id": forkPoint},
+	}
+	forked, err := runnable.InvokeWithConfig(ctx, map[string]any{"input": "b", "step1": "done with a"}, forkConfig)
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	if forked["step2"] != "done with b" || forked["step3"] != "done with b" {
+		t.Errorf("Fork should rerun step2 and step3, got %v", forked)
+	}
+
+	// The original history is intact and the fork branches off step1
+	history, err = runnable.GetStateHistory(ctx, graph.WithThreadID("history-thread"), nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != 5 {
+		t.Fatalf("Expected 5 snapshots after the fork, got %d", len(history))
+	}
+	if history[1].ParentID != forkPoint {
+		t.Errorf("The first checkpoint of the fork should branch off %s, got %s", forkPoint, history[1].ParentID)
+	}
+	if history[3].ParentID != forkPoint {
+		t.Error("The original checkpoint after step1 should be kept")
+	}
+
+	// The branch of the fork's head walks back through the fork point
+	head := history[0].Config.Configurable["checkpoint_id"].(string)
+	branch, err := runnable.GetStateHistory(ctx, &graph.Config{
+		Configurable: map[string]any{"thread_id": "history-thread", "checkpoint_id": head},
+	}, nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to get branch: %v", err)
+	}
+	var nodes []string
+	for _, s := range branch {
+		nodes = append(nodes, strings.Join(s.Next, ","))
+	}
+	if !slices.Equal(nodes, []string{"", "step3", "step2"}) {
+		t.Errorf("Unexpected branch %v", nodes)
+	}
+	if branch[0].Values.(map[string]any)["step3"] != "done with b" {
+		t.Error("The branch should start at the fork's head")
+	}
+
+	// Checkpoints of other threads cannot be forked
+	_, err = runnable.InvokeWithConfig(ctx, map[string]any{}, &graph.Config{
+		Configurable: map[string]any{"thread_id": "other-thread", "checkpoint_id": forkPoint},
+	})
+	if err == nil {
+		t.Error("Expected an error when forking a checkpoint of another thread")
+	}
+}
+
+func TestPendingWrites_ResumeOnlyRerunsFailedNode(t *testing.T) {
+	t.Parallel()
+
+	t.Run("memory", func(t *testing.T) {
+		testPendingWritesResume(t, graph.NewMemoryCheckpointStore())
+	})
+	t.Run("file", func(t *testing.T) {
+		// Pending writes come back decoded from JSON
+		fileStore, err := graph.NewFileCheckpointStore(t.TempDir())
+		if err != nil {
+			t.Fatalf("Failed to create file store: %v", err)
+		}
+		testPendingWritesResume(t, fileStore)
+	})
+	t.Run("encrypted", func(t *testing.T) {
+		// Pending writes are encrypted with the state they were saved with
+		inner := graph.NewMemoryCheckpointStore()
+		codecStore, err := codec.NewCodecCheckpointStore(inner,
+			codec.WithEncryption(codec.NewStaticKey("k1", []byte("0123456789abcdef0123456789abcdef"))))
+		if err != nil {
+			t.Fatalf("Failed to create codec store: %v", err)
+		}
+		testPendingWritesResume(t, codecStore)
+
+		saved, err := inner.ListByThread(context.Background(), "pending-writes-thread")
+		if err != nil {
+			t.Fatalf("Failed to list checkpoints: %v", err)
+		}
+		for _, cp := range saved {
+			data, err := json.Marshal(cp)
+			if err != nil {
+				t.Fatalf("Failed to marshal checkpoint: %v", err)
+			}
+			if strings.Contains(string(data), "notes on go") {
+				t.Errorf("Checkpoint %s holds the output of research in plaintext: %s", cp.ID, data)
+			}
+		}
+	})
+}
+
+func testPendingWritesResume(t *testing.T, checkpointStore graph.CheckpointStore) {
+	g := graph.NewCheckpointableStateGraphWithConfig[map[string]any](graph.CheckpointConfig{
+		Store:    checkpointStore,
+		AutoSave: true,
+	})
+	g.SetSchema(graph.NewMapSchema())
+
+	var researchRuns, flakyRuns int
+	g.AddNode("start", "start", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		return map[string]any{"topic": "go"}, nil
+	})
+	g.AddNode("research", "research", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		researchRuns++
+		return map[string]any{"research": fmt.Sprintf("notes on %v", state["topic"])}, nil
+	})
+	g.AddNode("flaky", "flaky", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		flakyRuns++
+		if flakyRuns == 1 {
+			return nil, fmt.Errorf("rate limited")
+		}
+		return map[string]any{"flaky": "ok"}, nil
+	})
+	g.SetEntryPoint("start")
+	g.AddEdge("start", "research")
+	g.AddEdge("start", "flaky")
+	g.AddEdge("research", graph.END)
+	g.AddEdge("flaky", graph.END)
+
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+
+	ctx := context.Background()
+	threadID := "pending-writes-thread"
+	if _, err := runnable.InvokeWithConfig(ctx, map[string]any{}, graph.WithThreadID(threadID)); err == nil {
+		t.Fatal("Expected the first run to fail")
+	}
+
+	// The failed superstep is saved with the output of the node that succeeded
+	snapshot, err := runnable.GetState(ctx, graph.WithThreadID(threadID))
+	if err != nil {
+		t.Fatalf("Failed to get state: %v", err)
+	}
+	if snapshot.Metadata["event"] != "error" {
+		t.Errorf("Expected an error checkpoint, got %v", snapshot.Metadata["event"])
+	}
+	writes, ok := snapshot.Metadata["pending_writes"].(map[string]any)
+	if !ok || len(writes) != 1 || writes["research"] == nil {
+		t.Fatalf("Expected the pending write of research, got %v", snapshot.Metadata["pending_writes"])
+	}
+
+	// Resuming the thread only reruns the failed node
+	result, err := runnable.InvokeWithConfig(ctx, map[string]any{}, graph.WithThreadID(threadID))
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if researchRuns != 1 {
+		t.Errorf("research should not run again, ran %d times", researchRuns)
+	}
+	if flakyRuns != 2 {
+		t.Errorf("flaky should run again, ran %d times", flakyRuns)
+	}
+	if result["research"] != "notes on go" || result["flaky"] != "ok" || result["topic"] != "go" {
+		t.Errorf("Unexpected result %v", result)
+	}
+}
+
+func TestPendingWrites_NotSavedForSingleNodeFailure(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewCheckpointableStateGraph[map[string]any]()
+	g.AddNode("broken", "broken", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		return nil, fmt.Errorf("boom")
+	})
+	g.SetEntryPoint("broken")
+	g.AddEdge("broken", graph.END)
+
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := runnable.InvokeWithConfig(ctx, map[string]any{}, graph.WithThreadID("single-failure")); err == nil {
+		t.Fatal("Expected the run to fail")
+	}
+	history, err := runnable.GetStateHistory(ctx, graph.WithThreadID("single-failure"), nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != 0 {
+		t.Errorf("No checkpoint should be saved when no node succeeded, got %d", len(history))
+	}
+}
+
+func TestCheckpoint_RecordsNextNodesAndResumesThere(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewCheckpointableStateGraph[map[string]any]()
+	g.SetSchema(graph.NewMapSchema())
+
+	runs := map[string]int{}
+	crash := true
+	for _, name := range []string{"classify", "billing", "support", "reply"} {
+		g.AddNode(name, name, func(ctx context.Context, state map[string]any) (map[string]any, error) {
+			runs[name]++
+			if name == "reply" && crash {
+				return nil, fmt.Errorf("process crashed")
+			}
+			return map[string]any{name: "done"}, nil
+		})
+	}
+	g.SetEntryPoint("classify")
+	g.AddConditionalEdge("classify", func(ctx context.Context, state map[string]any) string {
+		return "billing"
+	})
+	g.AddEdge("billing", "reply")
+	g.AddEdge("support", "reply")
+	g.AddEdge("reply", graph.END)
+
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+
+	ctx := context.Background()
+	config := graph.WithThreadID("next-nodes-thread")
+	if _, err := runnable.InvokeWithConfig(ctx, map[string]any{}, config); err == nil {
+		t.Fatal("Expected the first run to crash")
+	}
+
+	// The checkpoints record where the graph goes next, after routing
+	history, err := runnable.GetStateHistory(ctx, graph.WithThreadID("next-nodes-thread"), nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("Expected 2 checkpoints, got %d", len(history))
+	}
+	if !slices.Equal(history[1].Next, []string{"billing"}) || history[1].Metadata["step"] != 1 {
+		t.Errorf("Expected step 1 to continue at billing, got %v (step %v)", history[1].Next, history[1].Metadata["step"])
+	}
+	if !slices.Equal(history[0].Next, []string{"reply"}) || history[0].Metadata["step"] != 2 {
+		t.Errorf("Expected step 2 to continue at reply, got %v (step %v)", history[0].Next, history[0].Metadata["step"])
+	}
+
+	// Resuming runs reply only, and keeps counting supersteps
+	crash = false
+	result, err := runnable.InvokeWithConfig(ctx, map[string]any{}, graph.WithThreadID("next-nodes-thread"))
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if runs["classify"] != 1 || runs["billing"] != 1 || runs["reply"] != 2 || runs["support"] != 0 {
+		t.Errorf("Unexpected runs %v", runs)
+	}
+	if result["billing"] != "done" || result["reply"] != "done" {
+		t.Errorf("Unexpected result %v", result)
+	}
+
+	snapshot, err := runnable.GetState(ctx, graph.WithThreadID("next-nodes-thread"))
+	if err != nil {
+		t.Fatalf("Failed to get state: %v", err)
+	}
+	if len(snapshot.Next) != 0 || snapshot.Metadata["step"] != 3 {
+		t.Errorf("Expected the completed step 3, got %v (step %v)", snapshot.Next, snapshot.Metadata["step"])
+	}
+}
+
+type typedResearchState struct {
+	Topic string   `json:"topic"`
+	Notes []string `json:"notes"`
+}
+
+func TestCheckpoint_TypedStateRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	registry := st.NewTypeRegistry()
+	if err := registry.RegisterTypeInternal(reflect.TypeOf(typedResearchState{}), "typedResearchState"); err != nil {
+		t.Fatalf("Failed to register type: %v", err)
+	}
+
+	serializers := map[string]st.Serializer{
+		"registered json":    st.NewJSONSerializer(registry),
+		"registered gob":     st.NewGobSerializer(registry),
+		"registered msgpack": st.NewMsgpackSerializer(registry),
+		// Plain JSON values are converted back to the state type
+		"unregistered json": st.NewJSONSerializer(st.NewTypeRegistry()),
+	}
+	for name, serializer := range serializers {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			fileStore, err := graph.NewFileCheckpointStore(t.TempDir(), file.WithSerializer(serializer))
+			if err != nil {
+				t.Fatalf("Failed to create file store: %v", err)
+			}
+
+			g := graph.NewCheckpointableStateGraphWithConfig[typedResearchState](graph.CheckpointConfig{
+				Store:    fileStore,
+				AutoSave: true,
+			})
+			g.SetSchema(graph.NewStructSchema(typedResearchState{}, nil))
+			g.AddNode("search", "search", func(ctx context.Context, state typedResearchState) (typedResearchState, error) {
+				state.Notes = append(slices.Clone(state.Notes), "found "+state.Topic)
+				return state, nil
+			})
+			g.AddNode("review", "review", func(ctx context.Context, state typedResearchState) (typedResearchState, error) {
+				return state, nil
+			})
+			g.SetEntryPoint("search")
+			g.AddEdge("search", "review")
+			g.AddEdge("review", graph.END)
+
+			runnable, err := g.CompileCheckpointable()
+			if err != nil {
+				t.Fatalf("Failed to compile: %v", err)
+			}
+
+			ctx := context.Background()
+			config := graph.WithThreadID("typed-thread")
+			config.InterruptBefore = []string{"review"}
+			if _, err := runnable.InvokeWithConfig(ctx, typedResearchState{Topic: "go"}, config); err == nil {
+				t.Fatal("Expected an interrupt before review")
+			}
+
+			snapshot, err := runnable.GetState(ctx, graph.WithThreadID("typed-thread"))
+			if err != nil {
+				t.Fatalf("Failed to get state: %v", err)
+			}
+			want := typedResearchState{Topic: "go", Notes: []string{"found go"}}
+			if !reflect.DeepEqual(snapshot.Values, want) {
+				t.Fatalf("Expected snapshot values %#v, got %#v", want, snapshot.Values)
+			}
+
+			// Resuming continues with the saved state instead of the input
+			result, err := runnable.InvokeWithConfig(ctx, typedResearchState{}, graph.WithThreadID("typed-thread"))
+			if err != nil {
+				t.Fatalf("Resume failed: %v", err)
+			}
+			if !reflect.DeepEqual(result, want) {
+				t.Errorf("Expected result %#v, got %#v", want, result)
+			}
+		})
+	}
+}
+
+// Versions of a state before and after a deploy renamed Topic to Subject
+type migratingStateV1 struct {
+	Topic string `json:"topic"`
+	Step  string `json:"step"`
+}
+
+type migratingStateV2 struct {
+	Subject string `json:"subject"`
+	Step    string `json:"step"`
+}
+
+func newMigratingGraph[S any](t *testing.T, checkpointStore graph.CheckpointStore, review func(S) S) *graph.CheckpointableRunnable[S] {
+	t.Helper()
+	g := graph.NewCheckpointableStateGraphWithConfig[S](graph.CheckpointConfig{
+		Store:    checkpointStore,
+		AutoSave: true,
+	})
+	var zero S
+	g.SetSchema(graph.NewStructSchema(zero, nil))
+	g.AddNode("draft", "draft", func(ctx context.Context, state S) (S, error) {
+		return state, nil
+	})
+	g.AddNode("review", "review", func(ctx context.Context, state S) (S, error) {
+		return review(state), nil
+	})
+	g.SetEntryPoint("draft")
+	g.AddEdge("draft", "review")
+	g.AddEdge("review", graph.END)
+
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+	return runnable
+}
+
+func TestCheckpoint_MigratesStateOnLoad(t *testing.T) {
+	// The test changes the global type registry, under names no other test uses
+	if err := st.RegisterTypeWithValue(migratingStateV1{}, "migratingState"); err != nil {
+		t.Fatalf("Failed to register type: %v", err)
+	}
+
+	checkpointStore, err := graph.NewFileCheckpointStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create file store: %v", err)
+	}
+
+	ctx := context.Background()
+	before := newMigratingGraph(t, checkpointStore, func(s migratingStateV1) migratingStateV1 { return s })
+	config := graph.WithThreadID("migrating-thread")
+	config.InterruptBefore = []string{"review"}
+	if _, err := before.InvokeWithConfig(ctx, migratingStateV1{Topic: "go"}, config); err == nil {
+		t.Fatal("Expected an interrupt before review")
+	}
+
+	snapshot, err := before.GetState(ctx, graph.WithThreadID("migrating-thread"))
+	if err != nil {
+		t.Fatalf("Failed to get state: %v", err)
+	}
+	if snapshot.Metadata["state_type"] != "migratingState" || snapshot.Metadata["schema_version"] != float64(1) {
+		t.Errorf("Expected a checkpoint stamped with version 1, got %v", snapshot.Metadata)
+	}
+
+	// A new deploy renames the field and registers the migration
+	if err := st.RegisterTypeWithValue(migratingStateV2{}, "migratingState"); err != nil {
+		t.Fatalf("Failed to register type: %v", err)
+	}
+	err = st.RegisterMigration("migratingState", 1, func(state map[string]any) (map[string]any, error) {
+		state["subject"] = state["topic"]
+		delete(state, "topic")
+		return state, nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to register migration: %v", err)
+	}
+
+	after := newMigratingGraph(t, checkpointStore, func(s migratingStateV2) migratingStateV2 {
+		s.Step = "reviewed " + s.Subject
+		return s
+	})
+	snapshot, err = after.GetState(ctx, graph.WithThreadID("migrating-thread"))
+	if err != nil {
+		t.Fatalf("Failed to get state: %v", err)
+	}
+	if want := (migratingStateV2{Subject: "go"}); snapshot.Values != want {
+		t.Errorf("Expected migrated values %#v, got %#v", want, snapshot.Values)
+	}
+
+	result, err := after.InvokeWithConfig(ctx, migratingStateV2{}, graph.WithThreadID("migrating-thread"))
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if want := (migratingStateV2{Subject: "go", Step: "reviewed go"}); result != want {
+		t.Errorf("Expected result %#v, got %#v", want, result)
+	}
+
+	// Checkpoints of the resumed run are saved with the new version
+	snapshot, err = after.GetState(ctx, graph.WithThreadID("migrating-thread"))
+	if err != nil {
+		t.Fatalf("Failed to get state: %v", err)
+	}
+	if snapshot.Metadata["schema_version"] != float64(2) {
+		t.Errorf("Expected schema version 2, got %v", snapshot.Metadata["schema_version"])
+	}
+}
+
+// Versions of a state saved in deltas, before and after the same rename
+type (
+	migratingDeltaStateV1 migratingStateV1
+	migratingDeltaStateV2 migratingStateV2
+)
+
+func TestCheckpoint_MigratesDeltasOnLoad(t *testing.T) {
+	// The test changes the global type registry, under names no other test uses
+	if err := st.RegisterTypeWithValue(migratingDeltaStateV1{}, "migratingDeltaState"); err != nil {
+		t.Fatalf("Failed to register type: %v", err)
+	}
+
+	checkpointStore, err := graph.NewFileCheckpointStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create file store: %v", err)
+	}
+
+	ctx := context.Background()
+	before := newMigratingDeltaGraph(t, checkpointStore, func(migratingDeltaStateV1) migratingDeltaStateV1 {
+		return migratingDeltaStateV1{Topic: "go"}
+	}, func(s migratingDeltaStateV1) migratingDeltaStateV1 { return s })
+	config := graph.WithThreadID("migrating-delta-thread")
+	config.InterruptBefore = []string{"review"}
+	if _, err := before.InvokeWithConfig(ctx, migratingDeltaStateV1{Step: "started"}, config); err == nil {
+		t.Fatal("Expected an interrupt before review")
+	}
+
+	// The topic is only set in the delta of draft
+	checkpoints, err := checkpointStore.ListByThread(ctx, "migrating-delta-thread")
+	if err != nil {
+		t.Fatalf("Failed to list checkpoints: %v", err)
+	}
+	latest := checkpoints[len(checkpoints)-1]
+	if latest.Metadata["delta"] != true || latest.Metadata["state_type"] != "migratingDeltaState" {
+		t.Fatalf("Expected a delta stamped with its state type, got %v", latest.Metadata)
+	}
+
+	// A new deploy renames the field and registers the migration
+	if err := st.RegisterTypeWithValue(migratingDeltaStateV2{}, "migratingDeltaState"); err != nil {
+		t.Fatalf("Failed to register type: %v", err)
+	}
+	err = st.RegisterMigration("migratingDeltaState", 1, func(state map[string]any) (map[string]any, error) {
+		state["subject"] = state["topic"]
+		delete(state, "topic")
+		return state, nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to register migration: %v", err)
+	}
+
+	after := newMigratingDeltaGraph(t, checkpointStore, func(migratingDeltaStateV2) migratingDeltaStateV2 {
+		return migratingDeltaStateV2{Subject: "go"}
+	}, func(s migratingDeltaStateV2) migratingDeltaStateV2 {
+		s.Step = "reviewed " + s.Subject
+		return s
+	})
+	snapshot, err := after.GetState(ctx, graph.WithThreadID("migrating-delta-thread"))
+	if err != nil {
+		t.Fatalf("Failed to get state: %v", err)
+	}
+	if want := (migratingDeltaStateV2{Subject: "go", Step: "started"}); snapshot.Values != want {
+		t.Errorf("Expected migrated values %#v, got %#v", want, snapshot.Values)
+	}
+
+	result, err := after.InvokeWithConfig(ctx, migratingDeltaStateV2{}, graph.WithThreadID("migrating-delta-thread"))
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if want := (migratingDeltaStateV2{Subject: "go", Step: "reviewed go"}); result != want {
+		t.Errorf("Expected result %#v, got %#v", want, result)
+	}
+}
+
+// newMigratingDeltaGraph builds a graph saving deltas, where draft returns an
+// update setting the field the migration renames
+func newMigratingDeltaGraph[S any](t *testing.T, checkpointStore graph.CheckpointStore, draft, review func(S) S) *graph.CheckpointableRunnable[S] {
+	t.Helper()
+	g := graph.NewCheckpointableStateGraphWithConfig[S](graph.CheckpointConfig{
+		Store:       checkpointStore,
+		AutoSave:    true,
+		Incremental: true,
+	})
+	var zero S
+	g.SetSchema(graph.NewStructSchema(zero, nil))
+	g.AddNode("start", "start", func(ctx context.Context, state S) (S, error) {
+		return state, nil
+	})
+	g.AddNode("draft", "draft", func(ctx context.Context, state S) (S, error) {
+		return draft(state), nil
+	})
+	g.AddNode("review", "review", func(ctx context.Context, state S) (S, error) {
+		return review(state), nil
+	})
+	g.SetEntryPoint("start")
+	g.AddEdge("start", "draft")
+	g.AddEdge("draft", "review")
+	g.AddEdge("review", graph.END)
+
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+	return runnable
+}
+
+func TestCheckpoint_IncrementalSavesDeltas(t *testing.T) {
+	t.Parallel()
+
+	checkpointStore, err := graph.NewFileCheckpointStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create file store: %v", err)
+	}
+
+	g := graph.NewCheckpointableStateGraphWithConfig[map[string]any](graph.CheckpointConfig{
+		Store:            checkpointStore,
+		AutoSave:         true,
+		MaxCheckpoints:   3,
+		Incremental:      true,
+		SnapshotInterval: 4,
+	})
+	schema := graph.NewMapSchema()
+	schema.RegisterReducer("messages", graph.AppendReducer)
+	g.SetSchema(schema)
+
+	messages := func(state map[string]any) []string {
+		var msgs []string
+		switch v := state["messages"].(type) {
+		case []any:
+			for _, m := range v {
+				msgs = append(msgs, fmt.Sprint(m))
+			}
+		case []string:
+			msgs = v
+		}
+		return msgs
+	}
+
+	crash := true
+	g.AddNode("chat", "chat", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		turn := len(messages(state)) + 1
+		if crash && turn == 7 {
+			return nil, fmt.Errorf("process crashed")
+		}
+		return map[string]any{"messages": []any{fmt.Sprintf("turn %d", turn)}}, nil
+	})
+	g.SetEntryPoint("chat")
+	g.AddConditionalEdge("chat", func(ctx context.Context, state map[string]any) string {
+		if len(messages(state)) < 10 {
+			return "chat"
+		}
+		return graph.END
+	})
+
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := runnable.InvokeWithConfig(ctx, map[string]any{}, graph.WithThreadID("delta-thread")); err == nil {
+		t.Fatal("Expected the first run to crash")
+	}
+
+	// The state of the last checkpoint is rebuilt from the deltas
+	snapshot, err := runnable.GetState(ctx, graph.WithThreadID("delta-thread"))
+	if err != nil {
+		t.Fatalf("Failed to get state: %v", err)
+	}
+	if got := messages(snapshot.Values.(map[string]any)); len(got) != 6 || snapshot.Metadata["delta"] != true {
+		t.Errorf("Expected 6 messages rebuilt from a delta, got %v (%v)", got, snapshot.Metadata)
+	}
+
+	// Resuming continues from the rebuilt state
+	crash = false
+	result, err := runnable.InvokeWithConfig(ctx, map[string]any{}, graph.WithThreadID("delta-thread"))
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	want := make([]string, 10)
+	for i := range want {
+		want[i] = fmt.Sprintf("turn %d", i+1)
+	}
+	if got := messages(result); !slices.Equal(got, want) {
+		t.Errorf("Expected messages %v, got %v", want, got)
+	}
+
+	// Deltas hold the update of their superstep only, and cleanup keeps the
+	// full snapshot the remaining deltas build on
+	checkpoints, err := checkpointStore.ListByThread(ctx, "delta-thread")
+	if err != nil {
+		t.Fatalf("Failed to list checkpoints: %v", err)
+	}
+	if len(checkpoints) != 4 {
+		t.Fatalf("Expected 3 checkpoints and their snapshot, got %d", len(checkpoints))
+	}
+	if checkpoints[0].Metadata["delta"] == true {
+		t.Errorf("Expected the oldest checkpoint to be a full snapshot")
+	}
+	for _, cp := range checkpoints[1:] {
+		updates, ok := cp.State.([]any)
+		if cp.Metadata["delta"] != true || !ok || len(updates) != 1 {
+			t.Errorf("Expected a delta of one update, got %v (%v)", cp.State, cp.Metadata)
+		}
+	}
+
+	history, err := runnable.GetStateHistory(ctx, graph.WithThreadID("delta-thread"), nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != 4 {
+		t.Fatalf("Expected 4 snapshots, got %d", len(history))
+	}
+	for _, snapshot := range history {
+		step := snapshot.Metadata["step"].(float64)
+		if got := messages(snapshot.Values.(map[string]any)); !slices.Equal(got, want[:int(step)]) {
+			t.Errorf("Expected the messages of step %v, got %v", step, got)
+		}
+	}
+}
+
+func TestCheckpoint_ConcurrentInvokesKeepTheirThreads(t *testing.T) {
+	t.Parallel()
+
+	checkpointStore := graph.NewMemoryCheckpointStore()
+	g := graph.NewCheckpointableStateGraphWithConfig[map[string]any](graph.CheckpointConfig{
+		Store:       checkpointStore,
+		AutoSave:    true,
+		Incremental: true,
+	})
+	g.AddNode("count", "count", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		count, _ := state["count"].(int)
+		time.Sleep(time.Millisecond)
+		return map[string]any{"name": state["name"], "count": count + 1}, nil
+	})
+	g.SetEntryPoint("count")
+	g.AddConditionalEdge("count", func(ctx context.Context, state map[string]any) string {
+		if count, _ := state["count"].(int); count < 5 {
+			return "count"
+		}
+		return graph.END
+	})
+
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+
+	// One runnable serves the threads of several requests at once
+	ctx := context.Background()
+	threads := []string{"thread-a", "thread-b", "thread-c", "thread-d"}
+	var wg sync.WaitGroup
+	for _, threadID := range threads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := runnable.InvokeWithConfig(ctx, map[string]any{"name": threadID}, graph.WithThreadID(threadID)); err != nil {
+				t.Errorf("Run of %s failed: %v", threadID, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, threadID := range threads {
+		checkpoints, err := checkpointStore.ListByThread(ctx, threadID)
+		if err != nil {
+			t.Fatalf("Failed to list checkpoints: %v", err)
+		}
+		if len(checkpoints) != 5 {
+			t.Errorf("Expected 5 checkpoints of %s, got %d", threadID, len(checkpoints))
+		}
+		for i, checkpoint := range checkpoints {
+			if checkpoint.Metadata["thread_id"] != threadID || checkpoint.Metadata["step"] != i+1 {
+				t.Errorf("Checkpoint %d of %s has metadata %v", i, threadID, checkpoint.Metadata)
+			}
+		}
+
+		snapshot, err := runnable.GetState(ctx, graph.WithThreadID(threadID))
+		if err != nil {
+			t.Fatalf("Failed to get state: %v", err)
+		}
+		if state := snapshot.Values.(map[string]any); state["name"] != threadID || state["count"] != 5 {
+			t.Errorf("Expected the final state of %s, got %v", threadID, state)
+		}
+	}
+}
@@ -9,6 +9,7 @@ type Command struct {
 
 	// Goto specifies the next node(s) to execute.
 	// If set, it overrides the graph's edges.
-	// Can be a single string (node name) or []string.
+	// Can be a single string (node name) or []string, or a Send or []Send
+	// to run nodes with their own input; []any may mix both.
 	Goto any
 }
@@ -0,0 +1,108 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// conditionalEdge routes a node to one or more successors chosen at runtime.
+type conditionalEdge[S any] struct {
+	// router returns the paths to follow after the node
+	router func(ctx context.Context, state S) []string
+
+	// pathMap maps router results to node names. When nil, the router
+	// returns node names directly.
+	pathMap map[string]string
+
+	// sender replaces router for edges added with AddConditionalSends
+	sender func(ctx context.Context, state S) []Send
+}
+
+// AddConditionalEdges adds a conditional edge whose router may select several
+// successors at once. Every node the router selects runs in parallel in the next
+// superstep, which allows dynamic fan-out without returning a Command.
+//
+// The pathMap maps the values returned by the router to node names and lists
+// every branch the router can take. Its targets are validated when the graph is
+// compiled and the exporters draw one edge per entry. A router result missing
+// from the pathMap fails the run. With a nil pathMap the router returns node
+// names directly and the possible branches are unknown until runtime.
+//
+// Example:
+//
+//	g.AddConditionalEdges("plan", func(ctx context.Context, state MyState) []string {
+//	    var next []string
+//	    if state.NeedsSearch {
+//	        next = append(next, "search")
+//	    }
+//	    if state.NeedsCode {
+//	        next = append(next, "code")
+//	    }
+//	    if len(next) == 0 {
+//	        next = append(next, "done")
+//	    }
+//	    return next
+//	}, map[string]string{
+//	    "search": "web_search",
+//	    "code":   "code_interpreter",
+//	    "done":   graph.END,
+//	})
+func (g *StateGraph[S]) AddConditionalEdges(from string, router func(ctx context.Context, state S) []string, pathMap map[string]string) {
+	g.conditionalEdges[from] = conditionalEdge[S]{
+		router:  router,
+		pathMap: pathMap,
+	}
+}
+
+// targets runs the router and resolves its results to node names, or to Sends
+// for edges added with AddConditionalSends.
+func (e conditionalEdge[S]) targets(ctx context.Context, from string, state S) ([]string, []Send, error) {
+	if e.sender != nil {
+		// An empty list of Sends ends the branch
+		return nil, e.sender(ctx, state), nil
+	}
+
+	paths := e.router(ctx, state)
+	if len(paths) == 0 {
+		return nil, nil, fmt.Errorf("conditional edge returned empty next node from %s", from)
+	}
+
+	targets := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if path == "" {
+			return nil, nil, fmt.Errorf("conditional edge returned empty next node from %s", from)
+		}
+		if e.pathMap == nil {
+			targets = append(targets, path)
+			continue
+		}
+		target, ok := e.pathMap[path]
+		if !ok {
+			return nil, nil, fmt.Errorf("conditional edge from %s returned unknown path %q", from, path)
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil, nil
+}
+
+// branch is a single path map entry of a conditional edge.
+type branch struct {
+	path   string
+	target string
+}
+
+// branches returns the path map entries sorted by path, for stable output.
+func (e conditionalEdge[S]) branches() []branch {
+	paths := make([]string, 0, len(e.pathMap))
+	for path := range e.pathMap {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	branches := make([]branch, 0, len(paths))
+	for _, path := range paths {
+		branches = append(branches, branch{path: path, target: e.pathMap[path]})
+	}
+	return branches
+}
@@ -2,6 +2,7 @@ package graph_test
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"testing"
 
@@ -337,3 +338,84 @@ func TestConditionalEdges_ChainedConditions(t *testing.T) {
 		t.Errorf("Expected result -10, got %v", result)
 	}
 }
+
+func TestConditionalEdges_MultipleTargets(t *testing.T) {
+	t.Parallel()
+
+	schema := graph.NewMapSchema()
+	schema.RegisterReducer("visited", graph.AppendReducer)
+
+	g := graph.NewStateGraph[map[string]any]()
+	g.SetSchema(schema)
+
+	g.AddNode("plan", "plan", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		return map[string]any{"visited": []string{"plan"}}, nil
+	})
+	for _, name := range []string{"web_search", "code_interpreter", "calculator"} {
+		g.AddNode(name, name, func(ctx context.Context, state map[string]any) (map[string]any, error) {
+			return map[string]any{"visited": []string{name}}, nil
+		})
+		g.AddEdge(name, graph.END)
+	}
+
+	g.SetEntryPoint("plan")
+	g.AddConditionalEdges("plan", func(ctx context.Context, state map[string]any) []string {
+		return []string{"search", "code"}
+	}, map[string]string{
+		"search": "web_search",
+		"code":   "code_interpreter",
+		"math":   "calculator",
+		"done":   graph.END,
+	})
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile graph: %v", err)
+	}
+
+	result, err := runnable.Invoke(context.Background(), map[string]any{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	visited := result["visited"].([]string)
+	if strings.Join(visited, ",") != "plan,web_search,code_interpreter" {
+		t.Errorf("Expected plan followed by both selected branches, got %v", visited)
+	}
+}
+
+func TestConditionalEdges_PathMapValidation(t *testing.T) {
+	t.Parallel()
+
+	newGraph := func(pathMap map[string]string, router []string) *graph.StateGraph[map[string]any] {
+		g := graph.NewStateGraph[map[string]any]()
+		g.AddNode("route", "route", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+			return state, nil
+		})
+		g.AddNode("a", "a", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+			return state, nil
+		})
+		g.AddEdge("a", graph.END)
+		g.SetEntryPoint("route")
+		g.AddConditionalEdges("route", func(ctx context.Context, state map[string]any) []string {
+			return router
+		}, pathMap)
+		return g
+	}
+
+	// A path map target that is not a node is rejected at compile time
+	_, err := newGraph(map[string]string{"a": "a", "b": "missing"}, []string{"a"}).Compile()
+	if !errors.Is(err, graph.ErrNodeNotFound) {
+		t.Errorf("Expected ErrNodeNotFound, got %v", err)
+	}
+
+	// A router result missing from the path map fails the run
+	runnable, err := newGraph(map[string]string{"a": "a"}, []string{"b"}).Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile graph: %v", err)
+	}
+	_, err = runnable.Invoke(context.Background(), map[string]any{})
+	if err == nil || !strings.Contains(err.Error(), `unknown path "b"`) {
+		t.Errorf("Expected unknown path error, got %v", err)
+	}
+}
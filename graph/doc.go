@@ -98,6 +98,11 @@
 //	g.AddEdge("validate", graph.END)
 //	g.AddEdge("retry", "process")
 //
+//	// Routers may also select several branches, listed in a path map
+//	g.AddConditionalEdges("plan", func(ctx context.Context, state WorkflowState) []string {
+//		return []string{"search", "code"}
+//	}, map[string]string{"search": "web_search", "code": "code_interpreter"})
+//
 // Parallel Execution
 //
 //	// Add parallel nodes
@@ -147,6 +152,10 @@
 //		fmt.Printf("Event: %v\n", event)
 //	}
 //
+// Nodes stream tokens and other payloads through the StreamWriter in their context:
+//
+//	resp, err := model.GenerateContent(ctx, messages, graph.GetStreamWriter(ctx).CallOptions()...)
+//
 // # Listener System
 //
 // The package provides a powerful listener system for monitoring and reacting to graph events:
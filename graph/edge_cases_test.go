@@ -108,8 +108,9 @@ func TestLargeGraph(t *testing.T) {
 		t.Fatalf("Failed to compile large graph: %v", err)
 	}
 
+	// A linear chain needs one superstep per node, well past the default limit
 	start := time.Now()
-	result, err := runnable.Invoke(context.Background(), 0)
+	result, err := runnable.InvokeWithConfig(context.Background(), 0, &graph.Config{RecursionLimit: nodeCount})
 	duration := time.Since(start)
 
 	if err != nil {
@@ -123,6 +124,78 @@ func TestLargeGraph(t *testing.T) {
 	t.Logf("Large graph with %d nodes executed in %v", nodeCount, duration)
 }
 
+// TestRecursionLimit tests that runaway loops are stopped with a typed error
+func TestRecursionLimit(t *testing.T) {
+	t.Parallel()
+
+	buildLoop := func() *graph.StateGraph[int] {
+		g := graph.NewStateGraph[int]()
+		g.AddNode("loop", "loop", func(ctx context.Context, state int) (int, error) {
+			return state + 1, nil
+		})
+		// Buggy router that never reaches END
+		g.AddConditionalEdge("loop", func(ctx context.Context, state int) string {
+			return "loop"
+		})
+		g.SetEntryPoint("loop")
+		return g
+	}
+
+	t.Run("ConfiguredLimit", func(t *testing.T) {
+		runnable, err := buildLoop().Compile()
+		if err != nil {
+			t.Fatalf("Failed to compile: %v", err)
+		}
+
+		_, err = runnable.InvokeWithConfig(context.Background(), 0, &graph.Config{RecursionLimit: 5})
+		var recErr *graph.GraphRecursionError
+		if !errors.As(err, &recErr) {
+			t.Fatalf("Expected GraphRecursionError, got %v", err)
+		}
+		if recErr.Steps != 5 || recErr.Limit != 5 {
+			t.Errorf("Expected 5 steps with limit 5, got %d steps with limit %d", recErr.Steps, recErr.Limit)
+		}
+		if len(recErr.LastNodes) != 1 || recErr.LastNodes[0] != "loop" {
+			t.Errorf("Expected last nodes [loop], got %v", recErr.LastNodes)
+		}
+	})
+
+	t.Run("DefaultLimit", func(t *testing.T) {
+		runnable, err := buildLoop().Compile()
+		if err != nil {
+			t.Fatalf("Failed to compile: %v", err)
+		}
+
+		_, err = runnable.Invoke(context.Background(), 0)
+		var recErr *graph.GraphRecursionError
+		if !errors.As(err, &recErr) || recErr.Limit != graph.DefaultRecursionLimit {
+			t.Fatalf("Expected GraphRecursionError with default limit, got %v", err)
+		}
+	})
+
+	t.Run("SubgraphInheritsLimit", func(t *testing.T) {
+		parent := graph.NewStateGraph[int]()
+		if err := graph.AddSubgraph(parent, "child", buildLoop(),
+			func(s int) int { return s },
+			func(s int) int { return s }); err != nil {
+			t.Fatalf("Failed to add subgraph: %v", err)
+		}
+		parent.AddEdge("child", graph.END)
+		parent.SetEntryPoint("child")
+
+		runnable, err := parent.Compile()
+		if err != nil {
+			t.Fatalf("Failed to compile: %v", err)
+		}
+
+		_, err = runnable.InvokeWithConfig(context.Background(), 0, &graph.Config{RecursionLimit: 3})
+		var recErr *graph.GraphRecursionError
+		if !errors.As(err, &recErr) || recErr.Limit != 3 {
+			t.Fatalf("Expected GraphRecursionError with limit 3 from subgraph, got %v", err)
+		}
+	})
+}
+
 // TestConcurrentExecution tests thread safety
 func TestConcurrentExecution(t *testing.T) {
 	t.Parallel()
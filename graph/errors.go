@@ -8,6 +8,9 @@ type NodeInterrupt struct {
 	Node string
 	// Value is the data/query provided by the interrupt
 	Value any
+	// Namespace is the namespace path of the node that called Interrupt, which
+	// differs from Node when the interrupt was raised inside a subgraph
+	Namespace string
 }
 
 func (e *NodeInterrupt) Error() string {
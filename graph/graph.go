@@ -9,6 +9,10 @@ import (
 // END is a special constant used to represent the end node in the graph.
 const END = "END"
 
+// DefaultRecursionLimit is the maximum number of supersteps a graph may run
+// when Config.RecursionLimit is not set.
+const DefaultRecursionLimit = 100
+
 var (
 	// ErrEntryPointNotSet is returned when the entry point of the graph is not set.
 	ErrEntryPointNotSet = errors.New("entry point not set")
@@ -18,6 +22,12 @@ var (
 
 	// ErrNoOutgoingEdge is returned when no outgoing edge is found for a node.
 	ErrNoOutgoingEdge = errors.New("no outgoing edge found for node")
+
+	// ErrGraphTimeout is returned when a run exceeds Config.Timeout.
+	ErrGraphTimeout = errors.New("graph execution timed out")
+
+	// ErrNodeTimeout is returned when a single node exceeds its deadline.
+	ErrNodeTimeout = errors.New("node execution timed out")
 )
 
 // GraphInterrupt is returned when execution is interrupted by configuration or dynamic interrupt
@@ -30,6 +40,10 @@ type GraphInterrupt struct {
 	NextNodes []string
 	// InterruptValue is the value provided by the dynamic interrupt (if any)
 	InterruptValue any
+	// Namespace is the namespace path of the node that raised the interrupt, such
+	// as "research:search:ask_human" when it was raised inside nested subgraphs.
+	// Resume the parent thread to resume the subgraph.
+	Namespace string
 }
 
 func (e *GraphInterrupt) Error() string {
@@ -39,6 +53,37 @@ func (e *GraphInterrupt) Error() string {
 	return fmt.Sprintf("graph interrupted at node %s", e.Node)
 }
 
+// GraphRecursionError is returned when a graph runs more supersteps than its
+// recursion limit allows without reaching END.
+type GraphRecursionError struct {
+	// Limit is the recursion limit that was exceeded
+	Limit int
+	// Steps is the number of supersteps that completed
+	Steps int
+	// LastNodes are the nodes executed in the final completed superstep
+	LastNodes []string
+}
+
+func (e *GraphRecursionError) Error() string {
+	return fmt.Sprintf("recursion limit of %d reached after %d steps without hitting END (last nodes: %v)", e.Limit, e.Steps, e.LastNodes)
+}
+
+// ConflictingWriteError is returned when conflict detection is enabled (see
+// StateRunnable.SetConflictDetection) and several nodes of the same superstep
+// write different values to a key that has no reducer.
+type ConflictingWriteError struct {
+	// Key is the state key (map key or struct field) written more than once
+	Key string
+	// Nodes are the nodes that wrote to the key, in execution order
+	Nodes []string
+	// Step is the superstep in which the conflict happened
+	Step int
+}
+
+func (e *ConflictingWriteError) Error() string {
+	return fmt.Sprintf("conflicting writes to key %q in step %d by nodes %v", e.Key, e.Step, e.Nodes)
+}
+
 // Interrupt pauses execution and waits for input.
 // If resuming, it returns the value provided in the resume command.
 func Interrupt(ctx context.Context, value any) (any, error) {
@@ -57,7 +102,9 @@ type Edge struct {
 	To string
 }
 
-// RetryPolicy defines how to handle node failures
+// RetryPolicy defines how to handle node failures.
+// A node that timed out is retried once it has returned, or once it is abandoned
+// for ignoring its context (see WithTimeout).
 type RetryPolicy struct {
 	MaxRetries      int
 	BackoffStrategy BackoffStrategy
@@ -113,7 +113,8 @@ func TestStateGraph(t *testing.T) {
 				g.SetEntryPoint("node1")
 				return g
 			},
-			expectedError: fmt.Errorf("%w: node2", graph.ErrNodeNotFound),
+			// Reported by Compile's validation pass
+			expectedError: graph.ErrNodeNotFound,
 		},
 		{
 			name: "No outgoing edge",
@@ -0,0 +1,149 @@
+package graph
+
+import (
+	"maps"
+	"slices"
+)
+
+// Defer marks a node as deferred. A deferred node that is scheduled waits until
+// every other branch of the run has finished and then runs once, however many
+// branches routed to it. It is the way to join branches of different lengths
+// when the predecessors are not known up front; see AddJoinEdge otherwise.
+//
+// Example:
+//
+//	g.AddNode("aggregate", "Aggregate all results", aggregate, graph.Defer())
+func Defer() NodeOption {
+	return func(o *NodeOptions) {
+		o.Defer = true
+	}
+}
+
+// AddJoinEdge adds an edge from every node in sources to target, where target
+// only runs once all sources have finished since it last ran. Without a join
+// edge, a node reached by branches of different lengths runs once per branch.
+//
+// A source arrives whichever way it routes to target: its static edge, or a
+// conditional edge or Command.Goto overriding it. A Send to target from a source
+// still runs right away with its own input, and counts as the arrival of that source.
+//
+// Example:
+//
+//	g.AddEdge("start", "fast")
+//	g.AddEdge("start", "slow_1")
+//	g.AddEdge("slow_1", "slow_2")
+//	g.AddJoinEdge([]string{"fast", "slow_2"}, "combine")
+func (g *StateGraph[S]) AddJoinEdge(sources []string, target string) {
+	if g.joins == nil {
+		g.joins = make(map[string][]string)
+	}
+	for _, source := range sources {
+		g.AddEdge(source, target)
+		if !slices.Contains(g.joins[target], source) {
+			g.joins[target] = append(g.joins[target], source)
+		}
+	}
+}
+
+// joinArrivals records, per join target, which sources finished during a run.
+type joinArrivals map[string]map[string]bool
+
+// arrive records that source finished and reports whether every source of the
+// join into target has now finished, resetting the join if so.
+func (ja joinArrivals) arrive(target, source string, sources []string) bool {
+	if ja[target] == nil {
+		ja[target] = make(map[string]bool)
+	}
+	ja[target][source] = true
+
+	for _, s := range sources {
+		if !ja[target][s] {
+			return false
+		}
+	}
+	delete(ja, target)
+	return true
+}
+
+// routeTo records that source routed to target, by any edge, Command.Goto or
+// Send, and reports whether target is to be scheduled: always, unless source is
+// one of the sources of a join into target that some other source has not
+// reached yet.
+func (r *StateRunnable[S]) routeTo(arrivals joinArrivals, source, target string) bool {
+	sources, isJoin := r.graph.joins[target]
+	if !isJoin || !slices.Contains(sources, source) {
+		return true
+	}
+	return arrivals.arrive(target, source, sources)
+}
+
+type joinArrivalsKey struct{}
+
+// sources returns the sources that finished, per join target, as saved in
+// checkpoints.
+func (ja joinArrivals) sources() map[string][]string {
+	if len(ja) == 0 {
+		return nil
+	}
+	saved := make(map[string][]string, len(ja))
+	for target, sources := range ja {
+		saved[target] = slices.Sorted(maps.Keys(sources))
+	}
+	return saved
+}
+
+// joinArrivalsOf returns the arrivals saved in checkpoint metadata by sources.
+// Stores that decode metadata from JSON return them as map[string]any.
+func joinArrivalsOf(value any) joinArrivals {
+	arrivals := make(joinArrivals)
+	add := func(target string, sources []string) {
+		for _, source := range sources {
+			if arrivals[target] == nil {
+				arrivals[target] = make(map[string]bool)
+			}
+			arrivals[target][source] = true
+		}
+	}
+	switch v := value.(type) {
+	case map[string][]string:
+		for target, sources := range v {
+			add(target, sources)
+		}
+	case map[string]any:
+		for target, sources := range v {
+			add(target, metadataStrings(sources))
+		}
+	}
+	return arrivals
+}
+
+// holdDeferred moves the deferred nodes of a superstep into waiting as long as
+// other work remains, and releases all waiting nodes once only deferred nodes are left.
+func (r *StateRunnable[S]) holdDeferred(nodes, waiting []string, otherWork bool) ([]string, []string) {
+	var ready []string
+	for _, name := range nodes {
+		if r.graph.nodes[name].Options.Defer {
+			if !slices.Contains(waiting, name) {
+				waiting = append(waiting, name)
+			}
+			continue
+		}
+		ready = append(ready, name)
+	}
+
+	if len(ready) > 0 || otherWork {
+		return ready, waiting
+	}
+	return waiting, nil
+}
+
+// mergeNodeLists appends the nodes of extra missing from nodes.
+func mergeNodeLists(nodes, extra []string) []string {
+	merged := slices.Clone(nodes)
+	for _, n := range extra {
+		if !slices.Contains(merged, n) {
+			merged = append(merged, n)
+		}
+	}
+	return merged
+}
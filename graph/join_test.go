@@ -0,0 +1,237 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// buildUnevenBranches builds start -> fast and start -> slow_1 -> slow_2, with both
+// branches converging on combine, and counts how often combine runs.
+func buildUnevenBranches(t *testing.T, join func(g *StateGraph[map[string]any]), opts ...NodeOption) (*StateRunnable[map[string]any], *int) {
+	schema := NewMapSchema()
+	schema.RegisterReducer("visited", AppendReducer)
+
+	g := NewStateGraph[map[string]any]()
+	g.SetSchema(schema)
+
+	for _, name := range []string{"start", "fast", "slow_1", "slow_2"} {
+		g.AddNode(name, name, func(ctx context.Context, state map[string]any) (map[string]any, error) {
+			return map[string]any{"visited": []string{name}}, nil
+		})
+	}
+
+	runs := 0
+	g.AddNode("combine", "combine", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		runs++
+		return map[string]any{"visited": []string{"combine"}, "seen": len(state["visited"].([]string))}, nil
+	}, opts...)
+
+	g.SetEntryPoint("start")
+	g.AddEdge("start", "fast")
+	g.AddEdge("start", "slow_1")
+	g.AddEdge("slow_1", "slow_2")
+	join(g)
+	g.AddEdge("combine", END)
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+	return runnable, &runs
+}
+
+func TestJoin_WithoutJoinRunsPerBranch(t *testing.T) {
+	runnable, runs := buildUnevenBranches(t, func(g *StateGraph[map[string]any]) {
+		g.AddEdge("fast", "combine")
+		g.AddEdge("slow_2", "combine")
+	})
+
+	_, err := runnable.Invoke(context.Background(), map[string]any{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, *runs)
+}
+
+func TestJoin_AddJoinEdge(t *testing.T) {
+	runnable, runs := buildUnevenBranches(t, func(g *StateGraph[map[string]any]) {
+		g.AddJoinEdge([]string{"fast", "slow_2"}, "combine")
+	})
+
+	result, err := runnable.Invoke(context.Background(), map[string]any{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, *runs)
+
+	// combine saw the output of every branch
+	assert.Equal(t, 4, result["seen"])
+	assert.Equal(t, []string{"start", "fast", "slow_1", "slow_2", "combine"}, result["visited"])
+}
+
+func TestJoin_Defer(t *testing.T) {
+	runnable, runs := buildUnevenBranches(t, func(g *StateGraph[map[string]any]) {
+		g.AddEdge("fast", "combine")
+		g.AddEdge("slow_2", "combine")
+	}, Defer())
+
+	result, err := runnable.Invoke(context.Background(), map[string]any{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, *runs)
+	assert.Equal(t, 4, result["seen"])
+}
+
+func TestJoin_ConditionalEdgeArrives(t *testing.T) {
+	runnable, runs := buildUnevenBranches(t, func(g *StateGraph[map[string]any]) {
+		g.AddJoinEdge([]string{"fast", "slow_2"}, "combine")
+		// The router of fast takes precedence over its static edge into the join
+		g.AddConditionalEdge("fast", func(ctx context.Context, state map[string]any) string {
+			return "combine"
+		})
+	})
+
+	result, err := runnable.Invoke(context.Background(), map[string]any{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, *runs)
+	assert.Equal(t, 4, result["seen"])
+	assert.Equal(t, []string{"start", "fast", "slow_1", "slow_2", "combine"}, result["visited"])
+}
+
+// buildGotoJoin is buildUnevenBranches with fast routing to combine through
+// the Goto of a Command, and returns the states combine ran with.
+func buildGotoJoin(t *testing.T, gotoCombine any) (*StateRunnable[any], *[]map[string]any) {
+	schema := NewMapSchema()
+	schema.RegisterReducer("visited", AppendReducer)
+
+	g := NewStateGraph[any]()
+	g.SetSchema(&mapSchemaAdapterForAny{MapSchema: schema})
+
+	for _, name := range []string{"start", "slow_1", "slow_2"} {
+		g.AddNode(name, name, func(ctx context.Context, state any) (any, error) {
+			return map[string]any{"visited": []string{name}}, nil
+		})
+	}
+	g.AddNode("fast", "fast", func(ctx context.Context, state any) (any, error) {
+		return &Command{Update: map[string]any{"visited": []string{"fast"}}, Goto: gotoCombine}, nil
+	})
+
+	var combined []map[string]any
+	g.AddNode("combine", "combine", func(ctx context.Context, state any) (any, error) {
+		combined = append(combined, state.(map[string]any))
+		return map[string]any{"visited": []string{"combine"}}, nil
+	})
+
+	g.SetEntryPoint("start")
+	g.AddEdge("start", "fast")
+	g.AddEdge("start", "slow_1")
+	g.AddEdge("slow_1", "slow_2")
+	g.AddJoinEdge([]string{"fast", "slow_2"}, "combine")
+	g.AddEdge("combine", END)
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+	return runnable, &combined
+}
+
+func TestJoin_CommandGotoArrives(t *testing.T) {
+	runnable, combined := buildGotoJoin(t, "combine")
+
+	result, err := runnable.Invoke(context.Background(), map[string]any{})
+	assert.NoError(t, err)
+	assert.Len(t, *combined, 1)
+	assert.Equal(t, []string{"start", "fast", "slow_1", "slow_2", "combine"}, result.(map[string]any)["visited"])
+}
+
+func TestJoin_SendArrives(t *testing.T) {
+	runnable, combined := buildGotoJoin(t, NewSend("combine", map[string]any{"early": true}))
+
+	// The Send runs right away with its own input, and its source counts as
+	// arrived: the join completes once slow_2 finishes
+	_, err := runnable.Invoke(context.Background(), map[string]any{})
+	assert.NoError(t, err)
+	assert.Len(t, *combined, 2)
+	assert.Equal(t, map[string]any{"early": true}, (*combined)[0])
+	assert.Equal(t, []string{"start", "fast", "slow_1", "slow_2", "combine"}, (*combined)[1]["visited"])
+}
+
+func TestJoin_ResumeKeepsArrivals(t *testing.T) {
+	g := NewCheckpointableStateGraph[map[string]any]()
+	schema := NewMapSchema()
+	schema.RegisterReducer("visited", AppendReducer)
+	g.SetSchema(schema)
+
+	for _, name := range []string{"start", "fast", "slow_1", "slow_2", "combine"} {
+		g.AddNode(name, name, func(ctx context.Context, state map[string]any) (map[string]any, error) {
+			return map[string]any{"visited": []string{name}}, nil
+		})
+	}
+	g.SetEntryPoint("start")
+	g.AddEdge("start", "fast")
+	g.AddEdge("start", "slow_1")
+	g.AddEdge("slow_1", "slow_2")
+	g.AddJoinEdge([]string{"fast", "slow_2"}, "combine")
+	g.AddEdge("combine", END)
+
+	runnable, err := g.CompileCheckpointable()
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	config := WithThreadID("join-thread")
+	config.InterruptBefore = []string{"slow_2"}
+	_, err = runnable.InvokeWithConfig(ctx, map[string]any{}, config)
+	var interrupt *GraphInterrupt
+	assert.ErrorAs(t, err, &interrupt)
+
+	// The arrival of fast is saved with the checkpoint
+	snapshot, err := runnable.GetState(ctx, WithThreadID("join-thread"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"combine": {"fast"}}, snapshot.Metadata["joins"])
+
+	result, err := runnable.InvokeWithConfig(ctx, map[string]any{}, WithThreadID("join-thread"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"start", "fast", "slow_1", "slow_2", "combine"}, result["visited"])
+}
+
+func TestJoin_ResumeKeepsWaitingDeferredNodes(t *testing.T) {
+	g := NewCheckpointableStateGraph[map[string]any]()
+	schema := NewMapSchema()
+	schema.RegisterReducer("visited", AppendReducer)
+	g.SetSchema(schema)
+
+	for _, name := range []string{"start", "fast", "slow_1"} {
+		g.AddNode(name, name, func(ctx context.Context, state map[string]any) (map[string]any, error) {
+			return map[string]any{"visited": []string{name}}, nil
+		})
+	}
+	g.AddNode("slow_2", "slow_2", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		if _, err := Interrupt(ctx, "approve"); err != nil {
+			return nil, err
+		}
+		return map[string]any{"visited": []string{"slow_2"}}, nil
+	})
+	g.AddNode("combine", "combine", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		return map[string]any{"visited": []string{"combine"}}, nil
+	}, Defer())
+	g.SetEntryPoint("start")
+	g.AddEdge("start", "fast")
+	g.AddEdge("start", "slow_1")
+	g.AddEdge("slow_1", "slow_2")
+	g.AddEdge("fast", "combine")
+	g.AddEdge("slow_2", END)
+	g.AddEdge("combine", END)
+
+	runnable, err := g.CompileCheckpointable()
+	assert.NoError(t, err)
+
+	// combine waits for slow_2 when slow_2 interrupts
+	ctx := context.Background()
+	_, err = runnable.InvokeWithConfig(ctx, map[string]any{}, WithThreadID("defer-thread"))
+	var interrupt *GraphInterrupt
+	assert.ErrorAs(t, err, &interrupt)
+
+	snapshot, err := runnable.GetState(ctx, WithThreadID("defer-thread"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"slow_2", "combine"}, snapshot.Next)
+
+	config := WithThreadID("defer-thread")
+	config.ResumeValue = "approved"
+	result, err := runnable.InvokeWithConfig(ctx, map[string]any{}, config)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"start", "fast", "slow_1", "slow_2", "combine"}, result["visited"])
+}
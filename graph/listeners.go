@@ -47,6 +47,9 @@ const (
 
 	// EventCustom indicates a custom user-defined event
 	EventCustom NodeEvent = "custom"
+
+	// EventGraphStep indicates a superstep has completed (carries the full state)
+	EventGraphStep NodeEvent = "graph_step"
 )
 
 // NodeListener defines the interface for typed node event listeners
@@ -83,6 +86,27 @@ type StreamEvent[S any] struct {
 	// Metadata contains additional event-specific data
 	Metadata map[string]any
 
+	// RunID is the ID of the run that emitted the event (for events emitted
+	// through a StreamWriter)
+	RunID string
+
+	// Data is the payload of events emitted through a StreamWriter, such as
+	// the text chunk of an EventToken
+	Data any
+
+	// Mode is the stream mode the event was emitted for
+	Mode StreamMode
+
+	// Step is the superstep the event belongs to (for events emitted by
+	// StateRunnable.Stream)
+	Step int
+
+	// Namespace is the namespace path of the node that generated the event,
+	// such as "parent_node:child_node" for a node of a subgraph. Events of a
+	// subgraph whose state type differs from S leave State zero and, in values
+	// mode, carry the subgraph state in Data.
+	Namespace string
+
 	// Duration is how long the node took (only for Complete events)
 	Duration time.Duration
 }
@@ -256,7 +280,7 @@ func NewListenableStateGraph[S any]() *ListenableStateGraph[S] {
 }
 
 // AddNode adds a node with listener capabilities
-func (g *ListenableStateGraph[S]) AddNode(name string, description string, fn func(ctx context.Context, state S) (S, error)) *ListenableNode[S] {
+func (g *ListenableStateGraph[S]) AddNode(name string, description string, fn func(ctx context.Context, state S) (S, error), opts ...NodeOption) *ListenableNode[S] {
 	node := TypedNode[S]{
 		Name:        name,
 		Description: description,
@@ -266,7 +290,7 @@ func (g *ListenableStateGraph[S]) AddNode(name string, description string, fn fu
 	listenableNode := NewListenableNode(node)
 
 	// Add to both the base graph and our listenable nodes map
-	g.StateGraph.AddNode(name, description, fn)
+	g.StateGraph.AddNode(name, description, fn, opts...)
 	g.listenableNodes[name] = listenableNode
 
 	return listenableNode
@@ -354,6 +378,7 @@ func (lr *ListenableRunnable[S]) Stream(ctx context.Context, initialState S) <-c
 	// Create a streaming listener
 	// Using DefaultStreamConfig from streaming.go
 	streamListener := NewStreamingListener(eventChan, DefaultStreamConfig())
+	ctx = withStreamSink(ctx, streamListener.emitData)
 
 	// Add the listener to all nodes
 	lr.graph.AddGlobalListener(streamListener)
@@ -0,0 +1,54 @@
+package graph
+
+import "context"
+
+// NamespaceSeparator separates the node names of a namespace path. The node
+// "search" of a subgraph added to its parent as the node "research" runs in the
+// namespace "research:search".
+const NamespaceSeparator = ":"
+
+// nodeScope describes the node running with a context, so that subgraphs it
+// invokes can place themselves under it.
+type nodeScope struct {
+	namespace string
+	runID     string
+	tracer    *Tracer
+}
+
+type nodeScopeKey struct{}
+
+func withNodeScope(ctx context.Context, scope nodeScope) context.Context {
+	return context.WithValue(ctx, nodeScopeKey{}, scope)
+}
+
+func currentScope(ctx context.Context) nodeScope {
+	scope, _ := ctx.Value(nodeScopeKey{}).(nodeScope)
+	return scope
+}
+
+// GetNamespace returns the namespace path of the node running with ctx, such as
+// "parent_node:child_node" for a node of a subgraph. Outside of a node it returns
+// the namespace of the graph: "" for a top-level graph, or the path of the node
+// that invoked a subgraph, which is what callbacks of a subgraph observe.
+func GetNamespace(ctx context.Context) string {
+	return currentScope(ctx).namespace
+}
+
+// SubgraphThreadID returns the thread ID under which the checkpoints of the
+// subgraph running in namespace are saved for the parent thread threadID.
+// Setting "checkpoint_ns" next to "thread_id" in Config.Configurable reads them
+// with GetState.
+func SubgraphThreadID(threadID, namespace string) string {
+	if namespace == "" {
+		return threadID
+	}
+	return threadID + "|" + namespace
+}
+
+// joinNamespace appends a node name to a namespace path.
+func joinNamespace(namespace, node string) string {
+	if namespace == "" {
+		return node
+	}
+	return namespace + NamespaceSeparator + node
+}
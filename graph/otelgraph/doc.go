@@ -0,0 +1,28 @@
+// Package otelgraph exports the execution of langgraphgo graphs to OpenTelemetry.
+//
+// An Exporter turns the spans of a graph.Tracer into OpenTelemetry spans named
+// "graph", "node <name>" and "edge <from> -> <to>", keeping their parent/child
+// links, and the LLM, tool and retriever callbacks of a run into spans that
+// follow the GenAI semantic conventions ("chat <model>", "execute_tool <name>").
+//
+// Spans carry the node name, namespace path, thread ID, run ID and retry count of
+// the nodes (see the attribute keys of this package); errors are recorded with an
+// error status, while interrupts are only flagged with langgraph.interrupted.
+//
+// # Usage
+//
+//	exporter := otelgraph.NewExporter(otelgraph.WithTracerProvider(provider))
+//
+//	tracer := graph.NewTracer()
+//	tracer.AddHook(exporter)
+//	runnable.SetTracer(tracer)
+//
+//	result, err := runnable.InvokeWithConfig(ctx, state, &graph.Config{
+//	    Callbacks:    []graph.CallbackHandler{exporter},
+//	    Configurable: map[string]any{"thread_id": "conversation-1"},
+//	})
+//
+// Without WithTracerProvider the global provider set with otel.SetTracerProvider
+// is used. Spans nest under the OpenTelemetry span found in the context of the
+// run, so a graph invoked from an instrumented HTTP handler joins its trace.
+package otelgraph
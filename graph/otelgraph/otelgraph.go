@@ -0,0 +1,471 @@
+package otelgraph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/smallnest/langgraphgo/graph"
+	"github.com/tmc/langchaingo/llms"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.32.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the spans created by the Exporter.
+const ScopeName = "github.com/smallnest/langgraphgo/graph/otelgraph"
+
+// Attributes set on the spans of graphs, nodes and edges.
+const (
+	// EventKey is the graph.TraceEvent that started the span.
+	EventKey = attribute.Key("langgraph.event")
+	// NodeNameKey is the name of the node.
+	NodeNameKey = attribute.Key("langgraph.node.name")
+	// NamespaceKey is the namespace path of the node, such as "parent_node:child_node".
+	NamespaceKey = attribute.Key("langgraph.namespace")
+	// ThreadIDKey is the "thread_id" of the run's Config.Configurable.
+	ThreadIDKey = attribute.Key("langgraph.thread_id")
+	// RunIDKey is the ID of the graph run.
+	RunIDKey = attribute.Key("langgraph.run_id")
+	// RetryCountKey is the number of times the node was retried.
+	RetryCountKey = attribute.Key("langgraph.node.retry_count")
+	// InterruptedKey is set on nodes that stopped the graph with an interrupt.
+	InterruptedKey = attribute.Key("langgraph.interrupted")
+	// EdgeFromKey is the source node of an edge traversal.
+	EdgeFromKey = attribute.Key("langgraph.edge.from")
+	// EdgeToKey is the destination node of an edge traversal.
+	EdgeToKey = attribute.Key("langgraph.edge.to")
+)
+
+// Option configures an Exporter.
+type Option func(*Exporter)
+
+// WithTracerProvider sets the provider of the OpenTelemetry tracer. The global
+// provider is used by default.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(e *Exporter) {
+		e.tracer = provider.Tracer(ScopeName)
+	}
+}
+
+// WithSystem sets the gen_ai.system reported for LLM calls whose callbacks do not
+// name a provider, such as "openai" or "anthropic".
+func WithSystem(system string) Option {
+	return func(e *Exporter) {
+		e.system = system
+	}
+}
+
+// Exporter maps the spans of a graph.Tracer and the LLM and tool callbacks of a
+// run to OpenTelemetry spans.
+//
+// Register it as a hook of the tracer for graph, node and edge spans, and as a
+// callback of the run for LLM, tool and retriever spans, which follow the GenAI
+// semantic conventions. Spans keep the parent/child links of the graph: nodes of
+// a subgraph nest under the node that invoked it, and retries are recorded as
+// events of their node's span.
+type Exporter struct {
+	tracer trace.Tracer
+	system string
+
+	mu     sync.Mutex
+	spans  map[string]trace.Span // open spans by graph.TraceSpan ID
+	runs   map[string]trace.Span // open spans by callback run ID
+	chains map[string]bool       // IDs of graph runs
+}
+
+var (
+	_ graph.TraceHook       = (*Exporter)(nil)
+	_ graph.CallbackHandler = (*Exporter)(nil)
+)
+
+// NewExporter creates an Exporter.
+func NewExporter(opts ...Option) *Exporter {
+	e := &Exporter{
+		tracer: otel.Tracer(ScopeName),
+		spans:  make(map[string]trace.Span),
+		runs:   make(map[string]trace.Span),
+		chains: make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// OnEvent implements graph.TraceHook.
+func (e *Exporter) OnEvent(ctx context.Context, span *graph.TraceSpan) {
+	switch {
+	case span.Event == graph.TraceEventEdgeTraversal:
+		e.traceEdge(ctx, span)
+	case span.Event == graph.TraceEventNodeRetry:
+		if !span.EndTime.IsZero() {
+			e.traceRetry(span)
+		}
+	case span.EndTime.IsZero():
+		// The executor reports a failed node twice: on its own span, which
+		// carries the error, and on a standalone error span that is skipped
+		if span.Event != graph.TraceEventNodeError {
+			e.startSpan(ctx, span)
+		}
+	default:
+		e.endSpan(span)
+	}
+}
+
+func (e *Exporter) startSpan(ctx context.Context, span *graph.TraceSpan) {
+	attrs := append(e.commonAttributes(ctx, span), NodeNameKey.String(span.NodeName))
+
+	name := "graph"
+	if span.Event != graph.TraceEventGraphStart {
+		name = "node " + span.NodeName
+	}
+
+	_, otelSpan := e.tracer.Start(e.parentContext(ctx, span.ParentID), name,
+		trace.WithTimestamp(span.StartTime),
+		trace.WithAttributes(attrs...),
+	)
+
+	e.mu.Lock()
+	e.spans[span.ID] = otelSpan
+	e.mu.Unlock()
+}
+
+func (e *Exporter) endSpan(span *graph.TraceSpan) {
+	e.mu.Lock()
+	otelSpan, ok := e.spans[span.ID]
+	delete(e.spans, span.ID)
+	e.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	// The executor tags spans with their run once they have started
+	if runID, ok := span.Metadata["run_id"].(string); ok && runID != "" {
+		otelSpan.SetAttributes(RunIDKey.String(runID))
+	}
+	if retries, ok := span.Metadata["retries"].(int); ok {
+		otelSpan.SetAttributes(RetryCountKey.Int(retries))
+	}
+	setError(otelSpan, span.Error)
+	otelSpan.End(trace.WithTimestamp(span.EndTime))
+}
+
+func (e *Exporter) traceEdge(ctx context.Context, span *graph.TraceSpan) {
+	attrs := append(e.commonAttributes(ctx, span),
+		EdgeFromKey.String(span.FromNode),
+		EdgeToKey.String(span.ToNode),
+	)
+
+	_, otelSpan := e.tracer.Start(e.parentContext(ctx, span.ParentID),
+		fmt.Sprintf("edge %s -> %s", span.FromNode, span.ToNode),
+		trace.WithTimestamp(span.StartTime),
+		trace.WithAttributes(attrs...),
+	)
+	otelSpan.End(trace.WithTimestamp(span.StartTime))
+}
+
+// traceRetry records a retry as an event of the span of the retried node.
+func (e *Exporter) traceRetry(span *graph.TraceSpan) {
+	e.mu.Lock()
+	nodeSpan, ok := e.spans[span.ParentID]
+	e.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	attrs := []attribute.KeyValue{NodeNameKey.String(span.NodeName)}
+	if attempt, ok := span.Metadata["attempt"].(int); ok {
+		attrs = append(attrs, attribute.Int("langgraph.retry.attempt", attempt))
+	}
+	if delay, ok := span.Metadata["delay"].(time.Duration); ok {
+		attrs = append(attrs, attribute.String("langgraph.retry.delay", delay.String()))
+	}
+	if span.Error != nil {
+		attrs = append(attrs, semconv.ExceptionMessage(span.Error.Error()))
+	}
+	nodeSpan.AddEvent("retry", trace.WithTimestamp(span.StartTime), trace.WithAttributes(attrs...))
+}
+
+// commonAttributes returns the attributes shared by the spans of a run.
+func (e *Exporter) commonAttributes(ctx context.Context, span *graph.TraceSpan) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{EventKey.String(string(span.Event))}
+	if namespace, ok := span.Metadata["namespace"].(string); ok {
+		attrs = append(attrs, NamespaceKey.String(namespace))
+	}
+	if runID, ok := span.Metadata["run_id"].(string); ok && runID != "" {
+		attrs = append(attrs, RunIDKey.String(runID))
+	}
+	if threadID := threadID(ctx); threadID != "" {
+		attrs = append(attrs, ThreadIDKey.String(threadID))
+	}
+	return attrs
+}
+
+// parentContext returns ctx carrying the OpenTelemetry span of the graph span
+// parentID, if it is open.
+func (e *Exporter) parentContext(ctx context.Context, parentID string) context.Context {
+	if parentID == "" {
+		return ctx
+	}
+	e.mu.Lock()
+	parent, ok := e.spans[parentID]
+	e.mu.Unlock()
+	if !ok {
+		return ctx
+	}
+	return trace.ContextWithSpan(ctx, parent)
+}
+
+// callbackContext returns ctx carrying the OpenTelemetry span a callback span
+// nests under: the span of its parent run, or of the node it runs in.
+func (e *Exporter) callbackContext(ctx context.Context, parentRunID *string) context.Context {
+	if parentRunID != nil {
+		e.mu.Lock()
+		parent, ok := e.runs[*parentRunID]
+		e.mu.Unlock()
+		if ok {
+			return trace.ContextWithSpan(ctx, parent)
+		}
+	}
+	if nodeSpan := graph.SpanFromContext(ctx); nodeSpan != nil {
+		return e.parentContext(ctx, nodeSpan.ID)
+	}
+	return ctx
+}
+
+// OnChainStart implements graph.CallbackHandler. Graph runs are traced through
+// the tracer hook, so it only remembers the run.
+func (e *Exporter) OnChainStart(ctx context.Context, serialized map[string]any, inputs map[string]any, runID string, parentRunID *string, tags []string, metadata map[string]any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.chains[runID] = true
+}
+
+// OnChainEnd implements graph.CallbackHandler.
+func (e *Exporter) OnChainEnd(ctx context.Context, outputs map[string]any, runID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.chains, runID)
+}
+
+// OnChainError implements graph.CallbackHandler.
+func (e *Exporter) OnChainError(ctx context.Context, err error, runID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.chains, runID)
+}
+
+// OnLLMStart implements graph.CallbackHandler. The span is named after the
+// operation and model, such as "chat gpt-4o", taken from serialized or metadata
+// ("operation", "model", "provider").
+func (e *Exporter) OnLLMStart(ctx context.Context, serialized map[string]any, prompts []string, runID string, parentRunID *string, tags []string, metadata map[string]any) {
+	operation := lookup(serialized, metadata, "operation")
+	if operation == "" {
+		operation = "chat"
+	}
+	model := lookup(serialized, metadata, "model")
+	system := lookup(serialized, metadata, "provider")
+	if system == "" {
+		system = e.system
+	}
+
+	attrs := []attribute.KeyValue{semconv.GenAIOperationNameKey.String(operation)}
+	if system != "" {
+		attrs = append(attrs, semconv.GenAISystemKey.String(system))
+	}
+	name := operation
+	if model != "" {
+		attrs = append(attrs, semconv.GenAIRequestModel(model))
+		name += " " + model
+	}
+	if threadID := threadID(ctx); threadID != "" {
+		attrs = append(attrs, ThreadIDKey.String(threadID))
+	}
+
+	e.startRun(ctx, runID, parentRunID, name, trace.SpanKindClient, attrs)
+}
+
+// OnLLMEnd implements graph.CallbackHandler. Token usage and finish reasons are
+// read from an *llms.ContentResponse.
+func (e *Exporter) OnLLMEnd(ctx context.Context, response any, runID string) {
+	span := e.endRun(runID)
+	if span == nil {
+		return
+	}
+
+	if resp, ok := response.(*llms.ContentResponse); ok && resp != nil {
+		var reasons []string
+		var input, output int
+		for _, choice := range resp.Choices {
+			if choice.StopReason != "" {
+				reasons = append(reasons, choice.StopReason)
+			}
+			input += tokenCount(choice.GenerationInfo, "PromptTokens", "InputTokens")
+			output += tokenCount(choice.GenerationInfo, "CompletionTokens", "OutputTokens")
+		}
+		if len(reasons) > 0 {
+			span.SetAttributes(semconv.GenAIResponseFinishReasons(reasons...))
+		}
+		if input > 0 {
+			span.SetAttributes(semconv.GenAIUsageInputTokens(input))
+		}
+		if output > 0 {
+			span.SetAttributes(semconv.GenAIUsageOutputTokens(output))
+		}
+	}
+	span.End()
+}
+
+// OnLLMError implements graph.CallbackHandler.
+func (e *Exporter) OnLLMError(ctx context.Context, err error, runID string) {
+	e.failRun(runID, err)
+}
+
+// OnToolStart implements graph.CallbackHandler. The executor also reports every
+// completed node as a tool of its graph run; those are covered by node spans and
+// skipped.
+func (e *Exporter) OnToolStart(ctx context.Context, serialized map[string]any, inputStr string, runID string, parentRunID *string, tags []string, metadata map[string]any) {
+	if parentRunID != nil {
+		e.mu.Lock()
+		nodeReport := e.chains[*parentRunID]
+		e.mu.Unlock()
+		if nodeReport {
+			return
+		}
+	}
+
+	toolName := lookup(serialized, metadata, "name")
+	attrs := []attribute.KeyValue{
+		semconv.GenAIOperationNameExecuteTool,
+		semconv.GenAIToolName(toolName),
+	}
+	if threadID := threadID(ctx); threadID != "" {
+		attrs = append(attrs, ThreadIDKey.String(threadID))
+	}
+
+	e.startRun(ctx, runID, parentRunID, "execute_tool "+toolName, trace.SpanKindInternal, attrs)
+}
+
+// OnToolEnd implements graph.CallbackHandler.
+func (e *Exporter) OnToolEnd(ctx context.Context, output string, runID string) {
+	if span := e.endRun(runID); span != nil {
+		span.End()
+	}
+}
+
+// OnToolError implements graph.CallbackHandler.
+func (e *Exporter) OnToolError(ctx context.Context, err error, runID string) {
+	e.failRun(runID, err)
+}
+
+// OnRetrieverStart implements graph.CallbackHandler.
+func (e *Exporter) OnRetrieverStart(ctx context.Context, serialized map[string]any, query string, runID string, parentRunID *string, tags []string, metadata map[string]any) {
+	name := "retrieve"
+	if retriever := lookup(serialized, metadata, "name"); retriever != "" {
+		name += " " + retriever
+	}
+	e.startRun(ctx, runID, parentRunID, name, trace.SpanKindInternal, nil)
+}
+
+// OnRetrieverEnd implements graph.CallbackHandler.
+func (e *Exporter) OnRetrieverEnd(ctx context.Context, documents []any, runID string) {
+	if span := e.endRun(runID); span != nil {
+		span.SetAttributes(attribute.Int("langgraph.retriever.documents", len(documents)))
+		span.End()
+	}
+}
+
+// OnRetrieverError implements graph.CallbackHandler.
+func (e *Exporter) OnRetrieverError(ctx context.Context, err error, runID string) {
+	e.failRun(runID, err)
+}
+
+func (e *Exporter) startRun(ctx context.Context, runID string, parentRunID *string, name string, kind trace.SpanKind, attrs []attribute.KeyValue) {
+	_, span := e.tracer.Start(e.callbackContext(ctx, parentRunID), name,
+		trace.WithSpanKind(kind),
+		trace.WithAttributes(attrs...),
+	)
+
+	e.mu.Lock()
+	e.runs[runID] = span
+	e.mu.Unlock()
+}
+
+// endRun removes and returns the open span of a callback run, or nil.
+func (e *Exporter) endRun(runID string) trace.Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	span, ok := e.runs[runID]
+	if !ok {
+		return nil
+	}
+	delete(e.runs, runID)
+	return span
+}
+
+func (e *Exporter) failRun(runID string, err error) {
+	if span := e.endRun(runID); span != nil {
+		setError(span, err)
+		span.End()
+	}
+}
+
+// setError records err on span. Interrupts pause the graph rather than fail it,
+// so they are flagged without an error status.
+func setError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	var nodeInterrupt *graph.NodeInterrupt
+	var graphInterrupt *graph.GraphInterrupt
+	if errors.As(err, &nodeInterrupt) || errors.As(err, &graphInterrupt) {
+		span.SetAttributes(InterruptedKey.Bool(true))
+		return
+	}
+	span.RecordError(err)
+	span.SetAttributes(semconv.ErrorTypeKey.String(fmt.Sprintf("%T", err)))
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// threadID returns the thread ID of the run executing with ctx.
+func threadID(ctx context.Context) string {
+	if config := graph.GetConfig(ctx); config != nil {
+		if id, ok := config.Configurable["thread_id"].(string); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// lookup returns the string value of key in the first map that has it.
+func lookup(serialized, metadata map[string]any, key string) string {
+	if v, ok := serialized[key].(string); ok && v != "" {
+		return v
+	}
+	if v, ok := metadata[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// tokenCount returns the first of the keys found in a choice's generation info.
+func tokenCount(info map[string]any, keys ...string) int {
+	for _, key := range keys {
+		switch v := info[key].(type) {
+		case int:
+			return v
+		case int32:
+			return int(v)
+		case int64:
+			return int(v)
+		case float64:
+			return int(v)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,164 @@
+package otelgraph
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/smallnest/langgraphgo/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTestExporter() (*Exporter, *tracetest.InMemoryExporter) {
+	memory := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(memory))
+	return NewExporter(WithTracerProvider(provider), WithSystem("openai")), memory
+}
+
+func spanByName(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, s := range spans {
+		if s.Name == name {
+			return s
+		}
+	}
+	require.Failf(t, "span not found", "no span named %q", name)
+	return tracetest.SpanStub{}
+}
+
+func attrs(s tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value)
+	for _, kv := range s.Attributes {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestExporter_NodeSpans(t *testing.T) {
+	exporter, memory := newTestExporter()
+
+	attempts := 0
+	child := graph.NewStateGraph[map[string]any]()
+	child.AddNode("flaky", "flaky", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("temporary failure")
+		}
+		// An LLM call made by the node nests under its span
+		exporter.OnLLMStart(ctx, map[string]any{"model": "gpt-4o"}, []string{"hi"}, "llm-1", nil, nil, nil)
+		exporter.OnLLMEnd(ctx, &llms.ContentResponse{Choices: []*llms.ContentChoice{{
+			StopReason:     "stop",
+			GenerationInfo: map[string]any{"PromptTokens": 12, "CompletionTokens": 5},
+		}}}, "llm-1")
+		return state, nil
+	}, graph.WithRetry(&graph.RetryConfig{MaxAttempts: 3}))
+	child.SetEntryPoint("flaky")
+	child.AddEdge("flaky", graph.END)
+
+	g := graph.NewStateGraph[map[string]any]()
+	identity := func(s map[string]any) map[string]any { return s }
+	require.NoError(t, graph.AddSubgraph(g, "research", child, identity, identity))
+	g.AddNode("broken", "broken", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		return nil, errors.New("boom")
+	})
+	g.SetEntryPoint("research")
+	g.AddEdge("research", "broken")
+	g.AddEdge("broken", graph.END)
+
+	runnable, err := g.Compile()
+	require.NoError(t, err)
+	tracer := graph.NewTracer()
+	tracer.AddHook(exporter)
+	runnable.SetTracer(tracer)
+
+	_, err = runnable.InvokeWithConfig(context.Background(), map[string]any{}, &graph.Config{
+		Callbacks:    []graph.CallbackHandler{exporter},
+		Configurable: map[string]any{"thread_id": "thread-1"},
+	})
+	require.Error(t, err)
+
+	spans := memory.GetSpans()
+
+	// The subgraph and its node nest under the node that invoked it
+	research := spanByName(t, spans, "node research")
+	var subgraph tracetest.SpanStub
+	for _, s := range spans {
+		if s.Name == "graph" && s.Parent.SpanID() == research.SpanContext.SpanID() {
+			subgraph = s
+		}
+	}
+	require.True(t, subgraph.SpanContext.IsValid(), "no subgraph span under node research")
+	flaky := spanByName(t, spans, "node flaky")
+	assert.Equal(t, subgraph.SpanContext.SpanID(), flaky.Parent.SpanID())
+
+	// Node attributes
+	flakyAttrs := attrs(flaky)
+	assert.Equal(t, "flaky", flakyAttrs[NodeNameKey].AsString())
+	assert.Equal(t, "research:flaky", flakyAttrs[NamespaceKey].AsString())
+	assert.Equal(t, "thread-1", flakyAttrs[ThreadIDKey].AsString())
+	assert.NotEmpty(t, flakyAttrs[RunIDKey].AsString())
+	assert.Equal(t, int64(2), flakyAttrs[RetryCountKey].AsInt64())
+	assert.Len(t, flaky.Events, 2)
+	assert.Equal(t, "retry", flaky.Events[0].Name)
+	assert.Equal(t, codes.Unset, flaky.Status.Code)
+
+	// Errors set the status of the node span
+	broken := spanByName(t, spans, "node broken")
+	assert.Equal(t, codes.Error, broken.Status.Code)
+	assert.Equal(t, "boom", broken.Status.Description)
+
+	// LLM calls follow the GenAI semantic conventions
+	chat := spanByName(t, spans, "chat gpt-4o")
+	assert.Equal(t, flaky.SpanContext.SpanID(), chat.Parent.SpanID())
+	chatAttrs := attrs(chat)
+	assert.Equal(t, "chat", chatAttrs["gen_ai.operation.name"].AsString())
+	assert.Equal(t, "openai", chatAttrs["gen_ai.system"].AsString())
+	assert.Equal(t, "gpt-4o", chatAttrs["gen_ai.request.model"].AsString())
+	assert.Equal(t, int64(12), chatAttrs["gen_ai.usage.input_tokens"].AsInt64())
+	assert.Equal(t, int64(5), chatAttrs["gen_ai.usage.output_tokens"].AsInt64())
+	assert.Equal(t, []string{"stop"}, chatAttrs["gen_ai.response.finish_reasons"].AsStringSlice())
+
+	// Node completions reported as tools by the executor do not create spans
+	for _, s := range spans {
+		assert.NotContains(t, s.Name, "execute_tool")
+	}
+}
+
+func TestExporter_ToolAndEdgeSpans(t *testing.T) {
+	exporter, memory := newTestExporter()
+	ctx := context.Background()
+
+	parent := "agent-run"
+	exporter.OnToolStart(ctx, map[string]any{"name": "search"}, "query", "tool-1", &parent, nil, nil)
+	exporter.OnToolError(ctx, errors.New("timeout"), "tool-1")
+
+	start := time.Now()
+	exporter.OnEvent(ctx, &graph.TraceSpan{
+		ID:        "edge-1",
+		Event:     graph.TraceEventEdgeTraversal,
+		FromNode:  "a",
+		ToNode:    "b",
+		StartTime: start,
+		EndTime:   start,
+		Metadata:  map[string]any{},
+	})
+
+	spans := memory.GetSpans()
+	require.Len(t, spans, 2)
+
+	tool := spanByName(t, spans, "execute_tool search")
+	assert.Equal(t, "execute_tool", attrs(tool)["gen_ai.operation.name"].AsString())
+	assert.Equal(t, "search", attrs(tool)["gen_ai.tool.name"].AsString())
+	assert.Equal(t, codes.Error, tool.Status.Code)
+
+	edge := spanByName(t, spans, "edge a -> b")
+	assert.Equal(t, "a", attrs(edge)[EdgeFromKey].AsString())
+	assert.Equal(t, "b", attrs(edge)[EdgeToKey].AsString())
+}
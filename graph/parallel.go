@@ -112,7 +112,9 @@ func (g *StateGraph[S]) AddParallelNodes(
 	})
 }
 
-// MapReduceNode executes nodes in parallel and reduces results
+// MapReduceNode executes nodes in parallel and reduces results.
+// The set of map nodes is fixed when the graph is built; use Send when the
+// number of branches is only known at runtime.
 type MapReduceNode[S any] struct {
 	name     string
 	mapNodes []TypedNode[S]
@@ -174,6 +176,8 @@ func (g *StateGraph[S]) AddMapReduceNode(
 
 // FanOutFanIn creates a fan-out/fan-in pattern.
 // aggregator merges worker results into a state S that is passed to the collector.
+// All workers run within a single step; to join graph branches of different
+// lengths, use AddJoinEdge or a node added with Defer instead.
 func (g *StateGraph[S]) FanOutFanIn(
 	source string,
 	_ []string, // workers parameter kept for API compatibility
@@ -125,3 +125,120 @@ func TestParallelExecution_FanOut(t *testing.T) {
 	assert.True(t, hasB, "Node B should be visited")
 	assert.True(t, hasC, "Node C should be visited")
 }
+
+func TestParallelExecution_DeterministicFanOutOrder(t *testing.T) {
+	// Without a schema the last result wins, so the winner must be the branch
+	// whose edge was declared last, on every run.
+	for range 20 {
+		g := NewStateGraph[map[string]any]()
+		g.AddNode("start", "start", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+			return state, nil
+		})
+		for _, name := range []string{"c", "a", "d", "b"} {
+			g.AddNode(name, name, func(ctx context.Context, state map[string]any) (map[string]any, error) {
+				return map[string]any{"winner": name}, nil
+			})
+			g.AddEdge(name, END)
+		}
+		g.SetEntryPoint("start")
+		for _, name := range []string{"c", "a", "d", "b"} {
+			g.AddEdge("start", name)
+		}
+
+		runnable, err := g.Compile()
+		assert.NoError(t, err)
+
+		next, _, _, err := runnable.determineNextNodes(context.Background(), []string{"start"}, nil, nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"c", "a", "d", "b"}, next)
+
+		result, err := runnable.Invoke(context.Background(), map[string]any{})
+		assert.NoError(t, err)
+		assert.Equal(t, "b", result["winner"])
+	}
+}
+
+func TestParallelExecution_ConflictDetection(t *testing.T) {
+	build := func(schema StateSchema[map[string]any]) *StateRunnable[map[string]any] {
+		g := NewStateGraph[map[string]any]()
+		if schema != nil {
+			g.SetSchema(schema)
+		}
+		g.AddNode("start", "start", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+			return map[string]any{"shared": "start"}, nil
+		})
+		g.AddNode("left", "left", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+			return map[string]any{"shared": "left", "left": true}, nil
+		})
+		g.AddNode("right", "right", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+			return map[string]any{"shared": "right", "right": true}, nil
+		})
+		g.SetEntryPoint("start")
+		g.AddEdge("start", "left")
+		g.AddEdge("start", "right")
+		g.AddEdge("left", END)
+		g.AddEdge("right", END)
+
+		runnable, err := g.Compile()
+		assert.NoError(t, err)
+		runnable.SetConflictDetection(true)
+		return runnable
+	}
+
+	t.Run("conflicting writes fail", func(t *testing.T) {
+		_, err := build(NewMapSchema()).Invoke(context.Background(), map[string]any{})
+		var conflictErr *ConflictingWriteError
+		assert.ErrorAs(t, err, &conflictErr)
+		assert.Equal(t, "shared", conflictErr.Key)
+		assert.Equal(t, []string{"left", "right"}, conflictErr.Nodes)
+		assert.Equal(t, 2, conflictErr.Step)
+	})
+
+	t.Run("keys with reducers may be written concurrently", func(t *testing.T) {
+		schema := NewMapSchema()
+		schema.RegisterReducer("shared", func(current, next any) (any, error) {
+			return next, nil
+		})
+		result, err := build(schema).Invoke(context.Background(), map[string]any{})
+		assert.NoError(t, err)
+		assert.Equal(t, true, result["left"])
+		assert.Equal(t, true, result["right"])
+	})
+
+	t.Run("writes in place are detected", func(t *testing.T) {
+		g := NewStateGraph[map[string]any]()
+		g.SetSchema(NewMapSchema())
+		g.AddNode("start", "start", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+			return map[string]any{"shared": "start"}, nil
+		})
+		g.AddNode("left", "left", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+			state["shared"] = "left"
+			return state, nil
+		})
+		g.AddNode("right", "right", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+			return map[string]any{"shared": "right"}, nil
+		})
+		g.SetEntryPoint("start")
+		g.AddEdge("start", "left")
+		g.AddEdge("start", "right")
+		g.AddEdge("left", END)
+		g.AddEdge("right", END)
+
+		runnable, err := g.Compile()
+		assert.NoError(t, err)
+		runnable.SetConflictDetection(true)
+
+		_, err = runnable.Invoke(context.Background(), map[string]any{})
+		var conflictErr *ConflictingWriteError
+		assert.ErrorAs(t, err, &conflictErr)
+		assert.Equal(t, []string{"left", "right"}, conflictErr.Nodes)
+	})
+
+	t.Run("last write wins when disabled", func(t *testing.T) {
+		runnable := build(NewMapSchema())
+		runnable.SetConflictDetection(false)
+		result, err := runnable.Invoke(context.Background(), map[string]any{})
+		assert.NoError(t, err)
+		assert.Equal(t, "right", result["shared"])
+	})
+}
@@ -0,0 +1,35 @@
+// Package replay records complete runs of langgraphgo graphs and replays them
+// offline in a browser.
+//
+// A Recorder writes a run to a JSONL trace, one Record per line: the graph and
+// node spans with their input and output state, retries, the edges taken, the
+// state after each superstep, and the prompts and responses of LLM calls, tool
+// calls and retrievals. RenderHTML turns a trace into a single HTML page, which
+// loads nothing else, with a timeline of the supersteps and the diagram of the
+// graph, where the nodes that ran are highlighted; clicking a node shows its
+// state diff.
+//
+// # Usage
+//
+//	recorder, err := replay.Create("run.jsonl",
+//	    replay.WithDiagram(graph.NewExporter(g).Diagram()))
+//	if err != nil {
+//	    return err
+//	}
+//	defer recorder.Close()
+//
+//	tracer := graph.NewTracer()
+//	tracer.AddHook(recorder)
+//	runnable.SetTracer(tracer)
+//
+//	result, err := runnable.InvokeWithConfig(ctx, state, &graph.Config{
+//	    Callbacks: []graph.CallbackHandler{recorder},
+//	})
+//
+// Then render the trace:
+//
+//	err = replay.RenderFile("run.jsonl", "run.html")
+//
+// States are recorded as JSON; values JSON cannot represent are recorded as
+// their string form.
+package replay
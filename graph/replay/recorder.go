@@ -0,0 +1,479 @@
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/smallnest/langgraphgo/graph"
+)
+
+// Kind is the kind of a Record.
+type Kind string
+
+const (
+	// KindGraph holds the diagram of the graph that ran.
+	KindGraph Kind = "graph"
+	// KindSpanStart is the start of a graph or node span, with its input state.
+	KindSpanStart Kind = "span_start"
+	// KindSpanEnd is the end of a graph or node span, with its output state.
+	KindSpanEnd Kind = "span_end"
+	// KindRetry is a failed node attempt that is about to be retried.
+	KindRetry Kind = "retry"
+	// KindEdge is an edge taken from one node to another.
+	KindEdge Kind = "edge"
+	// KindStep is the state after a superstep.
+	KindStep Kind = "step"
+	// KindRunStart, KindRunEnd and KindRunError are the chain callbacks of a run.
+	KindRunStart Kind = "run_start"
+	KindRunEnd   Kind = "run_end"
+	KindRunError Kind = "run_error"
+	// KindLLMStart, KindLLMEnd and KindLLMError record an LLM prompt and response.
+	KindLLMStart Kind = "llm_start"
+	KindLLMEnd   Kind = "llm_end"
+	KindLLMError Kind = "llm_error"
+	// KindToolStart, KindToolEnd and KindToolError record a tool call.
+	KindToolStart Kind = "tool_start"
+	KindToolEnd   Kind = "tool_end"
+	KindToolError Kind = "tool_error"
+	// KindRetrieverStart, KindRetrieverEnd and KindRetrieverError record a retrieval.
+	KindRetrieverStart Kind = "retriever_start"
+	KindRetrieverEnd   Kind = "retriever_end"
+	KindRetrieverError Kind = "retriever_error"
+)
+
+// Record is one line of a run trace.
+type Record struct {
+	Kind Kind      `json:"kind"`
+	Time time.Time `json:"time"`
+
+	// SpanID is the ID of the span of span, retry and edge records. ParentID is
+	// the span they nest under; for callbacks, the span of the node that made
+	// the call.
+	SpanID   string           `json:"span_id,omitempty"`
+	ParentID string           `json:"parent_id,omitempty"`
+	Event    graph.TraceEvent `json:"event,omitempty"`
+
+	Node      string `json:"node,omitempty"`
+	From      string `json:"from,omitempty"`
+	To        string `json:"to,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+
+	// Step is the superstep of the graph span ParentID that a node ran in, or
+	// that a step record completed.
+	Step int `json:"step,omitempty"`
+
+	// RunID is the ID of the graph run of spans, or of the callback run.
+	RunID       string `json:"run_id,omitempty"`
+	ParentRunID string `json:"parent_run_id,omitempty"`
+
+	// Name is the model, tool or retriever of a callback.
+	Name string `json:"name,omitempty"`
+
+	State    json.RawMessage `json:"state,omitempty"`
+	Prompts  []string        `json:"prompts,omitempty"`
+	Input    string          `json:"input,omitempty"`
+	Output   json.RawMessage `json:"output,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Duration time.Duration   `json:"duration,omitempty"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+
+	// Diagram is the structure of the graph of a graph record.
+	Diagram *graph.Diagram `json:"diagram,omitempty"`
+}
+
+// Option configures a Recorder.
+type Option func(*Recorder)
+
+// WithDiagram stores the structure of the graph, as returned by
+// graph.Exporter.Diagram, at the top of the trace so that the replay viewer
+// can draw it.
+func WithDiagram(diagram graph.Diagram) Option {
+	return func(r *Recorder) {
+		r.diagram = &diagram
+	}
+}
+
+// Recorder writes a complete run to a JSONL trace, one Record per line: every
+// span with its state, every edge taken, the state after each superstep, and
+// the LLM prompts and responses, tool calls and retrievals of the run.
+//
+// Register it as a hook of the graph's Tracer for spans and edges, and as a
+// callback of the run for supersteps and calls. Render the trace with RenderHTML.
+type Recorder struct {
+	diagram *graph.Diagram
+
+	mu     sync.Mutex
+	enc    *json.Encoder
+	buf    *bufio.Writer
+	closer io.Closer
+	err    error
+
+	started map[string]bool // spans whose start was recorded
+	steps   map[string]int  // completed supersteps by graph span ID
+	chains  map[string]bool // IDs of graph runs
+	skipped map[string]bool // node completions the executor reports as tools
+}
+
+var (
+	_ graph.TraceHook            = (*Recorder)(nil)
+	_ graph.GraphCallbackHandler = (*Recorder)(nil)
+)
+
+// NewRecorder creates a Recorder writing to w.
+func NewRecorder(w io.Writer, opts ...Option) *Recorder {
+	r := &Recorder{
+		buf:     bufio.NewWriter(w),
+		started: make(map[string]bool),
+		steps:   make(map[string]int),
+		chains:  make(map[string]bool),
+		skipped: make(map[string]bool),
+	}
+	r.enc = json.NewEncoder(r.buf)
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.diagram != nil {
+		r.write(Record{Kind: KindGraph, Time: time.Now(), Diagram: r.diagram})
+	}
+	return r
+}
+
+// Create creates the file path and returns a Recorder writing to it. Close
+// flushes the trace and closes the file.
+func Create(path string, opts ...Option) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace file: %w", err)
+	}
+	r := NewRecorder(f, opts...)
+	r.closer = f
+	return r, nil
+}
+
+// Close flushes the trace and closes the file of a Recorder made by Create. It
+// returns the first error that occurred while writing.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.err
+	if flushErr := r.buf.Flush(); err == nil {
+		err = flushErr
+	}
+	if r.closer != nil {
+		if closeErr := r.closer.Close(); err == nil {
+			err = closeErr
+		}
+		r.closer = nil
+	}
+	return err
+}
+
+// write appends a record to the trace, keeping the first error. Each record is
+// flushed so that a trace survives a crash of the run it records.
+func (r *Recorder) write(rec Record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.writeLocked(rec)
+}
+
+func (r *Recorder) writeLocked(rec Record) {
+	if r.err != nil {
+		return
+	}
+	if err := r.enc.Encode(rec); err != nil {
+		r.err = err
+		return
+	}
+	r.err = r.buf.Flush()
+}
+
+// OnEvent implements graph.TraceHook.
+func (r *Recorder) OnEvent(ctx context.Context, span *graph.TraceSpan) {
+	rec := Record{
+		SpanID:    span.ID,
+		ParentID:  span.ParentID,
+		Event:     span.Event,
+		Node:      span.NodeName,
+		Namespace: metadataString(span.Metadata, "namespace"),
+		RunID:     metadataString(span.Metadata, "run_id"),
+		State:     encode(span.State),
+		Error:     errorString(span.Error),
+		Metadata:  encode(span.Metadata),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case span.Event == graph.TraceEventEdgeTraversal:
+		rec.Kind = KindEdge
+		rec.Time = span.StartTime
+		rec.From = span.FromNode
+		rec.To = span.ToNode
+		rec.Node = ""
+		rec.State = nil
+	case span.Event == graph.TraceEventNodeRetry:
+		// Retries are complete once they end
+		if span.EndTime.IsZero() {
+			return
+		}
+		rec.Kind = KindRetry
+		rec.Time = span.EndTime
+	case span.EndTime.IsZero():
+		// The executor reports a failed node twice: on its own span, which
+		// carries the error, and on a standalone error span that is skipped
+		if span.Event == graph.TraceEventNodeError {
+			return
+		}
+		r.started[span.ID] = true
+		rec.Kind = KindSpanStart
+		rec.Time = span.StartTime
+		if span.Event == graph.TraceEventNodeStart {
+			rec.Step = r.steps[span.ParentID] + 1
+		}
+	default:
+		if !r.started[span.ID] {
+			return
+		}
+		delete(r.started, span.ID)
+		rec.Kind = KindSpanEnd
+		rec.Time = span.EndTime
+		rec.Duration = span.Duration
+	}
+	r.writeLocked(rec)
+}
+
+// OnGraphStep implements graph.GraphCallbackHandler.
+func (r *Recorder) OnGraphStep(ctx context.Context, stepNode string, state any) {
+	rec := Record{
+		Kind:      KindStep,
+		Time:      time.Now(),
+		Node:      stepNode,
+		Namespace: graph.GetNamespace(ctx),
+		State:     encode(state),
+	}
+	if span := graph.SpanFromContext(ctx); span != nil {
+		rec.ParentID = span.ID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps[rec.ParentID]++
+	rec.Step = r.steps[rec.ParentID]
+	r.writeLocked(rec)
+}
+
+// OnChainStart implements graph.CallbackHandler.
+func (r *Recorder) OnChainStart(ctx context.Context, serialized map[string]any, inputs map[string]any, runID string, parentRunID *string, tags []string, metadata map[string]any) {
+	r.mu.Lock()
+	r.chains[runID] = true
+	r.mu.Unlock()
+
+	rec := r.callback(ctx, KindRunStart, runID, parentRunID)
+	rec.Namespace = graph.GetNamespace(ctx)
+	rec.State = encode(inputs)
+	r.write(rec)
+}
+
+// OnChainEnd implements graph.CallbackHandler.
+func (r *Recorder) OnChainEnd(ctx context.Context, outputs map[string]any, runID string) {
+	r.endChain(runID)
+	rec := r.callback(ctx, KindRunEnd, runID, nil)
+	rec.State = encode(outputs)
+	r.write(rec)
+}
+
+// OnChainError implements graph.CallbackHandler.
+func (r *Recorder) OnChainError(ctx context.Context, err error, runID string) {
+	r.endChain(runID)
+	rec := r.callback(ctx, KindRunError, runID, nil)
+	rec.Error = errorString(err)
+	r.write(rec)
+}
+
+func (r *Recorder) endChain(runID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.chains, runID)
+}
+
+// OnLLMStart implements graph.CallbackHandler.
+func (r *Recorder) OnLLMStart(ctx context.Context, serialized map[string]any, prompts []string, runID string, parentRunID *string, tags []string, metadata map[string]any) {
+	rec := r.callback(ctx, KindLLMStart, runID, parentRunID)
+	rec.Name = lookup(serialized, metadata, "model", "name")
+	rec.Prompts = prompts
+	r.write(rec)
+}
+
+// OnLLMEnd implements graph.CallbackHandler.
+func (r *Recorder) OnLLMEnd(ctx context.Context, response any, runID string) {
+	rec := r.callback(ctx, KindLLMEnd, runID, nil)
+	rec.Output = encode(response)
+	r.write(rec)
+}
+
+// OnLLMError implements graph.CallbackHandler.
+func (r *Recorder) OnLLMError(ctx context.Context, err error, runID string) {
+	rec := r.callback(ctx, KindLLMError, runID, nil)
+	rec.Error = errorString(err)
+	r.write(rec)
+}
+
+// OnToolStart implements graph.CallbackHandler. The executor also reports each
+// completed node as a tool of its graph run; those reports are skipped since
+// the node's span already records them.
+func (r *Recorder) OnToolStart(ctx context.Context, serialized map[string]any, inputStr string, runID string, parentRunID *string, tags []string, metadata map[string]any) {
+	if parentRunID != nil {
+		r.mu.Lock()
+		nodeReport := r.chains[*parentRunID]
+		if nodeReport {
+			r.skipped[runID] = true
+		}
+		r.mu.Unlock()
+		if nodeReport {
+			return
+		}
+	}
+
+	rec := r.callback(ctx, KindToolStart, runID, parentRunID)
+	rec.Name = lookup(serialized, metadata, "name")
+	rec.Input = inputStr
+	r.write(rec)
+}
+
+// OnToolEnd implements graph.CallbackHandler.
+func (r *Recorder) OnToolEnd(ctx context.Context, output string, runID string) {
+	if r.skipTool(runID) {
+		return
+	}
+	rec := r.callback(ctx, KindToolEnd, runID, nil)
+	rec.Output = encode(output)
+	r.write(rec)
+}
+
+// OnToolError implements graph.CallbackHandler.
+func (r *Recorder) OnToolError(ctx context.Context, err error, runID string) {
+	if r.skipTool(runID) {
+		return
+	}
+	rec := r.callback(ctx, KindToolError, runID, nil)
+	rec.Error = errorString(err)
+	r.write(rec)
+}
+
+func (r *Recorder) skipTool(runID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.skipped[runID] {
+		delete(r.skipped, runID)
+		return true
+	}
+	return false
+}
+
+// OnRetrieverStart implements graph.CallbackHandler.
+func (r *Recorder) OnRetrieverStart(ctx context.Context, serialized map[string]any, query string, runID string, parentRunID *string, tags []string, metadata map[string]any) {
+	rec := r.callback(ctx, KindRetrieverStart, runID, parentRunID)
+	rec.Name = lookup(serialized, metadata, "name")
+	rec.Input = query
+	r.write(rec)
+}
+
+// OnRetrieverEnd implements graph.CallbackHandler.
+func (r *Recorder) OnRetrieverEnd(ctx context.Context, documents []any, runID string) {
+	rec := r.callback(ctx, KindRetrieverEnd, runID, nil)
+	rec.Output = encode(documents)
+	r.write(rec)
+}
+
+// OnRetrieverError implements graph.CallbackHandler.
+func (r *Recorder) OnRetrieverError(ctx context.Context, err error, runID string) {
+	rec := r.callback(ctx, KindRetrieverError, runID, nil)
+	rec.Error = errorString(err)
+	r.write(rec)
+}
+
+// callback starts a record of a callback, nested under the span of the node
+// running with ctx.
+func (r *Recorder) callback(ctx context.Context, kind Kind, runID string, parentRunID *string) Record {
+	rec := Record{Kind: kind, Time: time.Now(), RunID: runID}
+	if parentRunID != nil {
+		rec.ParentRunID = *parentRunID
+	}
+	if span := graph.SpanFromContext(ctx); span != nil {
+		rec.ParentID = span.ID
+	}
+	return rec
+}
+
+// ReadFile reads the trace written to path by a Recorder.
+func ReadFile(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace file: %w", err)
+	}
+	defer f.Close()
+	return ReadRecords(f)
+}
+
+// ReadRecords reads the records of a trace written by a Recorder.
+func ReadRecords(r io.Reader) ([]Record, error) {
+	var records []Record
+	dec := json.NewDecoder(r)
+	for {
+		var rec Record
+		if err := dec.Decode(&rec); err != nil {
+			if errors.Is(err, io.EOF) {
+				return records, nil
+			}
+			return records, fmt.Errorf("failed to read trace record %d: %w", len(records)+1, err)
+		}
+		records = append(records, rec)
+	}
+}
+
+// encode marshals v to JSON, falling back to its string form for values that
+// JSON cannot represent, such as functions and channels.
+func encode(v any) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprintf("%+v", v))
+	}
+	return data
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func metadataString(metadata map[string]any, key string) string {
+	s, _ := metadata[key].(string)
+	return s
+}
+
+// lookup returns the first of keys set to a string in serialized or metadata.
+func lookup(serialized, metadata map[string]any, keys ...string) string {
+	for _, key := range keys {
+		if s := metadataString(serialized, key); s != "" {
+			return s
+		}
+		if s := metadataString(metadata, key); s != "" {
+			return s
+		}
+	}
+	return ""
+}
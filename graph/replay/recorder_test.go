@@ -0,0 +1,140 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/smallnest/langgraphgo/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runRecorded runs a graph whose "plan" node calls an LLM and a tool and whose
+// "act" node is retried once, recording it with its diagram to a trace written
+// to w.
+func runRecorded(t *testing.T, w io.Writer) {
+	t.Helper()
+
+	var recorder *Recorder
+	attempts := 0
+	g := graph.NewStateGraph[map[string]any]()
+	g.AddNode("plan", "plan", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		recorder.OnLLMStart(ctx, map[string]any{"model": "gpt-4o"}, []string{"what next?"}, "llm-1", nil, nil, nil)
+		recorder.OnLLMEnd(ctx, map[string]any{"text": "search"}, "llm-1")
+		recorder.OnToolStart(ctx, map[string]any{"name": "search"}, "weather", "tool-1", nil, nil, nil)
+		recorder.OnToolEnd(ctx, "sunny", "tool-1")
+		return map[string]any{"plan": "search", "count": 1}, nil
+	})
+	g.AddNode("act", "act", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		attempts++
+		if attempts < 2 {
+			return nil, errors.New("temporary failure")
+		}
+		return map[string]any{"result": "sunny", "count": 2}, nil
+	}, graph.WithRetry(&graph.RetryConfig{MaxAttempts: 2}))
+	g.SetEntryPoint("plan")
+	g.AddEdge("plan", "act")
+	g.AddEdge("act", graph.END)
+
+	recorder = NewRecorder(w, WithDiagram(graph.NewExporter(g).Diagram()))
+	runnable, err := g.Compile()
+	require.NoError(t, err)
+	tracer := graph.NewTracer()
+	tracer.AddHook(recorder)
+	runnable.SetTracer(tracer)
+
+	_, err = runnable.InvokeWithConfig(context.Background(), map[string]any{"count": 0}, &graph.Config{
+		Callbacks: []graph.CallbackHandler{recorder},
+	})
+	require.NoError(t, err)
+	require.NoError(t, recorder.Close())
+}
+
+func recordsOf(records []Record, kind Kind) []Record {
+	var found []Record
+	for _, rec := range records {
+		if rec.Kind == kind {
+			found = append(found, rec)
+		}
+	}
+	return found
+}
+
+func TestRecorder_RecordsRun(t *testing.T) {
+	var buf bytes.Buffer
+	runRecorded(t, &buf)
+
+	records, err := ReadRecords(&buf)
+	require.NoError(t, err)
+	require.NotEmpty(t, records)
+	assert.Equal(t, KindGraph, records[0].Kind)
+	require.NotNil(t, records[0].Diagram)
+	assert.Equal(t, "plan", records[0].Diagram.Entry)
+	assert.Equal(t, []string{"START", "plan", "act", graph.END}, records[0].Diagram.Nodes)
+
+	// Spans: the graph and its two nodes, with their input and output state
+	starts := recordsOf(records, KindSpanStart)
+	require.Len(t, starts, 3)
+	graphSpan := starts[0]
+	assert.Equal(t, graph.TraceEventGraphStart, graphSpan.Event)
+	assert.NotEmpty(t, graphSpan.RunID)
+	plan := starts[1]
+	assert.Equal(t, "plan", plan.Node)
+	assert.Equal(t, graphSpan.SpanID, plan.ParentID)
+	assert.Equal(t, 1, plan.Step)
+	assert.JSONEq(t, `{"count":0}`, string(plan.State))
+	act := starts[2]
+	assert.Equal(t, "act", act.Node)
+	assert.Equal(t, 2, act.Step)
+	assert.Len(t, recordsOf(records, KindSpanEnd), 3)
+
+	// Retries are attached to their node
+	retries := recordsOf(records, KindRetry)
+	require.Len(t, retries, 1)
+	assert.Equal(t, act.SpanID, retries[0].ParentID)
+	assert.Equal(t, "temporary failure", retries[0].Error)
+
+	// Edges and supersteps
+	edges := recordsOf(records, KindEdge)
+	require.Len(t, edges, 2)
+	assert.Equal(t, "plan", edges[0].From)
+	assert.Equal(t, "act", edges[0].To)
+	steps := recordsOf(records, KindStep)
+	require.Len(t, steps, 2)
+	assert.Equal(t, 2, steps[1].Step)
+	assert.JSONEq(t, `{"count":2,"result":"sunny"}`, string(steps[1].State))
+
+	// Calls nest under the node that made them, node reports are skipped
+	llm := recordsOf(records, KindLLMStart)
+	require.Len(t, llm, 1)
+	assert.Equal(t, plan.SpanID, llm[0].ParentID)
+	assert.Equal(t, "gpt-4o", llm[0].Name)
+	assert.Equal(t, []string{"what next?"}, llm[0].Prompts)
+	tools := recordsOf(records, KindToolStart)
+	require.Len(t, tools, 1)
+	assert.Equal(t, "search", tools[0].Name)
+	toolEnds := recordsOf(records, KindToolEnd)
+	require.Len(t, toolEnds, 1)
+	assert.JSONEq(t, `"sunny"`, string(toolEnds[0].Output))
+	assert.Len(t, recordsOf(records, KindRunStart), 1)
+	assert.Len(t, recordsOf(records, KindRunEnd), 1)
+}
+
+func TestRecorder_CreateAndReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.jsonl")
+	recorder, err := Create(path)
+	require.NoError(t, err)
+	recorder.OnGraphStep(context.Background(), "step", map[string]any{"ch": make(chan int)})
+	require.NoError(t, recorder.Close())
+
+	records, err := ReadFile(path)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, KindStep, records[0].Kind)
+	// Values JSON cannot represent are recorded as strings
+	assert.Contains(t, string(records[0].State), "map[ch:")
+}
@@ -0,0 +1,425 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/smallnest/langgraphgo/graph"
+)
+
+// RenderFile renders the trace written to tracePath by a Recorder as an HTML
+// page at htmlPath.
+func RenderFile(tracePath, htmlPath string) error {
+	records, err := ReadFile(tracePath)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := RenderHTML(&buf, records); err != nil {
+		return err
+	}
+	if err := os.WriteFile(htmlPath, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write replay page: %w", err)
+	}
+	return nil
+}
+
+// RenderHTML writes a single HTML page replaying a trace: a timeline of the
+// nodes of each superstep, and the diagram of the graph recorded with
+// WithDiagram with the nodes that ran highlighted. Clicking a node shows its input, output,
+// state diff and the LLM, tool and retriever calls it made.
+//
+// The page loads nothing else and works offline: the diagram is drawn as an
+// SVG image when the page is rendered, and its Mermaid source is included to
+// be copied to other tools.
+func RenderHTML(w io.Writer, records []Record) error {
+	return pageTemplate.Execute(w, buildView(records))
+}
+
+// view is the data of the replay page.
+type view struct {
+	Diagram  string
+	Graph    template.HTML
+	Duration string
+	Error    string
+	Nodes    []nodeView
+	Edges    []edgeView
+	Steps    int
+	// Latest maps the top-level nodes of the diagram to their last run
+	Latest map[string]string
+}
+
+type nodeView struct {
+	ID        string          `json:"id"`
+	Node      string          `json:"node"`
+	Namespace string          `json:"namespace"`
+	Step      int             `json:"step"`
+	Depth     int             `json:"-"`
+	Left      float64         `json:"-"`
+	Width     float64         `json:"-"`
+	Start     string          `json:"start"`
+	Duration  string          `json:"duration"`
+	Error     string          `json:"error,omitempty"`
+	Retries   int             `json:"retries,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	Output    json.RawMessage `json:"output,omitempty"`
+	Diff      []Change        `json:"diff"`
+	Calls     []*callView     `json:"calls"`
+}
+
+type edgeView struct {
+	From      string
+	To        string
+	Namespace string
+}
+
+type callView struct {
+	Kind     string          `json:"kind"`
+	Name     string          `json:"name,omitempty"`
+	Input    string          `json:"input,omitempty"`
+	Output   json.RawMessage `json:"output,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Duration string          `json:"duration,omitempty"`
+
+	start time.Time
+}
+
+// Change is a key of the state that a node changed.
+type Change struct {
+	// Key is the changed key, or "" when the state is not a JSON object.
+	Key string `json:"key"`
+	// Before is the value read by the node, if any.
+	Before json.RawMessage `json:"before,omitempty"`
+	// After is the value the node wrote.
+	After json.RawMessage `json:"after"`
+}
+
+// Diff returns the keys of the state that a node wrote with a different value
+// than it read, from the JSON input and output of its span. A node may return a
+// partial update, so keys missing from output are not reported as removed.
+func Diff(input, output json.RawMessage) []Change {
+	if len(output) == 0 {
+		return nil
+	}
+	var before, after any
+	_ = json.Unmarshal(input, &before)
+	if err := json.Unmarshal(output, &after); err != nil {
+		return nil
+	}
+
+	afterMap, ok := after.(map[string]any)
+	if !ok {
+		if reflect.DeepEqual(before, after) {
+			return nil
+		}
+		return []Change{{Before: input, After: output}}
+	}
+
+	beforeMap, _ := before.(map[string]any)
+	keys := make([]string, 0, len(afterMap))
+	for key := range afterMap {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var changes []Change
+	for _, key := range keys {
+		old, existed := beforeMap[key]
+		if existed && reflect.DeepEqual(old, afterMap[key]) {
+			continue
+		}
+		change := Change{Key: key, After: encode(afterMap[key])}
+		if existed {
+			change.Before = encode(old)
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+func buildView(records []Record) view {
+	v := view{Latest: make(map[string]string)}
+
+	var start, end time.Time
+	for _, rec := range records {
+		if rec.Kind == KindGraph {
+			continue
+		}
+		if start.IsZero() || rec.Time.Before(start) {
+			start = rec.Time
+		}
+		if rec.Time.After(end) {
+			end = rec.Time
+		}
+	}
+	total := end.Sub(start)
+	v.Duration = formatDuration(total)
+
+	var diagram *graph.Diagram
+	nodes := make(map[string]*nodeView)
+	var order []string
+	calls := make(map[string]*callView) // by callback run ID
+	var root string
+
+	for _, rec := range records {
+		switch rec.Kind {
+		case KindGraph:
+			diagram = rec.Diagram
+		case KindSpanStart:
+			if rec.Event == graph.TraceEventGraphStart && rec.ParentID == "" && root == "" {
+				root = rec.SpanID
+			}
+			if rec.Event != graph.TraceEventNodeStart {
+				continue
+			}
+			nodes[rec.SpanID] = &nodeView{
+				ID:        rec.SpanID,
+				Node:      rec.Node,
+				Namespace: rec.Namespace,
+				Step:      rec.Step,
+				Depth:     strings.Count(rec.Namespace, graph.NamespaceSeparator),
+				Left:      percent(rec.Time.Sub(start), total),
+				Start:     "+" + formatDuration(rec.Time.Sub(start)),
+				Input:     rec.State,
+			}
+			order = append(order, rec.SpanID)
+		case KindSpanEnd:
+			if rec.SpanID == root {
+				v.Error = rec.Error
+			}
+			node, ok := nodes[rec.SpanID]
+			if !ok {
+				continue
+			}
+			node.Width = max(percent(rec.Duration, total), 0.5)
+			node.Duration = formatDuration(rec.Duration)
+			node.Error = rec.Error
+			node.Output = rec.State
+			node.Diff = Diff(node.Input, node.Output)
+		case KindRetry:
+			if node, ok := nodes[rec.ParentID]; ok {
+				node.Retries++
+			}
+		case KindEdge:
+			v.Edges = append(v.Edges, edgeView{From: rec.From, To: rec.To, Namespace: rec.Namespace})
+		case KindStep:
+			if rec.ParentID == root {
+				v.Steps = max(v.Steps, rec.Step)
+			}
+		case KindLLMStart, KindToolStart, KindRetrieverStart:
+			call := &callView{
+				Kind:  strings.TrimSuffix(string(rec.Kind), "_start"),
+				Name:  rec.Name,
+				Input: rec.Input,
+				start: rec.Time,
+			}
+			if len(rec.Prompts) > 0 {
+				call.Input = strings.Join(rec.Prompts, "\n---\n")
+			}
+			calls[rec.RunID] = call
+			if node, ok := nodes[rec.ParentID]; ok {
+				node.Calls = append(node.Calls, call)
+			}
+		case KindLLMEnd, KindToolEnd, KindRetrieverEnd, KindLLMError, KindToolError, KindRetrieverError:
+			if call, ok := calls[rec.RunID]; ok {
+				call.Output = rec.Output
+				call.Error = rec.Error
+				call.Duration = formatDuration(rec.Time.Sub(call.start))
+			}
+		}
+	}
+
+	for _, id := range order {
+		node := nodes[id]
+		if node.Calls == nil {
+			node.Calls = []*callView{}
+		}
+		if node.Diff == nil {
+			node.Diff = []Change{}
+		}
+		v.Nodes = append(v.Nodes, *node)
+		if node.Namespace == node.Node {
+			v.Latest[node.Node] = node.ID
+		}
+	}
+	if diagram != nil {
+		v.Diagram = highlight(diagram.DrawMermaid(graph.MermaidOptions{}), v.Latest)
+		v.Graph = renderSVG(*diagram, v.Latest)
+	}
+	return v
+}
+
+// highlight styles the nodes of a Mermaid diagram that ran, and makes them
+// clickable.
+func highlight(diagram string, visited map[string]string) string {
+	names := make([]string, 0, len(visited))
+	for name := range visited {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var sb strings.Builder
+	sb.WriteString(strings.TrimRight(diagram, "\n"))
+	sb.WriteString("\n")
+	for _, name := range names {
+		sb.WriteString(fmt.Sprintf("    style %s fill:#FFD700,stroke:#B8860B,stroke-width:3px\n", name))
+		sb.WriteString(fmt.Sprintf("    click %s showNode\n", name))
+	}
+	return sb.String()
+}
+
+func percent(d, total time.Duration) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(d) / float64(total) * 100
+}
+
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Microsecond).String()
+}
+
+var pageTemplate = template.Must(template.New("replay").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Run trace</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 0; color: #222; }
+header { padding: 12px 20px; background: #263238; color: #fff; }
+header .error { color: #ff8a80; }
+main { display: grid; grid-template-columns: minmax(0, 3fr) minmax(0, 2fr); gap: 20px; padding: 20px; }
+h2 { font-size: 15px; margin: 0 0 8px; }
+section { margin-bottom: 24px; }
+.row { display: flex; align-items: center; height: 24px; cursor: pointer; font-size: 12px; }
+.row:hover { background: #f1f5f9; }
+.row.selected { background: #e3f2fd; }
+.label { width: 220px; flex: none; overflow: hidden; white-space: nowrap; text-overflow: ellipsis; }
+.step { width: 56px; flex: none; color: #607d8b; }
+.track { position: relative; flex: 1; height: 14px; background: #fafafa; }
+.bar { position: absolute; height: 14px; background: #42a5f5; border-radius: 2px; }
+.bar.error { background: #ef5350; }
+.bar.retried { background: #ffa726; }
+pre { background: #f5f5f5; padding: 8px; overflow: auto; font-size: 12px; white-space: pre-wrap; word-break: break-word; }
+#graph { overflow: auto; }
+#graph .clickable { cursor: pointer; }
+#graph .clickable:hover rect { stroke-width: 4; }
+summary { cursor: pointer; font-size: 12px; color: #607d8b; }
+table { border-collapse: collapse; width: 100%; font-size: 12px; }
+td, th { border: 1px solid #ddd; padding: 4px; text-align: left; vertical-align: top; }
+td pre { margin: 0; padding: 0; background: none; }
+.muted { color: #607d8b; }
+</style>
+</head>
+<body>
+<header>
+<strong>Run trace</strong> &middot; {{.Duration}} &middot; {{len .Nodes}} node runs &middot; {{.Steps}} supersteps
+{{if .Error}}<div class="error">{{.Error}}</div>{{end}}
+</header>
+<main>
+<div>
+<section>
+<h2>Timeline</h2>
+{{range .Nodes}}<div class="row" id="row-{{.ID}}" data-id="{{.ID}}" onclick="showSpan(this.dataset.id)">
+<span class="step">step {{.Step}}</span>
+<span class="label" style="padding-left: {{.Depth}}em" title="{{.Namespace}}">{{.Node}}{{if .Retries}} (retries: {{.Retries}}){{end}}</span>
+<span class="track"><span class="bar{{if .Error}} error{{else if .Retries}} retried{{end}}" style="left: {{printf "%.3f" .Left}}%; width: {{printf "%.3f" .Width}}%" title="{{.Start}}, {{.Duration}}"></span></span>
+</div>
+{{else}}<p class="muted">No node ran.</p>
+{{end}}
+</section>
+<section>
+<h2>Graph</h2>
+{{if .Diagram}}<div id="graph">{{.Graph}}</div>
+<details><summary>Mermaid source</summary><pre>{{.Diagram}}</pre></details>{{else}}<p class="muted">The trace has no diagram; record it with WithMermaid.</p>{{end}}
+</section>
+<section>
+<h2>Edges</h2>
+{{range .Edges}}<div class="muted">{{if .Namespace}}{{.Namespace}}: {{end}}{{.From}} &rarr; {{.To}}</div>
+{{else}}<p class="muted">No edge was taken.</p>
+{{end}}
+</section>
+</div>
+<div id="details"><p class="muted">Click a node in the timeline or the graph to see its state diff.</p></div>
+</main>
+<script>
+const nodes = {{.Nodes}};
+const latest = {{.Latest}};
+const byID = {};
+for (const n of nodes || []) byID[n.id] = n;
+
+function el(tag, text, cls) {
+  const e = document.createElement(tag);
+  if (text !== undefined) e.textContent = text;
+  if (cls) e.className = cls;
+  return e;
+}
+
+function json(v) {
+  return v === undefined || v === null ? "" : JSON.stringify(v, null, 2);
+}
+
+function showSpan(id) {
+  const n = byID[id];
+  if (!n) return;
+  document.querySelectorAll(".row.selected").forEach(r => r.classList.remove("selected"));
+  const row = document.getElementById("row-" + id);
+  if (row) row.classList.add("selected");
+
+  const d = document.getElementById("details");
+  d.replaceChildren();
+  d.append(el("h2", n.namespace + " (step " + n.step + ")"));
+  d.append(el("p", "started " + n.start + ", took " + n.duration + (n.retries ? ", retried " + n.retries + " times" : ""), "muted"));
+  if (n.error) d.append(el("pre", n.error));
+
+  d.append(el("h2", "State diff"));
+  if (n.diff.length === 0) {
+    d.append(el("p", "No change.", "muted"));
+  } else {
+    const t = el("table");
+    const head = el("tr");
+    ["key", "before", "after"].forEach(h => head.append(el("th", h)));
+    t.append(head);
+    for (const c of n.diff) {
+      const tr = el("tr");
+      tr.append(el("td", c.key || "(state)"));
+      const before = el("td"); before.append(el("pre", json(c.before)));
+      const after = el("td"); after.append(el("pre", json(c.after)));
+      tr.append(before, after);
+      t.append(tr);
+    }
+    d.append(t);
+  }
+
+  if (n.calls.length > 0) {
+    d.append(el("h2", "Calls"));
+    for (const c of n.calls) {
+      d.append(el("p", c.kind + (c.name ? " " + c.name : "") + (c.duration ? ", " + c.duration : "")));
+      if (c.input) d.append(el("pre", c.input));
+      if (c.error) d.append(el("pre", c.error));
+      else if (c.output !== undefined) d.append(el("pre", json(c.output)));
+    }
+  }
+
+  d.append(el("h2", "Input"));
+  d.append(el("pre", json(n.input)));
+  d.append(el("h2", "Output"));
+  d.append(el("pre", json(n.output)));
+}
+
+function showNode(name) {
+  if (latest[name]) showSpan(latest[name]);
+}
+document.querySelectorAll("#graph [data-node]").forEach(g => g.addEventListener("click", () => showNode(g.dataset.node)));
+</script>
+</body>
+</html>
+`))
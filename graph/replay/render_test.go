@@ -0,0 +1,148 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/smallnest/langgraphgo/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	changes := Diff(json.RawMessage(`{"a":1,"b":"x"}`), json.RawMessage(`{"a":1,"b":"y","c":true}`))
+	require.Len(t, changes, 2)
+	assert.Equal(t, "b", changes[0].Key)
+	assert.JSONEq(t, `"x"`, string(changes[0].Before))
+	assert.JSONEq(t, `"y"`, string(changes[0].After))
+	assert.Equal(t, "c", changes[1].Key)
+	assert.Nil(t, changes[1].Before)
+
+	// States that are not objects are compared as a whole
+	changes = Diff(json.RawMessage(`"before"`), json.RawMessage(`"after"`))
+	require.Len(t, changes, 1)
+	assert.Equal(t, "", changes[0].Key)
+	assert.Empty(t, Diff(json.RawMessage(`3`), json.RawMessage(`3`)))
+
+	// Failed nodes have no output
+	assert.Empty(t, Diff(json.RawMessage(`{"a":1}`), nil))
+}
+
+func TestRenderHTML(t *testing.T) {
+	var trace bytes.Buffer
+	runRecorded(t, &trace)
+
+	path := filepath.Join(t.TempDir(), "run.jsonl")
+	require.NoError(t, os.WriteFile(path, trace.Bytes(), 0o644))
+	records, err := ReadFile(path)
+	require.NoError(t, err)
+
+	v := buildView(records)
+	require.Len(t, v.Nodes, 2)
+	assert.Equal(t, 2, v.Steps)
+	assert.Len(t, v.Edges, 2)
+	plan := v.Nodes[0]
+	assert.Equal(t, "plan", plan.Node)
+	require.Len(t, plan.Calls, 2)
+	assert.Equal(t, "llm", plan.Calls[0].Kind)
+	assert.Equal(t, "what next?", plan.Calls[0].Input)
+	assert.Equal(t, "tool", plan.Calls[1].Kind)
+	require.Len(t, plan.Diff, 2)
+	assert.Equal(t, "count", plan.Diff[0].Key)
+	assert.Equal(t, "plan", plan.Diff[1].Key)
+	assert.Equal(t, 1, v.Nodes[1].Retries)
+	assert.Contains(t, v.Diagram, "style act fill:#FFD700")
+	assert.Contains(t, v.Diagram, "click plan showNode")
+
+	htmlPath := filepath.Join(t.TempDir(), "run.html")
+	require.NoError(t, RenderFile(path, htmlPath))
+	page, err := os.ReadFile(htmlPath)
+	require.NoError(t, err)
+	html := string(page)
+	assert.Contains(t, html, "style plan fill:#FFD700")
+	assert.Contains(t, html, `<g class="node clickable" data-node="plan">`)
+	assert.Contains(t, html, `fill="#FFD700"`)
+
+	// The page loads nothing from elsewhere
+	assert.NotContains(t, html, "<script src")
+	assert.NotContains(t, html, "import ")
+	assert.NotContains(t, html, "https://")
+	assert.Contains(t, html, `onclick="showSpan(this.dataset.id)"`)
+	assert.Contains(t, html, `"node":"act"`)
+}
+
+func TestRenderSVG(t *testing.T) {
+	noop := func(ctx context.Context, state map[string]any) (map[string]any, error) { return state, nil }
+	g := graph.NewStateGraph[map[string]any]()
+	g.AddNode("plan", "plan", noop)
+	g.AddNode("act", "act", noop)
+	g.AddNode("review", "review", noop)
+	g.SetEntryPoint("plan")
+	g.AddEdge("plan", "act")
+	g.AddEdge("plan", graph.END)
+	g.AddConditionalEdges("act", func(ctx context.Context, state map[string]any) []string { return nil },
+		map[string]string{"again": "plan", "check": "review", "done": graph.END})
+	g.AddConditionalEdge("review", func(ctx context.Context, state map[string]any) string { return graph.END })
+	diagram := graph.NewExporter(g).Diagram()
+
+	// The flowchart has the nodes and edges DrawMermaid draws
+	fc := newFlowchart(diagram, map[string]string{"act": "span-1"})
+	ids := []string{}
+	for _, n := range fc.nodes {
+		ids = append(ids, n.id)
+	}
+	assert.Equal(t, []string{"START", "plan", "act", "review", graph.END, "review_condition"}, ids)
+	assert.Contains(t, fc.edges, flowEdge{from: "act", to: "plan", label: "again", dashed: true})
+	assert.Contains(t, fc.edges, flowEdge{from: "review", to: "review_condition", dashed: true})
+	assert.True(t, fc.byID[graph.END].rounded)
+	assert.Equal(t, "?", fc.byID["review_condition"].label)
+	assert.True(t, fc.byID["act"].clickable)
+	assert.False(t, fc.byID["plan"].clickable)
+
+	// The edge back to plan does not push it below act, and nodes are ranked
+	// by their longest path from START
+	fc.layout()
+	ranks := map[string]int{}
+	for _, n := range fc.nodes {
+		ranks[n.id] = n.rank
+	}
+	assert.Equal(t, map[string]int{"START": 0, "plan": 1, "act": 2, "review": 3, graph.END: 3, "review_condition": 4}, ranks)
+
+	// Ranks go down without overlapping, and the nodes of a rank side by side
+	for _, a := range fc.nodes {
+		for _, b := range fc.nodes {
+			switch {
+			case a.rank < b.rank:
+				assert.Less(t, a.y+a.height, b.y, "%s above %s", a.id, b.id)
+			case a.rank == b.rank && a != b:
+				assert.True(t, a.x+a.width < b.x || b.x+b.width < a.x, "%s beside %s", a.id, b.id)
+			}
+		}
+	}
+
+	// Edges skipping ranks curve around the left of the nodes, and edges
+	// going back around the right
+	left, right := fc.nodes[0].x, 0.0
+	for _, n := range fc.nodes {
+		left, right = min(left, n.x), max(right, n.x+n.width)
+	}
+	_, x, _ := fc.edgePath(fc.byID["plan"], fc.byID[graph.END])
+	assert.Less(t, x, left)
+	_, x, _ = fc.edgePath(fc.byID["act"], fc.byID["plan"])
+	assert.Greater(t, x, right)
+
+	svg := string(renderSVG(diagram, map[string]string{"act": "span-1"}))
+	assert.Contains(t, svg, `<g class="node clickable" data-node="act">`)
+	assert.Contains(t, svg, `stroke-width="3"`)
+	assert.Contains(t, svg, ">again</text>")
+	assert.Contains(t, svg, `stroke-dasharray="5 5"`)
+
+	// Node names cannot inject markup
+	svg = string(renderSVG(graph.Diagram{Nodes: []string{"<script>"}}, map[string]string{"<script>": "span-1"}))
+	assert.NotContains(t, svg, "<script>")
+	assert.Contains(t, svg, "&lt;script&gt;")
+}
@@ -0,0 +1,275 @@
+package replay
+
+import (
+	"fmt"
+	"html/template"
+	"slices"
+	"strings"
+
+	"github.com/smallnest/langgraphgo/graph"
+)
+
+// flowchart is the drawing of a graph.Diagram, with the shapes and colors
+// that graph.Diagram.DrawMermaid gives its nodes.
+type flowchart struct {
+	nodes []*flowNode
+	byID  map[string]*flowNode
+	edges []flowEdge
+
+	// low and high bound the nodes across the flow
+	low, high float64
+}
+
+type flowNode struct {
+	id          string
+	label       string
+	rounded     bool
+	fill        string
+	stroke      string
+	strokeWidth string
+	dashed      bool
+	clickable   bool
+
+	rank          int
+	x, y          float64
+	width, height float64
+}
+
+type flowEdge struct {
+	from, to string
+	label    string
+	dashed   bool
+}
+
+const (
+	nodeHeight = 36.0
+	rankGap    = 60.0
+	nodeGap    = 30.0
+	margin     = 20.0
+)
+
+// newFlowchart returns the flowchart of a diagram, where the nodes in visited
+// are highlighted and clickable.
+func newFlowchart(diagram graph.Diagram, visited map[string]string) *flowchart {
+	fc := &flowchart{byID: make(map[string]*flowNode)}
+	for _, id := range diagram.Nodes {
+		node := fc.node(id)
+		switch id {
+		case "START":
+			node.rounded, node.fill = true, "#90EE90"
+		case graph.END:
+			node.rounded, node.fill = true, "#FFB6C1"
+		case diagram.Entry:
+			node.fill = "#87CEEB"
+		}
+	}
+
+	for _, e := range diagram.Edges {
+		// Conditional edges whose targets are unknown lead to a placeholder
+		to := e.To
+		if to == "" {
+			to = e.From + "_condition"
+			placeholder := fc.node(to)
+			placeholder.label, placeholder.rounded, placeholder.dashed = "?", true, true
+			placeholder.fill, placeholder.stroke = "#FFFFE0", "#333"
+		}
+		fc.node(e.From)
+		fc.node(to)
+		fc.edges = append(fc.edges, flowEdge{from: e.From, to: to, label: e.Label, dashed: e.Conditional})
+	}
+
+	for id := range visited {
+		if node, ok := fc.byID[id]; ok {
+			node.fill, node.stroke, node.strokeWidth = "#FFD700", "#B8860B", "3"
+			node.clickable = true
+		}
+	}
+	return fc
+}
+
+// node returns the node with the given ID, adding it on first use.
+func (fc *flowchart) node(id string) *flowNode {
+	node, ok := fc.byID[id]
+	if !ok {
+		node = &flowNode{id: id, label: id, fill: "#ECECFF", stroke: "#9370DB", strokeWidth: "1"}
+		fc.byID[id] = node
+		fc.nodes = append(fc.nodes, node)
+	}
+	return node
+}
+
+// layout places the nodes in ranks, the longest path from the nodes without
+// predecessors, ignoring the edges that close a cycle.
+func (fc *flowchart) layout() (width, height float64) {
+	back := fc.backEdges()
+	preds := make(map[string][]string)
+	for i, e := range fc.edges {
+		if !back[i] && e.from != e.to {
+			preds[e.to] = append(preds[e.to], e.from)
+		}
+	}
+
+	done := make(map[string]bool)
+	var rank func(n *flowNode) int
+	rank = func(n *flowNode) int {
+		if done[n.id] {
+			return n.rank
+		}
+		done[n.id] = true
+		for _, p := range preds[n.id] {
+			n.rank = max(n.rank, rank(fc.byID[p])+1)
+		}
+		return n.rank
+	}
+
+	var ranks [][]*flowNode
+	for _, n := range fc.nodes {
+		r := rank(n)
+		for len(ranks) <= r {
+			ranks = append(ranks, nil)
+		}
+		n.width = max(80, 8*float64(len(n.label))+24)
+		n.height = nodeHeight
+	}
+	for _, n := range fc.nodes {
+		ranks[n.rank] = append(ranks[n.rank], n)
+	}
+
+	// Size of each rank across and along the flow
+	across := make([]float64, len(ranks))
+	along := make([]float64, len(ranks))
+	for r, nodes := range ranks {
+		for i, n := range nodes {
+			if i > 0 {
+				across[r] += nodeGap
+			}
+			across[r] += n.width
+			along[r] = max(along[r], n.height)
+		}
+	}
+	span := slices.Max(append(across, 0))
+	fc.low, fc.high = margin+rankGap/2, margin+rankGap/2+span
+
+	pos := margin
+	for r, nodes := range ranks {
+		offset := margin + rankGap/2 + (span-across[r])/2
+		for _, n := range nodes {
+			n.x, n.y = offset, pos+(along[r]-n.height)/2
+			offset += n.width + nodeGap
+		}
+		pos += along[r] + rankGap
+	}
+
+	// Room on both sides for the edges curving around the ranks
+	return span + 2*margin + rankGap, pos - rankGap + margin
+}
+
+// backEdges returns the indexes of the edges that close a cycle, found by a
+// depth-first search from the nodes in order.
+func (fc *flowchart) backEdges() map[int]bool {
+	out := make(map[string][]int)
+	for i, e := range fc.edges {
+		out[e.from] = append(out[e.from], i)
+	}
+
+	back := make(map[int]bool)
+	state := make(map[string]int) // 1 while on the stack, 2 once done
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = 1
+		for _, i := range out[id] {
+			switch state[fc.edges[i].to] {
+			case 0:
+				visit(fc.edges[i].to)
+			case 1:
+				back[i] = true
+			}
+		}
+		state[id] = 2
+	}
+	for _, n := range fc.nodes {
+		if state[n.id] == 0 {
+			visit(n.id)
+		}
+	}
+	return back
+}
+
+// renderSVG draws a diagram as an SVG image, top down, with the nodes in
+// visited highlighted. The clickable nodes carry their ID in a data-node
+// attribute.
+func renderSVG(diagram graph.Diagram, visited map[string]string) template.HTML {
+	fc := newFlowchart(diagram, visited)
+	if len(fc.nodes) == 0 {
+		return ""
+	}
+	width, height := fc.layout()
+	esc := template.HTMLEscapeString
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f" font-size="13">`, width, height, width, height)
+	sb.WriteString(`<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="7" markerHeight="7" orient="auto-start-reverse"><path d="M0,0L10,5L0,10z" fill="#555"/></marker></defs>`)
+
+	for _, e := range fc.edges {
+		from, to := fc.byID[e.from], fc.byID[e.to]
+		path, lx, ly := fc.edgePath(from, to)
+		dash := ""
+		if e.dashed {
+			dash = ` stroke-dasharray="5 4"`
+		}
+		fmt.Fprintf(&sb, `<path d="%s" fill="none" stroke="#555" stroke-width="1.5"%s marker-end="url(#arrow)"/>`, path, dash)
+		if e.label != "" {
+			fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f" text-anchor="middle" fill="#333" paint-order="stroke" stroke="#fff" stroke-width="4">%s</text>`, lx, ly, esc(e.label))
+		}
+	}
+
+	for _, n := range fc.nodes {
+		radius := 4.0
+		if n.rounded {
+			radius = n.height / 2
+		}
+
+		if n.clickable {
+			fmt.Fprintf(&sb, `<g class="node clickable" data-node="%s">`, esc(n.id))
+		} else {
+			sb.WriteString(`<g class="node">`)
+		}
+		fmt.Fprintf(&sb, `<title>%s</title>`, esc(n.id))
+		fmt.Fprintf(&sb, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="%.1f" fill="%s" stroke="%s" stroke-width="%s"`,
+			n.x, n.y, n.width, n.height, radius, n.fill, n.stroke, n.strokeWidth)
+		if n.dashed {
+			sb.WriteString(` stroke-dasharray="5 5"`)
+		}
+		sb.WriteString(`/>`)
+		fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f" text-anchor="middle" dominant-baseline="central">%s</text></g>`,
+			n.x+n.width/2, n.y+n.height/2, esc(n.label))
+	}
+
+	sb.WriteString(`</svg>`)
+	return template.HTML(sb.String())
+}
+
+// edgePath returns the SVG path of an edge and the position of its label.
+// Edges to the next rank join facing sides. Edges skipping ranks curve around
+// the left of the graph, and edges going back, or within a rank, around the
+// right, so that they do not cross the nodes in between.
+func (fc *flowchart) edgePath(from, to *flowNode) (string, float64, float64) {
+	if to.rank == from.rank+1 {
+		x1, y1 := from.x+from.width/2, from.y+from.height
+		x2, y2 := to.x+to.width/2, to.y
+		return fmt.Sprintf("M%.1f,%.1fL%.1f,%.1f", x1, y1, x2, y2), (x1 + x2) / 2, (y1 + y2) / 2
+	}
+	if to.rank > from.rank {
+		x1, y1 := from.x, from.y+from.height/2
+		x2, y2 := to.x, to.y+to.height/2
+		bend := fc.low - rankGap/2
+		return fmt.Sprintf("M%.1f,%.1fC%.1f,%.1f %.1f,%.1f %.1f,%.1f", x1, y1, bend, y1, bend, y2, x2, y2), bend, (y1 + y2) / 2
+	}
+	x1, y1 := from.x+from.width, from.y+from.height/2
+	x2, y2 := to.x+to.width, to.y+to.height/2
+	bend := fc.high + rankGap/2
+	if from == to {
+		y1, y2 = from.y+from.height/3, from.y+2*from.height/3
+	}
+	return fmt.Sprintf("M%.1f,%.1fC%.1f,%.1f %.1f,%.1f %.1f,%.1f", x1, y1, bend, y1, bend, y2, x2, y2), bend, (y1 + y2) / 2
+}
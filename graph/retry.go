@@ -14,6 +14,7 @@ type RetryConfig struct {
 	InitialDelay    time.Duration
 	MaxDelay        time.Duration
 	BackoffFactor   float64
+	Jitter          float64          // Random variation applied to each delay, e.g. 0.25 for ±25%
 	RetryableErrors func(error) bool // Determines if an error should trigger retry
 }
 
@@ -31,6 +32,27 @@ func DefaultRetryConfig() *RetryConfig {
 	}
 }
 
+// backoffDelay returns the delay before the retry that follows the given
+// zero-based attempt, applying the backoff factor, the max delay cap and jitter.
+func (rc *RetryConfig) backoffDelay(attempt int) time.Duration {
+	factor := rc.BackoffFactor
+	if factor <= 0 {
+		factor = 1
+	}
+
+	delay := time.Duration(float64(rc.InitialDelay) * math.Pow(factor, float64(attempt)))
+	if rc.MaxDelay > 0 && delay > rc.MaxDelay {
+		delay = rc.MaxDelay
+	}
+
+	if rc.Jitter > 0 {
+		//nolint:gosec // Using weak RNG for jitter is acceptable, not security-critical
+		delay += time.Duration(float64(delay) * rc.Jitter * (2*rand.Float64() - 1))
+	}
+
+	return max(delay, 0)
+}
+
 // RetryNode wraps a node with retry logic
 type RetryNode[S any] struct {
 	node   TypedNode[S]
@@ -108,7 +130,9 @@ func (g *StateGraph[S]) AddNodeWithRetry(
 	g.AddNode(name, description, retryNode.Execute)
 }
 
-// TimeoutNode wraps a node with timeout logic
+// TimeoutNode wraps a node with timeout logic.
+// To give every node a deadline without wrapping each one, use
+// StateGraph.SetNodeTimeout or the WithTimeout node option instead.
 type TimeoutNode[S any] struct {
 	node    TypedNode[S]
 	timeout time.Duration
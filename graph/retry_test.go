@@ -3,6 +3,8 @@ package graph_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -257,6 +259,118 @@ func TestTimeoutNode(t *testing.T) {
 	})
 }
 
+func TestRuntimeTimeouts(t *testing.T) {
+	t.Parallel()
+
+	// blocking ignores its context to make sure the runtime still returns on time
+	blocking := func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		time.Sleep(200 * time.Millisecond)
+		return state, nil
+	}
+
+	t.Run("GraphNodeTimeout", func(t *testing.T) {
+		g := graph.NewStateGraph[map[string]any]()
+		g.SetNodeTimeout(20 * time.Millisecond)
+		g.AddNode("slow", "slow", blocking)
+		g.AddEdge("slow", graph.END)
+		g.SetEntryPoint("slow")
+
+		runnable, err := g.Compile()
+		if err != nil {
+			t.Fatalf("Failed to compile: %v", err)
+		}
+
+		start := time.Now()
+		_, err = runnable.Invoke(context.Background(), map[string]any{})
+		if !errors.Is(err, graph.ErrNodeTimeout) {
+			t.Fatalf("Expected ErrNodeTimeout, got %v", err)
+		}
+		if errors.Is(err, graph.ErrGraphTimeout) {
+			t.Error("Node timeout should not be reported as a graph timeout")
+		}
+		if time.Since(start) > 150*time.Millisecond {
+			t.Errorf("Node timeout took too long: %v", time.Since(start))
+		}
+	})
+
+	t.Run("PerNodeTimeoutOverridesDefault", func(t *testing.T) {
+		g := graph.NewStateGraph[map[string]any]()
+		g.SetNodeTimeout(10 * time.Millisecond)
+		g.AddNode("patient", "patient", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+			time.Sleep(30 * time.Millisecond)
+			return map[string]any{"value": successResult}, nil
+		}, graph.WithTimeout(time.Second))
+		g.AddEdge("patient", graph.END)
+		g.SetEntryPoint("patient")
+
+		runnable, err := g.Compile()
+		if err != nil {
+			t.Fatalf("Failed to compile: %v", err)
+		}
+
+		result, err := runnable.Invoke(context.Background(), map[string]any{})
+		if err != nil {
+			t.Fatalf("Execution failed: %v", err)
+		}
+		if result["value"] != successResult {
+			t.Errorf("Expected success, got %v", result)
+		}
+	})
+
+	t.Run("WaitsForNodeToReturn", func(t *testing.T) {
+		var returned atomic.Bool
+		g := graph.NewStateGraph[map[string]any]()
+		g.SetNodeTimeout(10 * time.Millisecond)
+		g.AddNode("cleanup", "cleanup", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+			<-ctx.Done()
+			// Clean up before returning, as a node honouring its context would
+			time.Sleep(10 * time.Millisecond)
+			returned.Store(true)
+			return nil, ctx.Err()
+		})
+		g.AddEdge("cleanup", graph.END)
+		g.SetEntryPoint("cleanup")
+
+		runnable, err := g.Compile()
+		if err != nil {
+			t.Fatalf("Failed to compile: %v", err)
+		}
+
+		_, err = runnable.Invoke(context.Background(), map[string]any{})
+		if !errors.Is(err, graph.ErrNodeTimeout) {
+			t.Fatalf("Expected ErrNodeTimeout, got %v", err)
+		}
+		if !returned.Load() {
+			t.Error("Node was still running after Invoke returned")
+		}
+	})
+
+	t.Run("ConfigTimeout", func(t *testing.T) {
+		g := graph.NewStateGraph[map[string]any]()
+		g.AddNode("slow", "slow", blocking)
+		g.AddEdge("slow", graph.END)
+		g.SetEntryPoint("slow")
+
+		runnable, err := g.Compile()
+		if err != nil {
+			t.Fatalf("Failed to compile: %v", err)
+		}
+
+		timeout := 20 * time.Millisecond
+		start := time.Now()
+		_, err = runnable.InvokeWithConfig(context.Background(), map[string]any{}, &graph.Config{Timeout: &timeout})
+		if !errors.Is(err, graph.ErrGraphTimeout) {
+			t.Fatalf("Expected ErrGraphTimeout, got %v", err)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Error("Graph timeout should wrap context.DeadlineExceeded")
+		}
+		if time.Since(start) > 150*time.Millisecond {
+			t.Errorf("Graph timeout took too long: %v", time.Since(start))
+		}
+	})
+}
+
 func TestCircuitBreaker(t *testing.T) {
 	t.Parallel()
 
@@ -558,3 +672,134 @@ func TestExponentialBackoffRetry(t *testing.T) {
 		}
 	})
 }
+
+type retryRecorder struct {
+	graph.NoOpCallbackHandler
+	mu       sync.Mutex
+	attempts []int
+}
+
+func (r *retryRecorder) OnNodeRetry(_ context.Context, _ string, attempt int, _ error, _ time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts = append(r.attempts, attempt)
+}
+
+func TestPerNodeRetryPolicy(t *testing.T) {
+	t.Parallel()
+
+	errTransient := errors.New("transient")
+
+	t.Run("RetriesMatchingErrors", func(t *testing.T) {
+		g := graph.NewStateGraph[map[string]any]()
+		callCount := int32(0)
+
+		g.AddNode("flaky", "flaky", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+			if atomic.AddInt32(&callCount, 1) < 3 {
+				return nil, fmt.Errorf("call failed: %w", errTransient)
+			}
+			return map[string]any{"value": successResult}, nil
+		}, graph.WithRetry(&graph.RetryConfig{
+			MaxAttempts:     3,
+			InitialDelay:    time.Millisecond,
+			MaxDelay:        5 * time.Millisecond,
+			BackoffFactor:   2.0,
+			Jitter:          0.5,
+			RetryableErrors: func(err error) bool { return errors.Is(err, errTransient) },
+		}))
+		g.AddEdge("flaky", graph.END)
+		g.SetEntryPoint("flaky")
+
+		runnable, err := g.Compile()
+		if err != nil {
+			t.Fatalf("Failed to compile: %v", err)
+		}
+
+		tracer := graph.NewTracer()
+		runnable.SetTracer(tracer)
+		recorder := &retryRecorder{}
+
+		result, err := runnable.InvokeWithConfig(context.Background(), map[string]any{}, &graph.Config{
+			Callbacks: []graph.CallbackHandler{recorder},
+		})
+		if err != nil {
+			t.Fatalf("Execution failed: %v", err)
+		}
+		if result["value"] != successResult {
+			t.Errorf("Expected success, got %v", result)
+		}
+		if atomic.LoadInt32(&callCount) != 3 {
+			t.Errorf("Expected 3 calls, got %d", callCount)
+		}
+		if len(recorder.attempts) != 2 || recorder.attempts[0] != 1 || recorder.attempts[1] != 2 {
+			t.Errorf("Expected retry callbacks for attempts [1 2], got %v", recorder.attempts)
+		}
+
+		retrySpans := 0
+		for _, span := range tracer.GetSpans() {
+			if span.Event == graph.TraceEventNodeRetry {
+				retrySpans++
+			}
+		}
+		if retrySpans != 2 {
+			t.Errorf("Expected 2 retry spans, got %d", retrySpans)
+		}
+	})
+
+	t.Run("StopsOnNonRetryableError", func(t *testing.T) {
+		g := graph.NewStateGraph[map[string]any]()
+		callCount := int32(0)
+
+		g.AddNode("fatal", "fatal", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+			atomic.AddInt32(&callCount, 1)
+			return nil, errors.New("invalid input")
+		}, graph.WithRetry(&graph.RetryConfig{
+			MaxAttempts:     5,
+			InitialDelay:    time.Millisecond,
+			RetryableErrors: func(err error) bool { return errors.Is(err, errTransient) },
+		}))
+		g.AddEdge("fatal", graph.END)
+		g.SetEntryPoint("fatal")
+
+		runnable, err := g.Compile()
+		if err != nil {
+			t.Fatalf("Failed to compile: %v", err)
+		}
+
+		if _, err := runnable.Invoke(context.Background(), map[string]any{}); err == nil {
+			t.Error("Expected error")
+		}
+		if atomic.LoadInt32(&callCount) != 1 {
+			t.Errorf("Expected 1 call, got %d", callCount)
+		}
+	})
+
+	t.Run("OverridesGraphPolicy", func(t *testing.T) {
+		g := graph.NewStateGraph[map[string]any]()
+		callCount := int32(0)
+
+		g.SetRetryPolicy(&graph.RetryPolicy{
+			MaxRetries:      5,
+			BackoffStrategy: graph.FixedBackoff,
+			RetryableErrors: []string{"boom"},
+		})
+		g.AddNode("pure", "pure", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+			atomic.AddInt32(&callCount, 1)
+			return nil, errors.New("boom")
+		}, graph.WithRetry(&graph.RetryConfig{MaxAttempts: 2, InitialDelay: time.Millisecond}))
+		g.AddEdge("pure", graph.END)
+		g.SetEntryPoint("pure")
+
+		runnable, err := g.Compile()
+		if err != nil {
+			t.Fatalf("Failed to compile: %v", err)
+		}
+
+		if _, err := runnable.Invoke(context.Background(), map[string]any{}); err == nil {
+			t.Error("Expected error")
+		}
+		if atomic.LoadInt32(&callCount) != 2 {
+			t.Errorf("Expected 2 calls, got %d", callCount)
+		}
+	})
+}
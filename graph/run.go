@@ -0,0 +1,226 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultRunRetention is how long a finished run started with Start can still
+// be looked up with GetRun, unless changed with SetRunRetention.
+const DefaultRunRetention = 10 * time.Minute
+
+// RunStatus is the lifecycle state of a Run.
+type RunStatus string
+
+const (
+	// RunStatusPending means the run was started but has not begun its first superstep.
+	RunStatusPending RunStatus = "pending"
+	// RunStatusRunning means the run is executing supersteps.
+	RunStatusRunning RunStatus = "running"
+	// RunStatusInterrupted means the run stopped on a GraphInterrupt and can be resumed.
+	RunStatusInterrupted RunStatus = "interrupted"
+	// RunStatusSucceeded means the run reached END.
+	RunStatusSucceeded RunStatus = "succeeded"
+	// RunStatusFailed means the run returned an error, including cancellation.
+	RunStatusFailed RunStatus = "failed"
+)
+
+// Run is a handle to a graph execution started in the background with
+// StateRunnable.Start. It is safe for concurrent use.
+type Run[S any] struct {
+	id     string
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu     sync.RWMutex
+	status RunStatus
+	step   int
+	nodes  []string
+	result S
+	err    error
+}
+
+// runObserver receives progress from the executor of a run started with Start.
+type runObserver interface {
+	runID() string
+	stepStarted(step int, nodes []string)
+}
+
+type runObserverKey struct{}
+
+// Start begins executing the graph in the background and returns immediately
+// with a handle to poll, wait for or cancel the run. The run can also be looked
+// up by its ID with GetRun, e.g. from another request of a service.
+//
+// The run is bound to ctx: cancelling ctx cancels the run. A service that
+// returns before the run finishes, such as an HTTP handler, should pass
+// context.WithoutCancel(r.Context()) or a long-lived context.
+//
+// Example:
+//
+//	run, err := app.Start(ctx, initialState, config)
+//	if err != nil {
+//	    return err
+//	}
+//	log.Printf("started run %s", run.ID())
+//
+//	// later, from another request
+//	run, ok := app.GetRun(runID)
+//	if ok && run.Status() == graph.RunStatusRunning {
+//	    run.Cancel()
+//	}
+func (r *StateRunnable[S]) Start(ctx context.Context, initialState S, config *Config) (*Run[S], error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	run := &Run[S]{
+		id:     generateRunID(),
+		cancel: cancel,
+		done:   make(chan struct{}),
+		status: RunStatusPending,
+	}
+	runCtx = context.WithValue(runCtx, runObserverKey{}, runObserver(run))
+	r.runs.Store(run.id, run)
+
+	go func() {
+		defer close(run.done)
+		defer cancel()
+
+		var result S
+		var err error
+		func() {
+			defer func() {
+				if p := recover(); p != nil {
+					err = fmt.Errorf("panic in run %s: %v", run.id, p)
+				}
+			}()
+			result, err = r.InvokeWithConfig(runCtx, initialState, config)
+		}()
+
+		run.finish(result, err)
+		r.retire(run)
+	}()
+
+	return run, nil
+}
+
+// GetRun returns a run started with Start by its ID. Finished runs are kept,
+// so that their result can still be read, for the retention period set with
+// SetRunRetention or until they are removed with DeleteRun.
+func (r *StateRunnable[S]) GetRun(runID string) (*Run[S], bool) {
+	run, ok := r.runs.Load(runID)
+	if !ok {
+		return nil, false
+	}
+	return run.(*Run[S]), true
+}
+
+// DeleteRun cancels a run started with Start if it is still running and
+// removes it, so that GetRun no longer finds it.
+func (r *StateRunnable[S]) DeleteRun(runID string) {
+	if run, ok := r.runs.LoadAndDelete(runID); ok {
+		run.(*Run[S]).Cancel()
+	}
+}
+
+// SetRunRetention sets how long finished runs can still be looked up with
+// GetRun, DefaultRunRetention by default. With a retention of zero or less, runs
+// are removed as soon as they finish. The Run returned by Start stays usable.
+func (r *StateRunnable[S]) SetRunRetention(retention time.Duration) {
+	r.runRetention = retention
+}
+
+// retire removes a finished run once its retention period has passed.
+func (r *StateRunnable[S]) retire(run *Run[S]) {
+	if r.runRetention <= 0 {
+		r.runs.CompareAndDelete(run.id, run)
+		return
+	}
+	time.AfterFunc(r.runRetention, func() {
+		r.runs.CompareAndDelete(run.id, run)
+	})
+}
+
+// ID returns the run ID, which is also the run ID passed to callbacks.
+func (r *Run[S]) ID() string {
+	return r.id
+}
+
+// Status returns the current lifecycle state of the run.
+func (r *Run[S]) Status() RunStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.status
+}
+
+// Step returns the number of the superstep being executed, starting at 1.
+// It is 0 until the first superstep begins.
+func (r *Run[S]) Step() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.step
+}
+
+// CurrentNodes returns the names of the nodes of the current superstep, or of
+// the last superstep once the run has finished.
+func (r *Run[S]) CurrentNodes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.nodes)
+}
+
+// Done returns a channel that is closed when the run finishes.
+func (r *Run[S]) Done() <-chan struct{} {
+	return r.done
+}
+
+// Wait blocks until the run finishes and returns its result, exactly as
+// InvokeWithConfig would have.
+func (r *Run[S]) Wait() (S, error) {
+	<-r.done
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.result, r.err
+}
+
+// Cancel stops the run. Nodes observe the cancellation through their context
+// and the run fails with context.Canceled. Cancelling a finished run is a no-op.
+func (r *Run[S]) Cancel() {
+	r.cancel()
+}
+
+func (r *Run[S]) runID() string {
+	return r.id
+}
+
+func (r *Run[S]) stepStarted(step int, nodes []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = RunStatusRunning
+	r.step = step
+	r.nodes = slices.Clone(nodes)
+}
+
+func (r *Run[S]) finish(result S, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.result = result
+	r.err = err
+
+	var interrupt *GraphInterrupt
+	switch {
+	case err == nil:
+		r.status = RunStatusSucceeded
+	case errors.As(err, &interrupt):
+		r.status = RunStatusInterrupted
+	default:
+		r.status = RunStatusFailed
+	}
+}
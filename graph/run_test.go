@@ -0,0 +1,207 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun_Succeeded(t *testing.T) {
+	release := make(chan struct{})
+
+	g := NewStateGraph[map[string]any]()
+	g.AddNode("first", "first", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		return map[string]any{"first": true}, nil
+	})
+	g.AddNode("second", "second", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		<-release
+		return map[string]any{"first": state["first"], "second": true}, nil
+	})
+	g.SetEntryPoint("first")
+	g.AddEdge("first", "second")
+	g.AddEdge("second", END)
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	run, err := runnable.Start(context.Background(), map[string]any{}, nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, run.ID())
+
+	// Poll until the run is blocked in the second superstep
+	assert.Eventually(t, func() bool { return run.Step() == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, RunStatusRunning, run.Status())
+	assert.Equal(t, []string{"second"}, run.CurrentNodes())
+
+	close(release)
+	result, err := run.Wait()
+	assert.NoError(t, err)
+	assert.Equal(t, true, result["second"])
+	assert.Equal(t, RunStatusSucceeded, run.Status())
+}
+
+func TestRun_PendingUntilFirstSuperstep(t *testing.T) {
+	g := NewStateGraph[map[string]any]()
+	g.AddNode("a", "a", passThrough)
+	g.SetEntryPoint("a")
+	g.AddEdge("a", END)
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	// The run starts its chain before its first superstep
+	gate := &chainStartGate{started: make(chan struct{}), release: make(chan struct{})}
+	run, err := runnable.Start(context.Background(), map[string]any{}, &Config{
+		Callbacks: []CallbackHandler{gate},
+	})
+	assert.NoError(t, err)
+
+	<-gate.started
+	assert.Equal(t, RunStatusPending, run.Status())
+	assert.Equal(t, 0, run.Step())
+
+	close(gate.release)
+	_, err = run.Wait()
+	assert.NoError(t, err)
+	assert.Equal(t, RunStatusSucceeded, run.Status())
+	assert.Equal(t, 1, run.Step())
+}
+
+func TestRun_Cancel(t *testing.T) {
+	started := make(chan struct{})
+
+	g := NewStateGraph[map[string]any]()
+	g.AddNode("slow", "slow", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	g.SetEntryPoint("slow")
+	g.AddEdge("slow", END)
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	handle, err := runnable.Start(context.Background(), map[string]any{}, nil)
+	assert.NoError(t, err)
+
+	// Look the run up by ID, as another request would
+	run, ok := runnable.GetRun(handle.ID())
+	assert.True(t, ok)
+	assert.Same(t, handle, run)
+
+	<-started
+	run.Cancel()
+
+	select {
+	case <-run.Done():
+	case <-time.After(time.Second):
+		t.Fatal("run did not stop after Cancel")
+	}
+
+	_, err = run.Wait()
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, RunStatusFailed, run.Status())
+
+	// Finished runs are kept during their retention period, or until deleted
+	_, ok = runnable.GetRun(run.ID())
+	assert.True(t, ok)
+	runnable.DeleteRun(run.ID())
+	_, ok = runnable.GetRun(run.ID())
+	assert.False(t, ok)
+}
+
+func TestRun_Retention(t *testing.T) {
+	g := NewStateGraph[map[string]any]()
+	g.AddNode("a", "a", passThrough)
+	g.SetEntryPoint("a")
+	g.AddEdge("a", END)
+
+	t.Run("RemovedAfterRetention", func(t *testing.T) {
+		runnable, err := g.Compile()
+		assert.NoError(t, err)
+		runnable.SetRunRetention(50 * time.Millisecond)
+
+		run, err := runnable.Start(context.Background(), map[string]any{}, nil)
+		assert.NoError(t, err)
+		_, err = run.Wait()
+		assert.NoError(t, err)
+
+		_, ok := runnable.GetRun(run.ID())
+		assert.True(t, ok, "finished run should be kept during its retention period")
+		assert.Eventually(t, func() bool {
+			_, ok := runnable.GetRun(run.ID())
+			return !ok
+		}, time.Second, 5*time.Millisecond)
+
+		// The handle still holds the outcome
+		assert.Equal(t, RunStatusSucceeded, run.Status())
+	})
+
+	t.Run("RemovedWhenFinished", func(t *testing.T) {
+		runnable, err := g.Compile()
+		assert.NoError(t, err)
+		runnable.SetRunRetention(0)
+
+		run, err := runnable.Start(context.Background(), map[string]any{}, nil)
+		assert.NoError(t, err)
+		_, err = run.Wait()
+		assert.NoError(t, err)
+
+		assert.Eventually(t, func() bool {
+			_, ok := runnable.GetRun(run.ID())
+			return !ok
+		}, time.Second, time.Millisecond)
+	})
+}
+
+func TestRun_InterruptedAndRunID(t *testing.T) {
+	g := NewStateGraph[map[string]any]()
+	g.AddNode("a", "a", passThrough)
+	g.AddNode("b", "b", passThrough)
+	g.SetEntryPoint("a")
+	g.AddEdge("a", "b")
+	g.AddEdge("b", END)
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	recorder := &runIDRecorder{}
+	run, err := runnable.Start(context.Background(), map[string]any{}, &Config{
+		Callbacks:       []CallbackHandler{recorder},
+		InterruptBefore: []string{"b"},
+	})
+	assert.NoError(t, err)
+
+	_, err = run.Wait()
+	var interrupt *GraphInterrupt
+	assert.ErrorAs(t, err, &interrupt)
+	assert.Equal(t, RunStatusInterrupted, run.Status())
+
+	// Callbacks see the same run ID as the handle
+	assert.Equal(t, run.ID(), recorder.runID)
+}
+
+type runIDRecorder struct {
+	NoOpCallbackHandler
+	runID string
+}
+
+func (r *runIDRecorder) OnChainStart(ctx context.Context, serialized map[string]any, inputs map[string]any, runID string, parentRunID *string, tags []string, metadata map[string]any) {
+	r.runID = runID
+}
+
+// chainStartGate holds a run in OnChainStart until it is released
+type chainStartGate struct {
+	NoOpCallbackHandler
+	started chan struct{}
+	release chan struct{}
+}
+
+func (g *chainStartGate) OnChainStart(ctx context.Context, serialized map[string]any, inputs map[string]any, runID string, parentRunID *string, tags []string, metadata map[string]any) {
+	close(g.started)
+	<-g.release
+}
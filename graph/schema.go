@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"maps"
 	"reflect"
+	"slices"
 )
 
 // StateSchema defines the structure and update logic for the graph state with type safety.
@@ -66,6 +67,16 @@ func (s *StructSchema[S]) Update(current S, new S) (S, error) {
 	return new, nil
 }
 
+// UpdateChannels merges the new state into the current state and returns the
+// names of the struct fields that changed.
+func (s *StructSchema[S]) UpdateChannels(current S, new S) (S, []string, error) {
+	result, err := s.Update(current, new)
+	if err != nil {
+		return result, nil, err
+	}
+	return result, diffChannels(current, result), nil
+}
+
 // DefaultStructMerge provides a default merge function for struct states.
 // It uses reflection to merge non-zero fields from new into current.
 // This is a sensible default for most struct types.
@@ -175,6 +186,16 @@ func (fm *FieldMerger[S]) Update(current S, new S) (S, error) {
 	return resultValue.Interface().(S), nil
 }
 
+// UpdateChannels merges the new state into the current state and returns the
+// names of the struct fields that changed.
+func (fm *FieldMerger[S]) UpdateChannels(current S, new S) (S, []string, error) {
+	result, err := fm.Update(current, new)
+	if err != nil {
+		return result, nil, err
+	}
+	return result, diffChannels(current, result), nil
+}
+
 // Common merge helpers for FieldMerger
 
 // AppendSliceMerge appends new slice to current slice.
@@ -329,6 +350,39 @@ func (s *MapSchema) Update(current, new map[string]any) (map[string]any, error)
 	return result, nil
 }
 
+// UpdateChannels merges the new map into the current map like Update and returns
+// the keys whose values changed. Only keys present in new are considered, so nodes
+// can return partial maps containing just the keys they touched.
+func (s *MapSchema) UpdateChannels(current, new map[string]any) (map[string]any, []string, error) {
+	if current != nil && sameValue(current, new) {
+		// The node updated its input in place, so any key it holds may have changed.
+		// The executor compares such updates with a copy of the state taken
+		// before the step instead.
+		return new, slices.Sorted(maps.Keys(new)), nil
+	}
+
+	result, err := s.Update(current, new)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var changed []string
+	for k, v := range new {
+		old, exists := current[k]
+		if _, hasReducer := s.Reducers[k]; hasReducer {
+			// Reducers skip values that are already merged
+			if !exists || !sameValue(old, v) {
+				changed = append(changed, k)
+			}
+		} else if !exists || !reflect.DeepEqual(old, v) {
+			changed = append(changed, k)
+		}
+	}
+	slices.Sort(changed)
+
+	return result, changed, nil
+}
+
 // Common Reducers
 
 // OverwriteReducer replaces the old value with the new one.
@@ -0,0 +1,87 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+)
+
+// Send schedules one run of a node with its own input instead of the graph state.
+//
+// Sends allow map-style fan-out whose width is only known at runtime, such as
+// one worker per retrieved document. Every Send returned in a superstep runs
+// in the next superstep, in parallel with any other scheduled node, and the
+// results are merged through the graph's schema like any parallel result.
+// After a sent node finishes, execution continues along its outgoing edges.
+//
+// Sends are returned from a router registered with AddConditionalSends or in
+// Command.Goto. Sends still pending when the graph is interrupted are not part
+// of GraphInterrupt.NextNodes, but checkpoints save them, so a resumed run
+// runs them.
+type Send struct {
+	// Node is the name of the node to run
+	Node string
+
+	// Arg is the input passed to the node. It must be of the graph's state type.
+	Arg any
+}
+
+// NewSend creates a Send that runs node with arg as its input.
+func NewSend(node string, arg any) Send {
+	return Send{Node: node, Arg: arg}
+}
+
+// AddConditionalSends adds a conditional edge whose router returns Sends, running
+// the target node once per Send, each with its own input.
+//
+// Example:
+//
+//	g.AddConditionalSends("retrieve", func(ctx context.Context, state map[string]any) []graph.Send {
+//	    var sends []graph.Send
+//	    for _, doc := range state["docs"].([]string) {
+//	        sends = append(sends, graph.NewSend("summarize", map[string]any{"doc": doc}))
+//	    }
+//	    return sends
+//	})
+func (g *StateGraph[S]) AddConditionalSends(from string, router func(ctx context.Context, state S) []Send) {
+	g.conditionalEdges[from] = conditionalEdge[S]{
+		sender: router,
+	}
+}
+
+// sendInput converts the argument of a Send to the graph's state type.
+func sendInput[S any](send Send) (S, error) {
+	var zero S
+	if send.Arg == nil {
+		return zero, nil
+	}
+	input, ok := send.Arg.(S)
+	if !ok {
+		return zero, fmt.Errorf("send to node %s: argument of type %T does not match the graph state type %T", send.Node, send.Arg, zero)
+	}
+	return input, nil
+}
+
+// gotoTargets splits a Command.Goto value into node names and Sends. Goto may be a
+// node name, a Send, or a slice of either.
+func gotoTargets(g any) ([]string, []Send) {
+	switch v := g.(type) {
+	case string:
+		return []string{v}, nil
+	case []string:
+		return v, nil
+	case Send:
+		return nil, []Send{v}
+	case []Send:
+		return nil, v
+	case []any:
+		var nodes []string
+		var sends []Send
+		for _, item := range v {
+			n, s := gotoTargets(item)
+			nodes = append(nodes, n...)
+			sends = append(sends, s...)
+		}
+		return nodes, sends
+	}
+	return nil, nil
+}
@@ -0,0 +1,224 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSend_FromRouter(t *testing.T) {
+	schema := NewMapSchema()
+	schema.RegisterReducer("summaries", AppendReducer)
+
+	g := NewStateGraph[map[string]any]()
+	g.SetSchema(schema)
+
+	g.AddNode("retrieve", "Retrieve documents", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		return map[string]any{"docs": []string{"a", "b", "c"}}, nil
+	})
+	g.AddNode("summarize", "Summarize one document", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		// Each run only sees its own payload
+		assert.Nil(t, state["docs"])
+		return map[string]any{"summaries": []string{"summary of " + state["doc"].(string)}}, nil
+	})
+	g.AddNode("report", "Write the report", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		return map[string]any{"report": len(state["summaries"].([]string))}, nil
+	})
+
+	g.SetEntryPoint("retrieve")
+	g.AddConditionalSends("retrieve", func(ctx context.Context, state map[string]any) []Send {
+		var sends []Send
+		for _, doc := range state["docs"].([]string) {
+			sends = append(sends, NewSend("summarize", map[string]any{"doc": doc}))
+		}
+		return sends
+	})
+	g.AddEdge("summarize", "report")
+	g.AddEdge("report", END)
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	result, err := runnable.Invoke(context.Background(), map[string]any{})
+	assert.NoError(t, err)
+
+	summaries := result["summaries"].([]string)
+	sort.Strings(summaries)
+	assert.Equal(t, []string{"summary of a", "summary of b", "summary of c"}, summaries)
+
+	// report ran once, after all summaries were merged
+	assert.Equal(t, 3, result["report"])
+}
+
+func TestSend_FromCommandGoto(t *testing.T) {
+	g := NewStateGraph[any]()
+
+	schema := NewMapSchema()
+	schema.RegisterReducer("count", func(curr, new any) (any, error) {
+		if curr == nil {
+			return new, nil
+		}
+		return curr.(int) + new.(int), nil
+	})
+	g.SetSchema(&mapSchemaAdapterForAny{MapSchema: schema})
+
+	g.AddNode("A", "A", func(ctx context.Context, state any) (any, error) {
+		return &Command{
+			Update: map[string]any{"count": 1},
+			Goto: []any{
+				"C",
+				NewSend("B", map[string]any{"n": 10}),
+				NewSend("B", map[string]any{"n": 20}),
+			},
+		}, nil
+	})
+	g.AddNode("B", "B", func(ctx context.Context, state any) (any, error) {
+		return map[string]any{"count": state.(map[string]any)["n"]}, nil
+	})
+	g.AddNode("C", "C", func(ctx context.Context, state any) (any, error) {
+		return map[string]any{"count": 100}, nil
+	})
+
+	g.SetEntryPoint("A")
+	g.AddEdge("B", END)
+	g.AddEdge("C", END)
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	res, err := runnable.Invoke(context.Background(), map[string]any{"count": 0})
+	assert.NoError(t, err)
+
+	// 1 (A) + 100 (C) + 10 + 20 (B twice, in the same superstep)
+	assert.Equal(t, 131, res.(map[string]any)["count"])
+}
+
+func TestSend_ArgumentTypeMismatch(t *testing.T) {
+	g := NewStateGraph[map[string]any]()
+	g.AddNode("start", "start", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		return state, nil
+	})
+	g.AddNode("worker", "worker", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		return state, nil
+	})
+	g.SetEntryPoint("start")
+	g.AddConditionalSends("start", func(ctx context.Context, state map[string]any) []Send {
+		return []Send{NewSend("worker", "not a map")}
+	})
+	g.AddEdge("worker", END)
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	_, err = runnable.Invoke(context.Background(), map[string]any{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("argument of type %T", "not a map"))
+}
+
+// buildCheckpointedSends builds retrieve -> summarize (one Send per document)
+// -> report, where summarize interrupts for the documents in interrupts.
+func buildCheckpointedSends(t *testing.T, checkpointStore CheckpointStore, interrupts map[string]bool) *CheckpointableRunnable[map[string]any] {
+	schema := NewMapSchema()
+	schema.RegisterReducer("summaries", AppendReducer)
+
+	g := NewCheckpointableStateGraphWithConfig[map[string]any](CheckpointConfig{
+		Store:    checkpointStore,
+		AutoSave: true,
+	})
+	g.SetSchema(schema)
+
+	g.AddNode("retrieve", "Retrieve documents", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		return map[string]any{"docs": []string{"a", "b", "c"}}, nil
+	})
+	g.AddNode("summarize", "Summarize one document", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		doc := state["doc"].(string)
+		if interrupts[doc] {
+			if _, err := Interrupt(ctx, "review "+doc); err != nil {
+				return nil, err
+			}
+		}
+		return map[string]any{"summaries": []string{"summary of " + doc}}, nil
+	})
+	g.AddNode("report", "Write the report", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		return map[string]any{"report": fmt.Sprint(state["summaries"])}, nil
+	})
+
+	g.SetEntryPoint("retrieve")
+	g.AddConditionalSends("retrieve", func(ctx context.Context, state map[string]any) []Send {
+		var sends []Send
+		for _, doc := range []string{"a", "b", "c"} {
+			sends = append(sends, NewSend("summarize", map[string]any{"doc": doc}))
+		}
+		return sends
+	})
+	g.AddEdge("summarize", "report")
+	g.AddEdge("report", END)
+
+	runnable, err := g.CompileCheckpointable()
+	assert.NoError(t, err)
+	return runnable
+}
+
+// summariesOf returns the summaries of a state, which stores decoding JSON
+// return as []any.
+func summariesOf(state map[string]any) []string {
+	var summaries []string
+	switch v := state["summaries"].(type) {
+	case []string:
+		summaries = append(summaries, v...)
+	case []any:
+		for _, s := range v {
+			summaries = append(summaries, fmt.Sprint(s))
+		}
+	}
+	sort.Strings(summaries)
+	return summaries
+}
+
+func TestSend_ResumeRunsPendingSends(t *testing.T) {
+	fileStore, err := NewFileCheckpointStore(t.TempDir())
+	assert.NoError(t, err)
+
+	for name, checkpointStore := range map[string]CheckpointStore{
+		"memory": NewMemoryCheckpointStore(),
+		"file":   fileStore,
+	} {
+		t.Run(name, func(t *testing.T) {
+			runnable := buildCheckpointedSends(t, checkpointStore, nil)
+
+			ctx := context.Background()
+			config := WithThreadID("sends-thread")
+			config.InterruptAfter = []string{"retrieve"}
+			_, err := runnable.InvokeWithConfig(ctx, map[string]any{}, config)
+			var interrupt *GraphInterrupt
+			assert.ErrorAs(t, err, &interrupt)
+
+			snapshot, err := runnable.GetState(ctx, WithThreadID("sends-thread"))
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"summarize"}, snapshot.Next)
+
+			result, err := runnable.InvokeWithConfig(ctx, map[string]any{}, WithThreadID("sends-thread"))
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"summary of a", "summary of b", "summary of c"}, summariesOf(result))
+		})
+	}
+}
+
+func TestSend_ResumeRerunsInterruptedSend(t *testing.T) {
+	interrupts := map[string]bool{"b": true}
+	runnable := buildCheckpointedSends(t, NewMemoryCheckpointStore(), interrupts)
+
+	ctx := context.Background()
+	_, err := runnable.InvokeWithConfig(ctx, map[string]any{}, WithThreadID("send-interrupt-thread"))
+	var interrupt *GraphInterrupt
+	assert.ErrorAs(t, err, &interrupt)
+
+	// The Send for b runs again with its own input
+	interrupts["b"] = false
+	result, err := runnable.InvokeWithConfig(ctx, map[string]any{}, WithThreadID("send-interrupt-thread"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"summary of a", "summary of b", "summary of c"}, summariesOf(result))
+}
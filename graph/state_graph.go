@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"reflect"
 	"slices"
 	"strings"
 	"sync"
@@ -32,15 +34,24 @@ type StateGraph[S any] struct {
 	// edges is a slice of Edge objects representing the connections between nodes
 	edges []Edge
 
-	// conditionalEdges contains a map between "From" node, while "To" node is derived based on the condition
-	conditionalEdges map[string]func(ctx context.Context, state S) string
+	// conditionalEdges contains a map between "From" node, while "To" nodes are derived based on the condition
+	conditionalEdges map[string]conditionalEdge[S]
 
 	// entryPoint is the name of the entry point node in the graph
 	entryPoint string
 
+	// duplicateNodes records node names that were added more than once
+	duplicateNodes []string
+
+	// joins maps join targets to the sources they wait for (see AddJoinEdge)
+	joins map[string][]string
+
 	// retryPolicy defines retry behavior for failed nodes
 	retryPolicy *RetryPolicy
 
+	// nodeTimeout is the default deadline for each node execution (0 means none)
+	nodeTimeout time.Duration
+
 	// stateMerger is an optional function to merge states from parallel execution
 	stateMerger TypedStateMerger[S]
 
@@ -53,6 +64,60 @@ type TypedNode[S any] struct {
 	Name        string
 	Description string
 	Function    func(ctx context.Context, state S) (S, error)
+
+	// Options holds optional per-node settings configured at AddNode time
+	Options NodeOptions
+}
+
+// NodeOptions holds optional per-node settings.
+type NodeOptions struct {
+	// RetryPolicy overrides the graph-wide retry policy for this node
+	RetryPolicy *RetryConfig
+
+	// Timeout overrides the graph-wide node timeout for this node
+	Timeout time.Duration
+
+	// Triggers are the channels the node subscribes to (see WithTriggers)
+	Triggers []string
+
+	// Defer makes the node wait for all other branches to finish (see Defer)
+	Defer bool
+}
+
+// NodeOption configures a node when it is added to the graph.
+type NodeOption func(*NodeOptions)
+
+// WithRetry attaches a retry policy to a single node. It takes precedence over
+// the graph-wide policy set with SetRetryPolicy.
+//
+// Example:
+//
+//	g.AddNode("call_llm", "Call the model", callLLM, graph.WithRetry(&graph.RetryConfig{
+//	    MaxAttempts:     4,
+//	    InitialDelay:    500 * time.Millisecond,
+//	    MaxDelay:        10 * time.Second,
+//	    BackoffFactor:   2.0,
+//	    Jitter:          0.2,
+//	    RetryableErrors: func(err error) bool { return errors.Is(err, ErrRateLimited) },
+//	}))
+func WithRetry(config *RetryConfig) NodeOption {
+	return func(o *NodeOptions) {
+		o.RetryPolicy = config
+	}
+}
+
+// WithTimeout sets a deadline for each execution attempt of a single node.
+// It takes precedence over the graph-wide default set with SetNodeTimeout.
+//
+// The node's context is cancelled at the deadline and the node is expected to
+// return; the executor waits for it during a short grace period before failing
+// the attempt. A node that ignores its context is abandoned after that and keeps
+// running in the background, still able to use its StreamWriter and anything it
+// shares with the next attempt, so nodes must honour ctx.
+func WithTimeout(timeout time.Duration) NodeOption {
+	return func(o *NodeOptions) {
+		o.Timeout = timeout
+	}
 }
 
 // StateMerger is a typed function to merge states from parallel execution.
@@ -67,12 +132,13 @@ type TypedStateMerger[S any] func(ctx context.Context, currentState S, newStates
 func NewStateGraph[S any]() *StateGraph[S] {
 	return &StateGraph[S]{
 		nodes:            make(map[string]TypedNode[S]),
-		conditionalEdges: make(map[string]func(ctx context.Context, state S) string),
+		conditionalEdges: make(map[string]conditionalEdge[S]),
 	}
 }
 
 // AddNode adds a new node to the state graph with the given name, description and function.
 // The node function is fully typed - no type assertions needed!
+// Optional NodeOption values configure per-node behavior such as retries.
 //
 // Example:
 //
@@ -80,11 +146,21 @@ func NewStateGraph[S any]() *StateGraph[S] {
 //	    state.Count++  // Type-safe access!
 //	    return state, nil
 //	})
-func (g *StateGraph[S]) AddNode(name string, description string, fn func(ctx context.Context, state S) (S, error)) {
+func (g *StateGraph[S]) AddNode(name string, description string, fn func(ctx context.Context, state S) (S, error), opts ...NodeOption) {
+	var options NodeOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if _, exists := g.nodes[name]; exists {
+		g.duplicateNodes = append(g.duplicateNodes, name)
+	}
+
 	g.nodes[name] = TypedNode[S]{
 		Name:        name,
 		Description: description,
 		Function:    fn,
+		Options:     options,
 	}
 }
 
@@ -108,7 +184,11 @@ func (g *StateGraph[S]) AddEdge(from, to string) {
 //	    return "low"
 //	})
 func (g *StateGraph[S]) AddConditionalEdge(from string, condition func(ctx context.Context, state S) string) {
-	g.conditionalEdges[from] = condition
+	g.conditionalEdges[from] = conditionalEdge[S]{
+		router: func(ctx context.Context, state S) []string {
+			return []string{condition(ctx, state)}
+		},
+	}
 }
 
 // SetEntryPoint sets the entry point node name for the state graph.
@@ -117,10 +197,19 @@ func (g *StateGraph[S]) SetEntryPoint(name string) {
 }
 
 // SetRetryPolicy sets the retry policy for the graph.
+// Nodes added with WithRetry use their own policy instead.
 func (g *StateGraph[S]) SetRetryPolicy(policy *RetryPolicy) {
 	g.retryPolicy = policy
 }
 
+// SetNodeTimeout sets the default deadline applied to every node execution attempt.
+// A node that exceeds it fails with ErrNodeTimeout. Nodes added with WithTimeout
+// use their own deadline instead. As with WithTimeout, nodes must honour their
+// context to stop when the deadline is reached.
+func (g *StateGraph[S]) SetNodeTimeout(timeout time.Duration) {
+	g.nodeTimeout = timeout
+}
+
 // SetStateMerger sets the state merger function for the state graph.
 func (g *StateGraph[S]) SetStateMerger(merger TypedStateMerger[S]) {
 	g.stateMerger = merger
@@ -133,20 +222,54 @@ func (g *StateGraph[S]) SetSchema(schema StateSchema[S]) {
 
 // StateRunnable represents a compiled state graph that can be invoked with type safety.
 type StateRunnable[S any] struct {
-	graph      *StateGraph[S]
-	tracer     *Tracer
-	nodeRunner func(ctx context.Context, nodeName string, state S) (S, error)
+	graph           *StateGraph[S]
+	tracer          *Tracer
+	nodeRunner      func(ctx context.Context, nodeName string, state S) (S, error)
+	detectConflicts bool
+
+	// runs holds the runs started with Start, by ID
+	runs *sync.Map
+
+	// runRetention is how long finished runs stay in runs (see SetRunRetention)
+	runRetention time.Duration
 }
 
 // Compile compiles the state graph and returns a StateRunnable instance.
+// The graph is validated first and a *ValidationError is returned if it has
+// structural errors; warnings are ignored (see CompileWithOptions).
 func (g *StateGraph[S]) Compile() (*StateRunnable[S], error) {
+	return g.CompileWithOptions(CompileOptions{})
+}
+
+// CompileWithOptions compiles the state graph after validating it. With
+// CompileOptions.Strict, validation warnings also fail the build.
+//
+// Example:
+//
+//	runnable, err := g.CompileWithOptions(graph.CompileOptions{Strict: true})
+//	var verr *graph.ValidationError
+//	if errors.As(err, &verr) {
+//	    for _, w := range verr.Warnings {
+//	        log.Println(w)
+//	    }
+//	}
+func (g *StateGraph[S]) CompileWithOptions(opts CompileOptions) (*StateRunnable[S], error) {
 	if g.entryPoint == "" {
 		return nil, ErrEntryPointNotSet
 	}
 
+	var verr *ValidationError
+	if err := g.Validate(); errors.As(err, &verr) {
+		if len(verr.Errors) > 0 || opts.Strict {
+			return nil, verr
+		}
+	}
+
 	return &StateRunnable[S]{
-		graph:  g,
-		tracer: nil, // Initialize with no tracer
+		graph:        g,
+		tracer:       nil, // Initialize with no tracer
+		runs:         &sync.Map{},
+		runRetention: DefaultRunRetention,
 	}, nil
 }
 
@@ -162,10 +285,18 @@ func (r *StateRunnable[S]) GetTracer() *Tracer {
 
 // WithTracer returns a new StateRunnable with the given tracer.
 func (r *StateRunnable[S]) WithTracer(tracer *Tracer) *StateRunnable[S] {
-	return &StateRunnable[S]{
-		graph:  r.graph,
-		tracer: tracer,
-	}
+	clone := *r
+	clone.tracer = tracer
+	return &clone
+}
+
+// SetConflictDetection enables or disables detection of conflicting writes.
+// When enabled, a superstep in which two or more parallel nodes write different
+// values to the same key fails with a *ConflictingWriteError instead of silently
+// letting the last write win. Keys with a reducer registered on a MapSchema or a
+// merge function registered on a FieldMerger are allowed to receive several writes.
+func (r *StateRunnable[S]) SetConflictDetection(enabled bool) {
+	r.detectConflicts = enabled
 }
 
 // Invoke executes the compiled state graph with the given input state.
@@ -181,7 +312,7 @@ func (r *StateRunnable[S]) Invoke(ctx context.Context, initialState S) (S, error
 }
 
 // InvokeWithConfig executes the compiled state graph with the given input state and config.
-func (r *StateRunnable[S]) InvokeWithConfig(ctx context.Context, initialState S, config *Config) (S, error) {
+func (r *StateRunnable[S]) InvokeWithConfig(ctx context.Context, initialState S, config *Config) (result S, err error) {
 	state := initialState
 
 	// If schema is defined, merge initialState into schema's initial state
@@ -196,14 +327,59 @@ func (r *StateRunnable[S]) InvokeWithConfig(ctx context.Context, initialState S,
 	}
 
 	currentNodes := []string{r.graph.entryPoint}
+	var pendingSends []Send
 
 	// Handle ResumeFrom
 	if config != nil && len(config.ResumeFrom) > 0 {
 		currentNodes = config.ResumeFrom
 	}
 
-	// Generate run ID for callbacks
+	// Bound the whole run by Config.Timeout
+	if config != nil && config.Timeout != nil && *config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, *config.Timeout,
+			fmt.Errorf("%w after %v: %w", ErrGraphTimeout, *config.Timeout, context.DeadlineExceeded))
+		defer cancel()
+	}
+
+	// A subgraph runs in the namespace of the node that invoked it, reports to
+	// that node's run and uses the parent's tracer unless it has its own
+	parentScope := currentScope(ctx)
+	namespace := parentScope.namespace
+	var parentRunID *string
+	if parentScope.runID != "" {
+		parentRunID = &parentScope.runID
+	}
+	if r.tracer == nil && parentScope.tracer != nil {
+		r = r.WithTracer(parentScope.tracer)
+	}
+
+	// Generate run ID for callbacks, reusing the ID of a run started with Start
 	runID := generateRunID()
+	observer, _ := ctx.Value(runObserverKey{}).(runObserver)
+	if observer != nil {
+		runID = observer.runID()
+		// Subgraphs invoked by this run must not report progress to its handle
+		ctx = context.WithValue(ctx, runObserverKey{}, nil)
+	}
+
+	// Outputs a failed run saved for nodes of its last superstep, reused instead
+	// of running those nodes again. Subgraphs must not reuse them.
+	reused, _ := ctx.Value(pendingWritesKey{}).(pendingWrites)
+	if reused != nil {
+		reused = maps.Clone(reused)
+		ctx = context.WithValue(ctx, pendingWritesKey{}, pendingWrites(nil))
+	}
+
+	// Sends a resumed run saved as pending run first, alongside ResumeFrom.
+	// Subgraphs must not run them.
+	if sends, _ := ctx.Value(pendingSendsKey{}).([]Send); len(sends) > 0 {
+		pendingSends = slices.Clone(sends)
+		if config == nil || len(config.ResumeFrom) == 0 {
+			currentNodes = nil
+		}
+		ctx = context.WithValue(ctx, pendingSendsKey{}, []Send(nil))
+	}
 
 	// Notify callbacks of graph start
 	if config != nil {
@@ -223,19 +399,45 @@ func (r *StateRunnable[S]) InvokeWithConfig(ctx context.Context, initialState S,
 			inputs := convertStateToMap(initialState)
 
 			for _, cb := range config.Callbacks {
-				cb.OnChainStart(ctx, serialized, inputs, runID, nil, config.Tags, config.Metadata)
+				cb.OnChainStart(ctx, serialized, inputs, runID, parentRunID, config.Tags, config.Metadata)
 			}
 		}
 	}
 
-	// Start graph tracing if tracer is set
-	var graphSpan *TraceSpan
+	// Events for a run streamed with Stream, which subgraphs write to as well
+	stream, _ := ctx.Value(runStreamKey{}).(runStream)
+
+	// Start graph tracing if tracer is set. Nodes and edges nest under the graph
+	// span, which ends however the run returns.
 	if r.tracer != nil {
-		graphSpan = r.tracer.StartSpan(ctx, TraceEventGraphStart, "graph")
-		graphSpan.State = initialState
+		graphSpan := r.tracer.startSpan(ctx, TraceEventGraphStart, "graph", initialState, runID)
+		spanCtx := ctx
+		defer func() {
+			r.tracer.EndSpan(spanCtx, graphSpan, result, err)
+		}()
+		ctx = ContextWithSpan(ctx, graphSpan)
+	}
+
+	recursionLimit := resolveRecursionLimit(ctx, config)
+	var step int
+	var lastNodes []string
+
+	// Channel versions and the versions each subscribed node last ran with
+	versions := make(channelVersions)
+	seenVersions := make(map[string]map[string]int)
+
+	// Deferred nodes waiting for other branches and arrivals at join edges. A
+	// resumed run continues with the arrivals of its checkpoint, which subgraphs
+	// must not reuse.
+	var waiting []string
+	arrivals, _ := ctx.Value(joinArrivalsKey{}).(joinArrivals)
+	if arrivals != nil {
+		ctx = context.WithValue(ctx, joinArrivalsKey{}, joinArrivals(nil))
+	} else {
+		arrivals = make(joinArrivals)
 	}
 
-	for len(currentNodes) > 0 {
+	for len(currentNodes) > 0 || len(pendingSends) > 0 || len(waiting) > 0 {
 		// Filter out END nodes
 		activeNodes := make([]string, 0, len(currentNodes))
 		for _, node := range currentNodes {
@@ -243,43 +445,141 @@ func (r *StateRunnable[S]) InvokeWithConfig(ctx context.Context, initialState S,
 				activeNodes = append(activeNodes, node)
 			}
 		}
-		currentNodes = activeNodes
+		currentNodes = r.triggeredNodes(activeNodes, versions, seenVersions)
+
+		// Deferred nodes wait until every other branch has finished
+		currentNodes, waiting = r.holdDeferred(currentNodes, waiting, len(pendingSends) > 0)
+
+		// Scheduled nodes read the shared state, Sends bring their own input
+		scheduled := len(currentNodes)
+		inputs := make([]S, len(currentNodes), len(currentNodes)+len(pendingSends))
+		for i := range inputs {
+			inputs[i] = state
+		}
+		for _, send := range pendingSends {
+			if send.Node == END {
+				continue
+			}
+			input, err := sendInput[S](send)
+			if err != nil {
+				var zero S
+				return zero, err
+			}
+			currentNodes = append(currentNodes, send.Node)
+			inputs = append(inputs, input)
+		}
+		pendingSends = nil
 
 		if len(currentNodes) == 0 {
 			break
 		}
 
+		// Abort runaway loops and timed out runs before running another superstep
+		var stopErr error
+		if timeoutErr := graphTimeoutError(ctx); timeoutErr != nil {
+			stopErr = timeoutErr
+		} else if ctxErr := ctx.Err(); ctxErr != nil {
+			stopErr = ctxErr
+		} else if step >= recursionLimit {
+			stopErr = &GraphRecursionError{
+				Limit:     recursionLimit,
+				Steps:     step,
+				LastNodes: lastNodes,
+			}
+		}
+		if stopErr != nil {
+			if config != nil && len(config.Callbacks) > 0 {
+				for _, cb := range config.Callbacks {
+					cb.OnChainError(ctx, stopErr, runID)
+				}
+			}
+			var zero S
+			return zero, stopErr
+		}
+		step++
+
 		// Check InterruptBefore
 		if config != nil && len(config.InterruptBefore) > 0 {
 			for _, node := range currentNodes {
 				if slices.Contains(config.InterruptBefore, node) {
-					return state, &GraphInterrupt{Node: node, State: state}
+					return state, &GraphInterrupt{Node: node, State: state, Namespace: joinNamespace(namespace, node)}
 				}
 			}
 		}
 
+		// Remember the channel versions subscribed nodes are about to read
+		for _, name := range currentNodes {
+			if triggers := r.graph.nodes[name].Options.Triggers; len(triggers) > 0 {
+				seenVersions[name] = versions.snapshot(triggers)
+			}
+		}
+
+		if observer != nil {
+			observer.stepStarted(step, currentNodes)
+		}
+
+		// Keep the state as it was before the step, for nodes that change it in place
+		stepState := copyState(state)
+
 		// Execute nodes in parallel
-		results, errorsList := r.executeNodesParallel(ctx, currentNodes, state, config, runID)
+		results, errorsList := r.executeNodesParallel(ctx, currentNodes, inputs, config, runID, reused)
+		reused = nil
 
 		// Process results (including results from interrupted nodes)
-		processedResults, nextNodesFromCommands := r.processNodeResults(results)
+		processedResults, commands := r.processNodeResults(currentNodes, results)
+
+		// Refuse to merge parallel writes that would overwrite each other
+		if r.detectConflicts {
+			if conflictErr := r.checkWriteConflicts(stepState, currentNodes, processedResults, errorsList, step); conflictErr != nil {
+				if config != nil && len(config.Callbacks) > 0 {
+					for _, cb := range config.Callbacks {
+						cb.OnChainError(ctx, conflictErr, runID)
+					}
+				}
+				var zero S
+				return zero, conflictErr
+			}
+		}
 
 		// Merge results into state (this preserves state updates from interrupted nodes)
 		var mergeErr error
-		state, mergeErr = r.mergeState(ctx, state, processedResults)
+		state, mergeErr = r.mergeState(ctx, stepState, state, processedResults, versions)
 		if mergeErr != nil {
 			var zero S
 			return zero, mergeErr
 		}
 
+		// Stream what each successful node returned
+		if stream != nil {
+			for i, res := range processedResults {
+				input := inputs[i]
+				if i < scheduled {
+					input = stepState
+				}
+				if errorsList[i] == nil {
+					stream.emitStep(StreamModeUpdates, runStreamEvent{
+						nodeName:  currentNodes[i],
+						namespace: joinNamespace(namespace, currentNodes[i]),
+						runID:     runID,
+						step:      step,
+						event:     NodeEventComplete,
+						state:     res,
+						data:      stateDelta(input, res),
+					})
+				}
+			}
+		}
+
 		// Now check for errors after merging state
 		// We check here to determine if we should save checkpoints (for interrupts) or not (for regular errors)
 		var hasNodeInterrupt bool
 		var nodeInterrupt *NodeInterrupt
-		for _, err := range errorsList {
+		var interrupted int
+		for i, err := range errorsList {
 			if err != nil {
 				if errors.As(err, &nodeInterrupt) {
 					hasNodeInterrupt = true
+					interrupted = i
 					break
 				}
 			}
@@ -288,25 +588,30 @@ func (r *StateRunnable[S]) InvokeWithConfig(ctx context.Context, initialState S,
 		// Keep track of nodes that ran for callbacks and interrupts
 		nodesRan := make([]string, len(currentNodes))
 		copy(nodesRan, currentNodes)
+		lastNodes = nodesRan
+		updates := make([]any, len(processedResults))
+		for i, res := range processedResults {
+			updates[i] = res
+		}
 
 		// Notify callbacks of step completion (and save checkpoints)
 		// For NodeInterrupt: we DO want to save the checkpoint (Issue #70)
 		// For regular errors: we DON'T want to save checkpoints
-		if config != nil && len(config.Callbacks) > 0 {
-			if hasNodeInterrupt {
-				// Save checkpoint before returning the interrupt
-				for _, cb := range config.Callbacks {
-					if gcb, ok := cb.(GraphCallbackHandler); ok {
-						var nodeName string
-						if len(nodesRan) == 1 {
-							nodeName = nodesRan[0]
-						} else {
-							nodeName = fmt.Sprintf("step:%v", nodesRan)
-						}
-						gcb.OnGraphStep(ctx, nodeName, state)
-					}
-				}
+		if hasNodeInterrupt {
+			// Save checkpoint before returning the interrupt, resuming at the node,
+			// or at its Send if it ran for one
+			next, sends := []string{nodeInterrupt.Node}, []Send(nil)
+			if interrupted >= scheduled {
+				next, sends = nil, []Send{{Node: currentNodes[interrupted], Arg: inputs[interrupted]}}
 			}
+			notifyStep(ctx, config, Superstep{
+				Step:    step,
+				Nodes:   nodesRan,
+				Next:    mergeNodeLists(next, waiting),
+				Sends:   sends,
+				Updates: updates,
+				Joins:   arrivals.sources(),
+			}, state)
 		}
 
 		// Now handle the errors
@@ -320,10 +625,42 @@ func (r *StateRunnable[S]) InvokeWithConfig(ctx context.Context, initialState S,
 						State:          state,
 						InterruptValue: nodeInterrupt.Value,
 						NextNodes:      []string{nodeInterrupt.Node},
+						Namespace:      nodeInterrupt.Namespace,
+					}
+				}
+
+				// Report the run timeout rather than the node error it caused
+				if timeoutErr := graphTimeoutError(ctx); timeoutErr != nil {
+					err = timeoutErr
+				}
+
+				// For regular errors (not interrupts), don't save checkpoint, but
+				// keep the outputs of the scheduled nodes that succeeded
+				if config != nil && len(config.Callbacks) > 0 {
+					var writes []PendingWrite
+					for i := range scheduled {
+						if errorsList[i] == nil {
+							writes = append(writes, PendingWrite{Node: currentNodes[i], Value: results[i]})
+						}
+					}
+					if len(writes) > 0 {
+						failed := Superstep{
+							Step:  step,
+							Nodes: currentNodes[:scheduled],
+							Next:  mergeNodeLists(currentNodes[:scheduled], waiting),
+							Joins: arrivals.sources(),
+						}
+						for i := scheduled; i < len(currentNodes); i++ {
+							failed.Sends = append(failed.Sends, Send{Node: currentNodes[i], Arg: inputs[i]})
+						}
+						for _, cb := range config.Callbacks {
+							if pcb, ok := cb.(PendingWritesCallbackHandler); ok {
+								pcb.OnPendingWrites(ctx, failed, stepState, writes, err)
+							}
+						}
 					}
 				}
 
-				// For regular errors (not interrupts), don't save checkpoint
 				// Notify callbacks of error
 				if config != nil && len(config.Callbacks) > 0 {
 					for _, cb := range config.Callbacks {
@@ -336,29 +673,43 @@ func (r *StateRunnable[S]) InvokeWithConfig(ctx context.Context, initialState S,
 		}
 
 		// Determine next nodes
-		nextNodesList, err := r.determineNextNodes(ctx, currentNodes, state, nextNodesFromCommands)
+		nextNodesList, nextSends, routedEdges, err := r.determineNextNodes(ctx, currentNodes, state, commands, arrivals)
 		if err != nil {
 			var zero S
 			return zero, err
 		}
 
+		// Record the edges taken
+		if r.tracer != nil {
+			for _, edge := range routedEdges {
+				r.tracer.TraceEdgeTraversal(ctx, edge.From, edge.To)
+			}
+		}
+
 		// Update currentNodes
 		currentNodes = nextNodesList
+		pendingSends = nextSends
+
+		if stream != nil {
+			stream.emitStep(StreamModeValues, runStreamEvent{
+				nodeName:  stepName(nodesRan),
+				namespace: namespace,
+				runID:     runID,
+				step:      step,
+				event:     EventGraphStep,
+				state:     state,
+			})
+		}
 
 		// Notify callbacks of step completion for normal execution (no errors)
-		if config != nil && len(config.Callbacks) > 0 {
-			for _, cb := range config.Callbacks {
-				if gcb, ok := cb.(GraphCallbackHandler); ok {
-					var nodeName string
-					if len(nodesRan) == 1 {
-						nodeName = nodesRan[0]
-					} else {
-						nodeName = fmt.Sprintf("step:%v", nodesRan)
-					}
-					gcb.OnGraphStep(ctx, nodeName, state)
-				}
-			}
-		}
+		notifyStep(ctx, config, Superstep{
+			Step:    step,
+			Nodes:   nodesRan,
+			Next:    runnableNodes(mergeNodeLists(nextNodesList, waiting)),
+			Sends:   slices.DeleteFunc(slices.Clone(nextSends), func(s Send) bool { return s.Node == END }),
+			Updates: updates,
+			Joins:   arrivals.sources(),
+		}, state)
 
 		// Check InterruptAfter
 		if config != nil && len(config.InterruptAfter) > 0 {
@@ -367,18 +718,14 @@ func (r *StateRunnable[S]) InvokeWithConfig(ctx context.Context, initialState S,
 					return state, &GraphInterrupt{
 						Node:      node,
 						State:     state,
-						NextNodes: nextNodesList,
+						NextNodes: mergeNodeLists(nextNodesList, waiting),
+						Namespace: joinNamespace(namespace, node),
 					}
 				}
 			}
 		}
 	}
 
-	// End graph tracing
-	if r.tracer != nil && graphSpan != nil {
-		r.tracer.EndSpan(ctx, graphSpan, state, nil)
-	}
-
 	// Notify callbacks of graph end
 	if config != nil && len(config.Callbacks) > 0 {
 		outputs := convertStateToMap(state)
@@ -390,26 +737,47 @@ func (r *StateRunnable[S]) InvokeWithConfig(ctx context.Context, initialState S,
 	return state, nil
 }
 
-// executeNodeWithRetry executes a node with retry logic based on the retry policy.
-func (r *StateRunnable[S]) executeNodeWithRetry(ctx context.Context, node TypedNode[S], state S) (S, error) {
+// resolveRecursionLimit returns the superstep limit for a run. An explicit config
+// takes precedence, then a config inherited through the context (so subgraphs use
+// their parent's limit), then DefaultRecursionLimit.
+func resolveRecursionLimit(ctx context.Context, config *Config) int {
+	if config != nil && config.RecursionLimit > 0 {
+		return config.RecursionLimit
+	}
+	if parent := GetConfig(ctx); parent != nil && parent.RecursionLimit > 0 {
+		return parent.RecursionLimit
+	}
+	return DefaultRecursionLimit
+}
+
+// graphTimeoutError returns the ErrGraphTimeout cause if the run's Config.Timeout has expired.
+func graphTimeoutError(ctx context.Context) error {
+	if cause := context.Cause(ctx); cause != nil && errors.Is(cause, ErrGraphTimeout) {
+		return cause
+	}
+	return nil
+}
+
+// executeNodeWithRetry executes a node with retry logic based on the node's own
+// retry policy, falling back to the graph-wide retry policy.
+func (r *StateRunnable[S]) executeNodeWithRetry(ctx context.Context, node TypedNode[S], state S, config *Config) (S, error) {
 	var lastErr error
 	var zero S
 
-	maxRetries := 1 // Default: no retries
-	if r.graph.retryPolicy != nil {
-		maxRetries = r.graph.retryPolicy.MaxRetries + 1 // +1 for initial attempt
+	maxAttempts := 1 // Default: no retries
+	if node.Options.RetryPolicy != nil {
+		maxAttempts = max(node.Options.RetryPolicy.MaxAttempts, 1)
+	} else if r.graph.retryPolicy != nil {
+		maxAttempts = r.graph.retryPolicy.MaxRetries + 1 // +1 for initial attempt
 	}
 
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		var result S
-		var err error
-
-		if r.nodeRunner != nil {
-			result, err = r.nodeRunner(ctx, node.Name, state)
-		} else {
-			result, err = node.Function(ctx, state)
-		}
+	timeout := r.graph.nodeTimeout
+	if node.Options.Timeout > 0 {
+		timeout = node.Options.Timeout
+	}
 
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		result, err := r.runNode(ctx, node, state, timeout)
 		if err == nil {
 			return result, nil
 		}
@@ -423,31 +791,133 @@ func (r *StateRunnable[S]) executeNodeWithRetry(ctx context.Context, node TypedN
 
 		lastErr = err
 
-		// Check if error is retryable
-		if r.graph.retryPolicy != nil && attempt < maxRetries-1 {
-			if r.isRetryableError(err) {
-				// Apply backoff strategy
-				delay := r.calculateBackoffDelay(attempt)
-				if delay > 0 {
-					select {
-					case <-time.After(delay):
-						// Continue with retry after delay
-					case <-ctx.Done():
-						// Context cancelled, return immediately
-						return zero, ctx.Err()
-					}
-				}
-				continue
+		// If max attempts reached, return error
+		if attempt >= maxAttempts-1 {
+			break
+		}
+
+		// Check if error is retryable and compute the backoff delay
+		var delay time.Duration
+		if policy := node.Options.RetryPolicy; policy != nil {
+			if policy.RetryableErrors != nil && !policy.RetryableErrors(err) {
+				break
 			}
+			delay = policy.backoffDelay(attempt)
+		} else {
+			if !r.isRetryableError(err) {
+				break
+			}
+			delay = r.calculateBackoffDelay(attempt)
 		}
 
-		// If not retryable or max retries reached, return error
-		break
+		r.reportRetry(ctx, node.Name, attempt+1, err, delay, state, config)
+
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+				// Continue with retry after delay
+			case <-ctx.Done():
+				// Context cancelled, return immediately
+				return zero, ctx.Err()
+			}
+		}
 	}
 
 	return zero, lastErr
 }
 
+// nodeTimeoutGrace is how long runNode waits for a node to return after its
+// context is cancelled by a timeout.
+const nodeTimeoutGrace = 50 * time.Millisecond
+
+// runNode executes a single attempt of a node. When the node has a timeout or the
+// context carries a deadline, the node runs in its own goroutine so that execution
+// returns soon after the deadline passes: the node gets nodeTimeoutGrace to return
+// once its context is cancelled, and is abandoned if it ignores it.
+func (r *StateRunnable[S]) runNode(ctx context.Context, node TypedNode[S], state S, timeout time.Duration) (S, error) {
+	call := func(ctx context.Context) (S, error) {
+		if r.nodeRunner != nil {
+			return r.nodeRunner(ctx, node.Name, state)
+		}
+		return node.Function(ctx, state)
+	}
+
+	if _, hasDeadline := ctx.Deadline(); timeout <= 0 && !hasDeadline {
+		return call(ctx)
+	}
+
+	nodeCtx := ctx
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		nodeCtx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+
+	type result struct {
+		value S
+		err   error
+	}
+	resultChan := make(chan result, 1)
+
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				var zero S
+				resultChan <- result{value: zero, err: fmt.Errorf("panic in node %s: %v", node.Name, p)}
+			}
+		}()
+		value, err := call(nodeCtx)
+		resultChan <- result{value: value, err: err}
+	}()
+
+	select {
+	case res := <-resultChan:
+		return res.value, res.err
+	case <-nodeCtx.Done():
+		// Give the node the chance to return once its context is cancelled, so
+		// that it does not keep running into the next attempt or superstep
+		cancel()
+		grace := time.NewTimer(nodeTimeoutGrace)
+		select {
+		case <-resultChan:
+		case <-grace.C:
+		}
+		grace.Stop()
+
+		var zero S
+		if ctx.Err() != nil {
+			// The run itself was cancelled or timed out
+			return zero, ctx.Err()
+		}
+		return zero, fmt.Errorf("%w: node %s exceeded %v", ErrNodeTimeout, node.Name, timeout)
+	}
+}
+
+// reportRetry notifies the tracer and retry-aware callbacks that a node failed
+// and is about to be retried.
+func (r *StateRunnable[S]) reportRetry(ctx context.Context, nodeName string, attempt int, err error, delay time.Duration, state S, config *Config) {
+	if r.tracer != nil {
+		// Count the retries on the span of the node being retried
+		if nodeSpan := SpanFromContext(ctx); nodeSpan != nil && nodeSpan.NodeName == nodeName {
+			nodeSpan.Metadata["retries"] = attempt
+		}
+
+		span := r.tracer.StartSpan(ctx, TraceEventNodeRetry, nodeName)
+		span.Metadata["attempt"] = attempt
+		span.Metadata["delay"] = delay
+		span.Metadata["run_id"] = currentScope(ctx).runID
+		r.tracer.EndSpan(ctx, span, state, err)
+	}
+
+	if config != nil {
+		for _, cb := range config.Callbacks {
+			if rcb, ok := cb.(RetryCallbackHandler); ok {
+				rcb.OnNodeRetry(ctx, nodeName, attempt, err, delay)
+			}
+		}
+	}
+}
+
 // isRetryableError checks if an error is retryable based on the retry policy.
 func (r *StateRunnable[S]) isRetryableError(err error) bool {
 	if r.graph.retryPolicy == nil {
@@ -486,13 +956,23 @@ func (r *StateRunnable[S]) calculateBackoffDelay(attempt int) time.Duration {
 	}
 }
 
-// executeNodesParallel executes valid nodes in parallel and returns their results or errors.
-func (r *StateRunnable[S]) executeNodesParallel(ctx context.Context, nodes []string, state S, config *Config, runID string) ([]S, []error) {
+// executeNodesParallel executes valid nodes in parallel, each with its input, and returns their results or errors.
+// Nodes with an output in reused are not run again.
+func (r *StateRunnable[S]) executeNodesParallel(ctx context.Context, nodes []string, inputs []S, config *Config, runID string, reused pendingWrites) ([]S, []error) {
 	var wg sync.WaitGroup
 	results := make([]S, len(nodes))
 	errorsList := make([]error, len(nodes))
 
 	for i, nodeName := range nodes {
+		// Reuse the output a failed run saved for the node, once
+		if value, ok := reused[nodeName]; ok {
+			if res, ok := value.(S); ok {
+				delete(reused, nodeName)
+				results[i] = res
+				continue
+			}
+		}
+
 		node, ok := r.graph.nodes[nodeName]
 		if !ok {
 			errorsList[i] = fmt.Errorf("%w: %s", ErrNodeNotFound, nodeName)
@@ -503,20 +983,25 @@ func (r *StateRunnable[S]) executeNodesParallel(ctx context.Context, nodes []str
 		idx := i
 		n := node
 		name := nodeName
+		state := inputs[i]
 
 		SafeGo(&wg, func() {
+			// The node runs in its namespace, where subgraphs it invokes nest
+			nodeNamespace := joinNamespace(GetNamespace(ctx), name)
+			nodeCtx := withNodeScope(ctx, nodeScope{namespace: nodeNamespace, runID: runID, tracer: r.tracer})
+
 			// Start node tracing
 			var nodeSpan *TraceSpan
 			if r.tracer != nil {
-				nodeSpan = r.tracer.StartSpan(ctx, TraceEventNodeStart, name)
-				nodeSpan.State = state
+				nodeSpan = r.tracer.startSpan(nodeCtx, TraceEventNodeStart, name, state, runID)
+				nodeCtx = ContextWithSpan(nodeCtx, nodeSpan)
 			}
 
 			var err error
 			var res S
 
-			// Execute node with retry logic
-			res, err = r.executeNodeWithRetry(ctx, n, state)
+			// Execute node with retry logic, with a stream writer bound to the node
+			res, err = r.executeNodeWithRetry(withNodeStreamWriter(nodeCtx, name, runID), n, state, config)
 
 			// End node tracing
 			if r.tracer != nil && nodeSpan != nil {
@@ -536,6 +1021,9 @@ func (r *StateRunnable[S]) executeNodesParallel(ctx context.Context, nodes []str
 				var nodeInterrupt *NodeInterrupt
 				if errors.As(err, &nodeInterrupt) {
 					nodeInterrupt.Node = name
+					if nodeInterrupt.Namespace == "" {
+						nodeInterrupt.Namespace = nodeNamespace
+					}
 					// For NodeInterrupt, save the result so state updates are preserved
 					results[idx] = res
 				}
@@ -565,9 +1053,16 @@ func (r *StateRunnable[S]) executeNodesParallel(ctx context.Context, nodes []str
 	return results, errorsList
 }
 
+// commandRoute holds where the Goto of the Commands returned by a node routes to.
+type commandRoute struct {
+	targets []string
+	sends   []Send
+}
+
 // processNodeResults processes the raw results from nodes, handling Commands.
-func (r *StateRunnable[S]) processNodeResults(results []S) ([]S, []string) {
-	var nextNodesFromCommands []string
+// It also returns where the nodes that returned a Command.Goto route to.
+func (r *StateRunnable[S]) processNodeResults(nodes []string, results []S) ([]S, map[string]*commandRoute) {
+	commands := make(map[string]*commandRoute)
 	processedResults := make([]S, len(results))
 
 	for i, res := range results {
@@ -592,12 +1087,14 @@ func (r *StateRunnable[S]) processNodeResults(results []S) ([]S, []string) {
 
 			// Extract Goto to determine next nodes
 			if cmd.Goto != nil {
-				switch g := cmd.Goto.(type) {
-				case string:
-					nextNodesFromCommands = append(nextNodesFromCommands, g)
-				case []string:
-					nextNodesFromCommands = append(nextNodesFromCommands, g...)
+				targets, sends := gotoTargets(cmd.Goto)
+				route := commands[nodes[i]]
+				if route == nil {
+					route = &commandRoute{}
+					commands[nodes[i]] = route
 				}
+				route.targets = append(route.targets, targets...)
+				route.sends = append(route.sends, sends...)
 			}
 		} else {
 			// Regular result - not a Command
@@ -605,13 +1102,50 @@ func (r *StateRunnable[S]) processNodeResults(results []S) ([]S, []string) {
 		}
 	}
 
-	return processedResults, nextNodesFromCommands
+	return processedResults, commands
+}
+
+// triggeredNodes filters out nodes subscribed to channels (see WithTriggers) that
+// already ran and whose channels have not changed since. Without a ChannelSchema
+// every node is kept.
+func (r *StateRunnable[S]) triggeredNodes(nodes []string, versions channelVersions, seen map[string]map[string]int) []string {
+	if _, ok := r.graph.Schema.(ChannelSchema[S]); !ok {
+		return nodes
+	}
+
+	triggered := make([]string, 0, len(nodes))
+	for _, name := range nodes {
+		if last, ran := seen[name]; ran && !versions.changedSince(last) {
+			continue
+		}
+		triggered = append(triggered, name)
+	}
+	return triggered
 }
 
 // mergeState merges the processed results into the current state.
-func (r *StateRunnable[S]) mergeState(ctx context.Context, currentState S, results []S) (S, error) {
+// With a ChannelSchema, the versions of the channels each result changed are bumped.
+// before is a copy of currentState taken before the nodes ran (see copyState):
+// a node that changed currentState in place and returned it changed the
+// channels that differ from before.
+func (r *StateRunnable[S]) mergeState(ctx context.Context, before, currentState S, results []S, versions channelVersions) (S, error) {
 	state := currentState
-	if r.graph.Schema != nil {
+	if channelSchema, ok := r.graph.Schema.(ChannelSchema[S]); ok && versions != nil {
+		// Fold partial updates channel by channel
+		for _, res := range results {
+			var changed []string
+			var err error
+			state, changed, err = channelSchema.UpdateChannels(state, res)
+			if err != nil {
+				var zero S
+				return zero, fmt.Errorf("schema update failed: %w", err)
+			}
+			if changedInPlace(currentState, res) {
+				changed = diffChannels(before, res)
+			}
+			versions.bump(changed)
+		}
+	} else if r.graph.Schema != nil {
 		// If Schema is defined, use it to update state with results
 		for _, res := range results {
 			var err error
@@ -636,56 +1170,166 @@ func (r *StateRunnable[S]) mergeState(ctx context.Context, currentState S, resul
 	return state, nil
 }
 
-// determineNextNodes determines the next nodes to execute based on static edges, conditional edges, or commands.
-func (r *StateRunnable[S]) determineNextNodes(ctx context.Context, currentNodes []string, state S, nextNodesFromCommands []string) ([]string, error) {
+// checkWriteConflicts returns a *ConflictingWriteError if two successful nodes of
+// the same superstep wrote different values to a key that has no reducer.
+func (r *StateRunnable[S]) checkWriteConflicts(state S, nodes []string, results []S, errs []error, step int) error {
+	writers := make(map[string][]int)
+	var keys []string
+	for i, res := range results {
+		if errs[i] != nil {
+			continue
+		}
+		for _, key := range writtenChannels(state, res) {
+			if r.hasReducer(key) {
+				continue
+			}
+			if _, ok := writers[key]; !ok {
+				keys = append(keys, key)
+			}
+			writers[key] = append(writers[key], i)
+		}
+	}
+
+	slices.Sort(keys)
+	for _, key := range keys {
+		idxs := writers[key]
+		if len(idxs) < 2 {
+			continue
+		}
+		first := channelValue(results[idxs[0]], key)
+		for _, idx := range idxs[1:] {
+			if !reflect.DeepEqual(first, channelValue(results[idx], key)) {
+				conflicting := make([]string, len(idxs))
+				for j, i := range idxs {
+					conflicting[j] = nodes[i]
+				}
+				return &ConflictingWriteError{Key: key, Nodes: conflicting, Step: step}
+			}
+		}
+	}
+	return nil
+}
+
+// hasReducer reports whether the graph's schema combines concurrent writes to key.
+func (r *StateRunnable[S]) hasReducer(key string) bool {
+	switch schema := any(r.graph.Schema).(type) {
+	case *MapSchema:
+		_, ok := schema.Reducers[key]
+		return ok
+	case *FieldMerger[S]:
+		_, ok := schema.FieldMergeFns[key]
+		return ok
+	}
+	return false
+}
+
+// notifyStep notifies the graph callbacks of config that a superstep completed.
+func notifyStep(ctx context.Context, config *Config, step Superstep, state any) {
+	if config == nil {
+		return
+	}
+	for _, cb := range config.Callbacks {
+		if scb, ok := cb.(SuperstepCallbackHandler); ok {
+			scb.OnSuperstep(ctx, step, state)
+		} else if gcb, ok := cb.(GraphCallbackHandler); ok {
+			gcb.OnGraphStep(ctx, stepName(step.Nodes), state)
+		}
+	}
+}
+
+// runnableNodes returns nodes without END.
+func runnableNodes(nodes []string) []string {
+	return slices.DeleteFunc(slices.Clone(nodes), func(n string) bool { return n == END })
+}
+
+// stepName names a superstep after its node, or its nodes if it ran several.
+func stepName(nodes []string) string {
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+	return fmt.Sprintf("step:%v", nodes)
+}
+
+// determineNextNodes determines the next nodes to execute, and the Sends to run
+// alongside them, based on static edges, conditional edges, or the Command.Goto
+// of each node, which overrides its edges. It also returns the edges taken,
+// including edges to END and to the nodes of Sends. Whatever the route, a join
+// target is only scheduled once all its sources have finished (see routeTo).
+func (r *StateRunnable[S]) determineNextNodes(ctx context.Context, currentNodes []string, state S, commands map[string]*commandRoute, arrivals joinArrivals) ([]string, []Send, []Edge, error) {
 	var nextNodesList []string
+	var nextSends []Send
+	var taken []Edge
+
+	// Keep the order in which the current nodes ran and the order in which their
+	// edges were declared so fan-out is deterministic
+	seen := make(map[string]bool)
+	addNext := func(n string) {
+		if !seen[n] {
+			seen[n] = true
+			nextNodesList = append(nextNodesList, n)
+		}
+	}
 
-	if len(nextNodesFromCommands) > 0 {
-		// Command.Goto overrides static edges
-		// We deduplicate
-		seen := make(map[string]bool)
-		for _, n := range nextNodesFromCommands {
-			if !seen[n] && n != END {
-				seen[n] = true
-				nextNodesList = append(nextNodesList, n)
+	// A node that ran several times through Sends is routed once
+	routed := make(map[string]bool)
+	for _, nodeName := range currentNodes {
+		if routed[nodeName] {
+			continue
+		}
+		routed[nodeName] = true
+
+		// A Command.Goto overrides the edges of its node
+		if route, ok := commands[nodeName]; ok {
+			for _, target := range route.targets {
+				taken = append(taken, Edge{From: nodeName, To: target})
+				if target != END && r.routeTo(arrivals, nodeName, target) {
+					addNext(target)
+				}
 			}
+			for _, send := range route.sends {
+				taken = append(taken, Edge{From: nodeName, To: send.Node})
+				r.routeTo(arrivals, nodeName, send.Node)
+			}
+			nextSends = append(nextSends, route.sends...)
+			continue
 		}
-	} else {
-		// Use static edges
-		nextNodesSet := make(map[string]bool)
-
-		for _, nodeName := range currentNodes {
-			// First check for conditional edges
-			nextNodeFn, hasConditional := r.graph.conditionalEdges[nodeName]
-			if hasConditional {
-				nextNode := nextNodeFn(ctx, state)
-				if nextNode == "" {
-					var zero S
-					_ = zero
-					return nil, fmt.Errorf("conditional edge returned empty next node from %s", nodeName)
+
+		// Then check for conditional edges
+		condEdge, hasConditional := r.graph.conditionalEdges[nodeName]
+		if hasConditional {
+			targets, sends, err := condEdge.targets(ctx, nodeName, state)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			for _, target := range targets {
+				if r.routeTo(arrivals, nodeName, target) {
+					addNext(target)
 				}
-				nextNodesSet[nextNode] = true
-			} else {
-				// Then check regular edges
-				foundNext := false
-				for _, edge := range r.graph.edges {
-					if edge.From == nodeName {
-						nextNodesSet[edge.To] = true
-						foundNext = true
-						// Do NOT break here, to allow fan-out (multiple edges from same node)
+				taken = append(taken, Edge{From: nodeName, To: target})
+			}
+			for _, send := range sends {
+				r.routeTo(arrivals, nodeName, send.Node)
+				taken = append(taken, Edge{From: nodeName, To: send.Node})
+			}
+			nextSends = append(nextSends, sends...)
+		} else {
+			// Then check regular edges
+			foundNext := false
+			for _, edge := range r.graph.edges {
+				if edge.From == nodeName {
+					taken = append(taken, edge)
+					if r.routeTo(arrivals, nodeName, edge.To) {
+						addNext(edge.To)
 					}
-				}
-
-				if !foundNext {
-					return nil, fmt.Errorf("%w: %s", ErrNoOutgoingEdge, nodeName)
+					foundNext = true
+					// Do NOT break here, to allow fan-out (multiple edges from same node)
 				}
 			}
-		}
 
-		// Update nextNodesList from set
-		for node := range nextNodesSet {
-			nextNodesList = append(nextNodesList, node)
+			if !foundNext {
+				return nil, nil, nil, fmt.Errorf("%w: %s", ErrNoOutgoingEdge, nodeName)
+			}
 		}
 	}
-	return nextNodesList, nil
+	return nextNodesList, nextSends, taken, nil
 }
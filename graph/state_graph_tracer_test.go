@@ -2,6 +2,7 @@ package graph
 
 import (
 	"context"
+	"errors"
 	"testing"
 )
 
@@ -80,3 +81,194 @@ func TestStateGraph_WithTracer(t *testing.T) {
 
 	t.Logf("StateGraph tracer test passed! Collected %d spans", len(spans))
 }
+
+// spansByEvent returns the spans of tracer with the given event, keyed by node
+// name, or by "from->to" for edges.
+func spansByEvent(tracer *Tracer, event TraceEvent) map[string]*TraceSpan {
+	found := make(map[string]*TraceSpan)
+	for _, span := range tracer.GetSpans() {
+		if span.Event != event {
+			continue
+		}
+		key := span.NodeName
+		if event == TraceEventEdgeTraversal {
+			key = span.FromNode + "->" + span.ToNode
+		}
+		found[key] = span
+	}
+	return found
+}
+
+func TestStateGraph_TracesEdgesUnderGraphSpan(t *testing.T) {
+	g := NewStateGraph[any]()
+	g.AddNode("A", "A", func(ctx context.Context, state any) (any, error) {
+		return &Command{Goto: "C"}, nil
+	})
+	g.AddNode("B", "B", func(ctx context.Context, state any) (any, error) {
+		return state, nil
+	})
+	g.AddNode("C", "C", func(ctx context.Context, state any) (any, error) {
+		return "routed", nil
+	})
+	g.AddNode("D", "D", func(ctx context.Context, state any) (any, error) {
+		return state, nil
+	})
+	g.SetEntryPoint("A")
+	g.AddEdge("A", "B")
+	g.AddConditionalEdge("C", func(ctx context.Context, state any) string {
+		return "D"
+	})
+	g.AddEdge("D", END)
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+	tracer := NewTracer()
+	runnable.SetTracer(tracer)
+
+	if _, err := runnable.Invoke(context.Background(), "start"); err != nil {
+		t.Fatalf("Failed to invoke: %v", err)
+	}
+
+	graphSpans := spansByEvent(tracer, TraceEventGraphEnd)
+	if len(graphSpans) != 1 {
+		t.Fatalf("expected 1 graph span, got %d", len(graphSpans))
+	}
+	graphSpan := graphSpans["graph"]
+
+	// Command.Goto, conditional and static edges are recorded, but not the
+	// static edge the Command overrode
+	edges := spansByEvent(tracer, TraceEventEdgeTraversal)
+	for _, key := range []string{"A->C", "C->D", "D->" + END} {
+		edge, ok := edges[key]
+		if !ok {
+			t.Errorf("missing edge %s", key)
+			continue
+		}
+		if edge.ParentID != graphSpan.ID {
+			t.Errorf("edge %s should be a child of the graph span", key)
+		}
+	}
+	if _, ok := edges["A->B"]; ok {
+		t.Error("edge A->B was overridden by Command.Goto and should not be traced")
+	}
+	if len(edges) != 3 {
+		t.Errorf("expected 3 edges, got %d", len(edges))
+	}
+
+	// Node spans are children of the graph span
+	nodes := spansByEvent(tracer, TraceEventNodeEnd)
+	for _, name := range []string{"A", "C", "D"} {
+		node, ok := nodes[name]
+		if !ok {
+			t.Errorf("missing span for node %s", name)
+			continue
+		}
+		if node.ParentID != graphSpan.ID {
+			t.Errorf("node %s should be a child of the graph span", name)
+		}
+	}
+}
+
+func TestStateGraph_TracesSubgraphUnderParentNode(t *testing.T) {
+	child := NewStateGraph[map[string]any]()
+	child.AddNode("search", "search", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		return state, nil
+	})
+	child.AddNode("summarize", "summarize", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		return state, nil
+	})
+	child.SetEntryPoint("search")
+	child.AddEdge("search", "summarize")
+	child.AddEdge("summarize", END)
+
+	g := NewStateGraph[map[string]any]()
+	identity := func(s map[string]any) map[string]any { return s }
+	if err := AddSubgraph(g, "research", child, identity, identity); err != nil {
+		t.Fatalf("Failed to add subgraph: %v", err)
+	}
+	g.SetEntryPoint("research")
+	g.AddEdge("research", END)
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+	tracer := NewTracer()
+	runnable.SetTracer(tracer)
+
+	if _, err := runnable.Invoke(context.Background(), map[string]any{}); err != nil {
+		t.Fatalf("Failed to invoke: %v", err)
+	}
+
+	// Rebuild the tree: graph -> research -> subgraph -> search, summarize
+	var root, subgraph *TraceSpan
+	for _, span := range tracer.GetSpans() {
+		if span.Event != TraceEventGraphEnd {
+			continue
+		}
+		if span.ParentID == "" {
+			root = span
+		} else {
+			subgraph = span
+		}
+	}
+	if root == nil || subgraph == nil {
+		t.Fatal("expected a root graph span and a subgraph span")
+	}
+
+	nodes := spansByEvent(tracer, TraceEventNodeEnd)
+	research := nodes["research"]
+	if research == nil || research.ParentID != root.ID {
+		t.Fatal("node research should be a child of the root graph span")
+	}
+	if subgraph.ParentID != research.ID {
+		t.Error("the subgraph span should be a child of node research")
+	}
+	for _, name := range []string{"search", "summarize"} {
+		if node := nodes[name]; node == nil || node.ParentID != subgraph.ID {
+			t.Errorf("node %s should be a child of the subgraph span", name)
+		}
+	}
+
+	// Edges of the subgraph nest under it and carry its namespace
+	edge := spansByEvent(tracer, TraceEventEdgeTraversal)["search->summarize"]
+	if edge == nil {
+		t.Fatal("missing edge search->summarize")
+	}
+	if edge.ParentID != subgraph.ID {
+		t.Error("edge search->summarize should be a child of the subgraph span")
+	}
+	if edge.Metadata["namespace"] != "research" {
+		t.Errorf("expected namespace research, got %v", edge.Metadata["namespace"])
+	}
+}
+
+func TestStateGraph_EndsGraphSpanOnError(t *testing.T) {
+	g := NewStateGraph[map[string]any]()
+	g.AddNode("broken", "broken", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		return nil, errors.New("boom")
+	})
+	g.SetEntryPoint("broken")
+	g.AddEdge("broken", END)
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+	tracer := NewTracer()
+	runnable.SetTracer(tracer)
+
+	if _, err := runnable.Invoke(context.Background(), map[string]any{}); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	graphSpan := spansByEvent(tracer, TraceEventGraphEnd)["graph"]
+	if graphSpan == nil {
+		t.Fatal("the graph span should end when the run fails")
+	}
+	if graphSpan.Error == nil {
+		t.Errorf("expected the graph span to record the error, got %v", graphSpan.Error)
+	}
+}
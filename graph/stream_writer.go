@@ -0,0 +1,125 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// streamSink receives the events emitted by nodes of a streamed run.
+type streamSink func(nodeName, namespace, runID string, event NodeEvent, data any)
+
+type streamSinkKey struct{}
+
+type streamWriterKey struct{}
+
+// withStreamSink makes the events emitted through stream writers of the run
+// executed with ctx reach sink.
+func withStreamSink(ctx context.Context, sink streamSink) context.Context {
+	return context.WithValue(ctx, streamSinkKey{}, sink)
+}
+
+// withNodeStreamWriter places a stream writer bound to the node in its context,
+// if the run is being streamed.
+func withNodeStreamWriter(ctx context.Context, nodeName, runID string) context.Context {
+	sink, ok := ctx.Value(streamSinkKey{}).(streamSink)
+	if !ok || sink == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, streamWriterKey{}, &StreamWriter{
+		nodeName:  nodeName,
+		namespace: GetNamespace(ctx),
+		runID:     runID,
+		sink:      sink,
+	})
+}
+
+// StreamWriter emits events from inside a running node to the stream of its run,
+// tagged with the node name, namespace and run ID. Use GetStreamWriter to obtain it.
+type StreamWriter struct {
+	nodeName  string
+	namespace string
+	runID     string
+	sink      streamSink
+}
+
+// GetStreamWriter returns the stream writer of the node running with ctx. It never
+// returns nil: when the run is not streamed, the writer silently drops events.
+//
+// Example:
+//
+//	g.AddNode("generate", "Generate an answer", func(ctx context.Context, state MyState) (MyState, error) {
+//	    resp, err := model.GenerateContent(ctx, state.Messages, graph.GetStreamWriter(ctx).CallOptions()...)
+//	    ...
+//	})
+func GetStreamWriter(ctx context.Context) *StreamWriter {
+	if w, ok := ctx.Value(streamWriterKey{}).(*StreamWriter); ok {
+		return w
+	}
+	return &StreamWriter{}
+}
+
+// Enabled reports whether the events emitted through the writer reach a stream.
+func (w *StreamWriter) Enabled() bool {
+	return w.sink != nil
+}
+
+// NodeName returns the name of the node the writer belongs to.
+func (w *StreamWriter) NodeName() string {
+	return w.nodeName
+}
+
+// Namespace returns the namespace path of the node the writer belongs to.
+func (w *StreamWriter) Namespace() string {
+	return w.namespace
+}
+
+// RunID returns the ID of the run the writer belongs to.
+func (w *StreamWriter) RunID() string {
+	return w.runID
+}
+
+// Emit sends an event with an arbitrary payload to the stream.
+func (w *StreamWriter) Emit(event NodeEvent, data any) {
+	if w.sink == nil {
+		return
+	}
+	w.sink(w.nodeName, w.namespace, w.runID, event, data)
+}
+
+// Write sends an arbitrary payload, such as progress or partial tool output,
+// to the stream as an EventCustom, received with StreamModeCustom.
+//
+// Example:
+//
+//	for i, url := range urls {
+//	    pages = append(pages, fetch(ctx, url))
+//	    graph.GetStreamWriter(ctx).Write(FetchProgress{Done: i + 1, Total: len(urls)})
+//	}
+func (w *StreamWriter) Write(data any) {
+	w.Emit(EventCustom, data)
+}
+
+// EmitToken sends a chunk of generated text to the stream as an EventToken.
+func (w *StreamWriter) EmitToken(token string) {
+	w.Emit(EventToken, token)
+}
+
+// StreamingFunc returns a function suitable for llms.WithStreamingFunc that
+// forwards every chunk produced by the model as an EventToken.
+func (w *StreamWriter) StreamingFunc() func(ctx context.Context, chunk []byte) error {
+	return func(_ context.Context, chunk []byte) error {
+		w.EmitToken(string(chunk))
+		return nil
+	}
+}
+
+// CallOptions returns the langchaingo call options that stream the model's output
+// through the writer, or nil when the run is not streamed, so models are only
+// switched to streaming when someone is listening.
+func (w *StreamWriter) CallOptions() []llms.CallOption {
+	if !w.Enabled() {
+		return nil
+	}
+	return []llms.CallOption{llms.WithStreamingFunc(w.StreamingFunc())}
+}
@@ -0,0 +1,189 @@
+package graph
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// streamingMockLLM streams its answer word by word through the streaming func
+type streamingMockLLM struct {
+	answer string
+}
+
+func (m *streamingMockLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	opts := llms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	if opts.StreamingFunc != nil {
+		for _, word := range strings.SplitAfter(m.answer, " ") {
+			if err := opts.StreamingFunc(ctx, []byte(word)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: m.answer}}}, nil
+}
+
+func (m *streamingMockLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return m.answer, nil
+}
+
+func TestStreamWriter_Tokens(t *testing.T) {
+	model := &streamingMockLLM{answer: "hello from the graph"}
+
+	g := NewStreamingStateGraphWithConfig[map[string]any](StreamConfig{
+		BufferSize: 100,
+		Mode:       StreamModeMessages,
+	})
+	g.AddNode("generate", "generate", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		resp, err := model.GenerateContent(ctx, nil, GetStreamWriter(ctx).CallOptions()...)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"answer": resp.Choices[0].Content}, nil
+	})
+	g.SetEntryPoint("generate")
+	g.AddEdge("generate", END)
+
+	runnable, err := g.CompileStreaming()
+	assert.NoError(t, err)
+
+	res := runnable.Stream(context.Background(), map[string]any{})
+
+	var tokens []string
+	runIDs := make(map[string]bool)
+	for event := range res.Events {
+		assert.Equal(t, EventToken, event.Event)
+		assert.Equal(t, "generate", event.NodeName)
+		tokens = append(tokens, event.Data.(string))
+		runIDs[event.RunID] = true
+	}
+
+	assert.Equal(t, []string{"hello ", "from ", "the ", "graph"}, tokens)
+	assert.Len(t, runIDs, 1)
+	assert.NotContains(t, runIDs, "")
+}
+
+func TestStreamWriter_DisabledOutsideStream(t *testing.T) {
+	writer := GetStreamWriter(context.Background())
+	assert.False(t, writer.Enabled())
+	assert.Nil(t, writer.CallOptions())
+
+	// Emitting without a stream is a no-op
+	writer.EmitToken("ignored")
+}
+
+type fetchProgress struct {
+	Done  int
+	Total int
+}
+
+func TestStreamWriter_CustomAndMultipleModes(t *testing.T) {
+	g := NewStreamingStateGraph[map[string]any]()
+	g.AddNode("fetch", "fetch", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		writer := GetStreamWriter(ctx)
+		for i := 1; i <= 3; i++ {
+			writer.Write(fetchProgress{Done: i, Total: 3})
+		}
+		writer.EmitToken("done")
+		return map[string]any{"pages": 3}, nil
+	})
+	g.SetEntryPoint("fetch")
+	g.AddEdge("fetch", END)
+
+	runnable, err := g.CompileStreaming()
+	assert.NoError(t, err)
+
+	res := runnable.Stream(context.Background(), map[string]any{}, StreamModeCustom, StreamModeMessages, StreamModeUpdates)
+
+	byMode := make(map[StreamMode][]StreamEvent[map[string]any])
+	for event := range res.Events {
+		byMode[event.Mode] = append(byMode[event.Mode], event)
+	}
+
+	custom := byMode[StreamModeCustom]
+	assert.Len(t, custom, 3)
+	for i, event := range custom {
+		assert.Equal(t, EventCustom, event.Event)
+		assert.Equal(t, "fetch", event.NodeName)
+		assert.Equal(t, fetchProgress{Done: i + 1, Total: 3}, event.Data)
+	}
+
+	assert.Len(t, byMode[StreamModeMessages], 1)
+	assert.Equal(t, "done", byMode[StreamModeMessages][0].Data)
+
+	assert.NotEmpty(t, byMode[StreamModeUpdates])
+	assert.Empty(t, byMode[StreamModeDebug])
+}
+
+func TestStreamWriter_EmitAfterStreamFinished(t *testing.T) {
+	// spawn hands its writer out, as a goroutine left running by the node would keep it
+	spawn := func(started chan<- *StreamWriter) func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		return func(ctx context.Context, state map[string]any) (map[string]any, error) {
+			started <- GetStreamWriter(ctx)
+			return state, nil
+		}
+	}
+
+	// emitUntilDone writes from several goroutines while the stream finishes and
+	// keeps writing after its Events channel is closed
+	emitUntilDone := func(writer *StreamWriter, events <-chan StreamEvent[map[string]any]) {
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+						writer.Write("late")
+						writer.EmitToken("late")
+					}
+				}
+			}()
+		}
+		for range events {
+		}
+		time.Sleep(20 * time.Millisecond)
+		close(stop)
+		wg.Wait()
+	}
+
+	t.Run("StateRunnable", func(t *testing.T) {
+		started := make(chan *StreamWriter, 1)
+		g := NewStateGraph[map[string]any]()
+		g.AddNode("spawn", "spawn", spawn(started))
+		g.SetEntryPoint("spawn")
+		g.AddEdge("spawn", END)
+		runnable, err := g.Compile()
+		assert.NoError(t, err)
+
+		res := runnable.Stream(context.Background(), map[string]any{}, nil, StreamModeCustom, StreamModeMessages, StreamModeValues)
+		emitUntilDone(<-started, res.Events)
+		<-res.Done
+	})
+
+	t.Run("StreamingRunnable", func(t *testing.T) {
+		started := make(chan *StreamWriter, 1)
+		g := NewStreamingStateGraph[map[string]any]()
+		g.AddNode("spawn", "spawn", spawn(started))
+		g.SetEntryPoint("spawn")
+		g.AddEdge("spawn", END)
+		runnable, err := g.CompileStreaming()
+		assert.NoError(t, err)
+
+		res := runnable.Stream(context.Background(), map[string]any{}, StreamModeCustom, StreamModeMessages)
+		emitUntilDone(<-started, res.Events)
+		<-res.Done
+	})
+}
@@ -3,6 +3,7 @@ package graph
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -12,10 +13,13 @@ type StreamMode string
 const (
 	// StreamModeValues emits the full state after each step
 	StreamModeValues StreamMode = "values"
-	// StreamModeUpdates emits the updates (deltas) from each node
+	// StreamModeUpdates emits the updates (deltas) from each node. With
+	// StateRunnable.Stream, each event carries only what the node returned.
 	StreamModeUpdates StreamMode = "updates"
-	// StreamModeMessages emits LLM messages/tokens (if available)
+	// StreamModeMessages emits LLM messages and the tokens nodes emit through their StreamWriter
 	StreamModeMessages StreamMode = "messages"
+	// StreamModeCustom emits the payloads nodes write through their StreamWriter
+	StreamModeCustom StreamMode = "custom"
 	// StreamModeDebug emits all events (default)
 	StreamModeDebug StreamMode = "debug"
 )
@@ -33,6 +37,19 @@ type StreamConfig struct {
 
 	// Mode specifies what kind of events to stream
 	Mode StreamMode
+
+	// Modes streams several kinds of events at once and takes precedence over
+	// Mode when set. An event matching several modes is emitted once per mode,
+	// with StreamEvent.Mode telling them apart.
+	Modes []StreamMode
+}
+
+// modes returns the stream modes selected by the config
+func (c StreamConfig) modes() []StreamMode {
+	if len(c.Modes) > 0 {
+		return c.Modes
+	}
+	return []StreamMode{c.Mode}
 }
 
 // DefaultStreamConfig returns the default streaming configuration
@@ -61,16 +78,30 @@ type StreamResult[S any] struct {
 
 	// Cancel function can be called to stop streaming
 	Cancel context.CancelFunc
+
+	// Dropped returns the number of events dropped so far because the Events
+	// buffer was full
+	Dropped func() int
 }
 
 // StreamingListener implements NodeListener for streaming events
 type StreamingListener[S any] struct {
 	eventChan chan<- StreamEvent[S]
 	config    StreamConfig
-	mutex     sync.RWMutex
+	// mutex is held for reading while an event is sent and for writing by
+	// Close, so that the channel can be closed once Close returns
+	mutex sync.RWMutex
 
-	droppedEvents int
+	droppedEvents atomic.Int64
 	closed        bool
+
+	// done is closed by Close to release the senders waiting for room in the channel
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// blockCtx makes the values and updates emitted by the executor wait for
+	// room in the channel until it is done, instead of being dropped
+	blockCtx context.Context
 }
 
 // NewStreamingListener creates a new streaming listener
@@ -78,25 +109,101 @@ func NewStreamingListener[S any](eventChan chan<- StreamEvent[S], config StreamC
 	return &StreamingListener[S]{
 		eventChan: eventChan,
 		config:    config,
+		done:      make(chan struct{}),
 	}
 }
 
 // emitEvent sends an event to the channel handling backpressure
 func (sl *StreamingListener[S]) emitEvent(event StreamEvent[S]) {
-	// Check if listener is closed
+	// Hold the lock until the event is sent, so Close waits for it
 	sl.mutex.RLock()
+	defer sl.mutex.RUnlock()
 	if sl.closed {
-		sl.mutex.RUnlock()
 		return
 	}
-	sl.mutex.RUnlock()
 
-	// Filter based on Mode
-	if !sl.shouldEmit(event) {
+	// Filter based on the selected modes, emitting once per matching mode
+	for _, mode := range sl.config.modes() {
+		if !shouldEmit(mode, event.Event) {
+			continue
+		}
+		event.Mode = mode
+		sl.send(event)
+	}
+}
+
+// runStream receives the values and updates of a run streamed with
+// StateRunnable.Stream and of the subgraphs it invokes, whatever their state type.
+type runStream interface {
+	emitStep(mode StreamMode, event runStreamEvent)
+}
+
+// runStreamEvent is a StreamEvent before its state is typed.
+type runStreamEvent struct {
+	nodeName  string
+	namespace string
+	runID     string
+	step      int
+	event     NodeEvent
+	state     any
+	data      any
+}
+
+// emitStep emits a value or update produced by the executor
+func (sl *StreamingListener[S]) emitStep(mode StreamMode, e runStreamEvent) {
+	event := StreamEvent[S]{
+		Timestamp: time.Now(),
+		NodeName:  e.nodeName,
+		Event:     e.event,
+		Metadata:  make(map[string]any),
+		RunID:     e.runID,
+		Data:      e.data,
+		Step:      e.step,
+		Namespace: e.namespace,
+	}
+	if s, ok := e.state.(S); ok {
+		event.State = s
+	} else if event.Data == nil {
+		event.Data = e.state
+	}
+	sl.emitForMode(mode, event)
+}
+
+// emitForMode sends an event produced for one stream mode if that mode, or
+// StreamModeDebug, is selected
+func (sl *StreamingListener[S]) emitForMode(mode StreamMode, event StreamEvent[S]) {
+	sl.mutex.RLock()
+	defer sl.mutex.RUnlock()
+	if sl.closed {
 		return
 	}
 
-	// Try to send event without blocking
+	for _, m := range sl.config.modes() {
+		if m == mode || m == StreamModeDebug {
+			event.Mode = m
+			if sl.blockCtx != nil {
+				sl.sendBlocking(event)
+			} else {
+				sl.send(event)
+			}
+		}
+	}
+}
+
+// sendBlocking delivers an event, waiting for room in the channel unless
+// blockCtx is done or the listener is closing
+func (sl *StreamingListener[S]) sendBlocking(event StreamEvent[S]) {
+	select {
+	case sl.eventChan <- event:
+	case <-sl.blockCtx.Done():
+		sl.handleBackpressure()
+	case <-sl.done:
+		sl.handleBackpressure()
+	}
+}
+
+// send delivers an event without blocking
+func (sl *StreamingListener[S]) send(event StreamEvent[S]) {
 	select {
 	case sl.eventChan <- event:
 		// Event sent successfully
@@ -109,22 +216,26 @@ func (sl *StreamingListener[S]) emitEvent(event StreamEvent[S]) {
 	}
 }
 
-func (sl *StreamingListener[S]) shouldEmit(event StreamEvent[S]) bool {
-	switch sl.config.Mode {
+// shouldEmit reports whether an event belongs to the given stream mode
+func shouldEmit(mode StreamMode, event NodeEvent) bool {
+	switch mode {
 	case StreamModeDebug:
 		return true
 	case StreamModeValues:
 		// Only emit OnGraphStep events (which contain full state)
 		// We expect a custom event type or we rely on node complete if it returns full state?
 		// For now, emit everything that looks like a state update
-		return event.Event == NodeEventComplete || event.Event == EventChainEnd
+		return event == NodeEventComplete || event == EventChainEnd || event == EventGraphStep
 	case StreamModeUpdates:
 		// Emit node outputs
-		return event.Event == NodeEventComplete || event.Event == EventChainEnd
+		return event == NodeEventComplete || event == EventChainEnd
 	case StreamModeMessages:
 		// Emit LLM events - this is tricky because generic S doesn't imply LLM events
 		// But if the event metadata says it's LLM...
-		return event.Event == EventLLMEnd || event.Event == EventLLMStart
+		return event == EventLLMEnd || event == EventLLMStart || event == EventToken
+	case StreamModeCustom:
+		// Emit payloads written by nodes
+		return event == EventCustom
 	default:
 		return true
 	}
@@ -143,8 +254,28 @@ func (sl *StreamingListener[S]) OnNodeEvent(ctx context.Context, event NodeEvent
 	sl.emitEvent(streamEvent)
 }
 
-// Close marks the listener as closed to prevent sending to closed channels
+// emitData forwards an event emitted by a node through its StreamWriter
+func (sl *StreamingListener[S]) emitData(nodeName, namespace, runID string, event NodeEvent, data any) {
+	sl.emitEvent(StreamEvent[S]{
+		Timestamp: time.Now(),
+		NodeName:  nodeName,
+		Event:     event,
+		Metadata:  make(map[string]any),
+		RunID:     runID,
+		Data:      data,
+		Namespace: namespace,
+	})
+}
+
+// Close marks the listener as closed to prevent sending to closed channels.
+// It returns once the events being sent are delivered or dropped, after which
+// the event channel can be closed: later events are discarded.
 func (sl *StreamingListener[S]) Close() {
+	sl.closeOnce.Do(func() {
+		if sl.done != nil {
+			close(sl.done)
+		}
+	})
 	sl.mutex.Lock()
 	defer sl.mutex.Unlock()
 	sl.closed = true
@@ -152,17 +283,12 @@ func (sl *StreamingListener[S]) Close() {
 
 // handleBackpressure manages channel backpressure
 func (sl *StreamingListener[S]) handleBackpressure() {
-	sl.mutex.Lock()
-	defer sl.mutex.Unlock()
-
-	sl.droppedEvents++
+	sl.droppedEvents.Add(1)
 }
 
 // GetDroppedEventsCount returns the number of dropped events
 func (sl *StreamingListener[S]) GetDroppedEventsCount() int {
-	sl.mutex.RLock()
-	defer sl.mutex.RUnlock()
-	return sl.droppedEvents
+	return int(sl.droppedEvents.Load())
 }
 
 // StreamingRunnable wraps a ListenableRunnable with streaming capabilities
@@ -184,8 +310,15 @@ func NewStreamingRunnableWithDefaults[S any](runnable *ListenableRunnable[S]) *S
 	return NewStreamingRunnable(runnable, DefaultStreamConfig())
 }
 
-// Stream executes the graph with real-time event streaming
-func (sr *StreamingRunnable[S]) Stream(ctx context.Context, initialState S) *StreamResult[S] {
+// Stream executes the graph with real-time event streaming.
+// Passing modes streams those modes instead of the ones in the StreamConfig,
+// for example Stream(ctx, state, StreamModeUpdates, StreamModeCustom).
+func (sr *StreamingRunnable[S]) Stream(ctx context.Context, initialState S, modes ...StreamMode) *StreamResult[S] {
+	config := sr.config
+	if len(modes) > 0 {
+		config.Modes = modes
+	}
+
 	// Create channels
 	eventChan := make(chan StreamEvent[S], sr.config.BufferSize)
 	resultChan := make(chan S, 1)
@@ -195,8 +328,9 @@ func (sr *StreamingRunnable[S]) Stream(ctx context.Context, initialState S) *Str
 	// Create cancellable context
 	streamCtx, cancel := context.WithCancel(ctx)
 
-	// Create streaming listener
-	streamingListener := NewStreamingListener(eventChan, sr.config)
+	// Create streaming listener, which also receives what nodes emit through their StreamWriter
+	streamingListener := NewStreamingListener(eventChan, config)
+	streamCtx = withStreamSink(streamCtx, streamingListener.emitData)
 
 	// Add the streaming listener to all nodes
 	// We add it globally using the graph
@@ -211,10 +345,7 @@ func (sr *StreamingRunnable[S]) Stream(ctx context.Context, initialState S) *Str
 			// Clean up: remove listener
 			sr.runnable.GetListenableGraph().RemoveGlobalListener(streamingListener)
 
-			// Give a small delay for any in-flight listener calls to complete
-			time.Sleep(10 * time.Millisecond)
-
-			// Now safe to close channels
+			// Now safe to close channels: Close waited for in-flight events
 			close(eventChan)
 			close(resultChan)
 			close(errorChan)
@@ -239,11 +370,115 @@ func (sr *StreamingRunnable[S]) Stream(ctx context.Context, initialState S) *Str
 	}()
 
 	return &StreamResult[S]{
-		Events: eventChan,
-		Result: resultChan,
-		Errors: errorChan,
-		Done:   doneChan,
-		Cancel: cancel,
+		Events:  eventChan,
+		Result:  resultChan,
+		Errors:  errorChan,
+		Done:    doneChan,
+		Cancel:  cancel,
+		Dropped: streamingListener.GetDroppedEventsCount,
+	}
+}
+
+type runStreamKey struct{}
+
+// Stream executes the compiled graph in the background and streams its events.
+// It works on any compiled graph, without a ListenableStateGraph. With no modes,
+// StreamModeValues is used.
+//
+//   - StreamModeValues emits an EventGraphStep with the full state after each superstep.
+//   - StreamModeUpdates emits a NodeEventComplete per node with only what the node
+//     returned in State, and in Data a map of the keys (or struct fields) whose
+//     values the node changed.
+//   - StreamModeMessages and StreamModeCustom emit what nodes write through their
+//     StreamWriter.
+//   - StreamModeDebug emits all of the above.
+//
+// Every event carries the run ID and the superstep number. Events of subgraphs
+// are streamed as well: updates and writer events carry the namespace path of
+// their node, such as "parent_node:child_node", and values events the namespace
+// of their graph, which is "" for the graph being streamed.
+//
+// Values and updates are never dropped: the run waits for the reader when the
+// Events buffer is full, so read Events until it is closed or call Cancel.
+// Messages and custom events are dropped instead, and counted by
+// StreamResult.Dropped, so that a slow reader does not hold up token streaming.
+//
+// Example:
+//
+//	res := app.Stream(ctx, state, nil, graph.StreamModeUpdates, graph.StreamModeMessages)
+//	for event := range res.Events {
+//	    fmt.Println(event.Mode, event.Step, event.NodeName, event.Data)
+//	}
+func (r *StateRunnable[S]) Stream(ctx context.Context, initialState S, config *Config, modes ...StreamMode) *StreamResult[S] {
+	streamConfig := DefaultStreamConfig()
+	streamConfig.Modes = modes
+	if len(modes) == 0 {
+		streamConfig.Modes = []StreamMode{StreamModeValues}
+	}
+	return r.StreamWithConfig(ctx, initialState, config, streamConfig)
+}
+
+// StreamWithConfig is Stream with the modes and the size of the Events buffer
+// set by a StreamConfig; its other fields are ignored. A zero BufferSize uses
+// the default size, and no mode StreamModeValues.
+//
+// Example:
+//
+//	res := app.StreamWithConfig(ctx, state, nil, graph.StreamConfig{
+//	    BufferSize: 10000,
+//	    Modes:      []graph.StreamMode{graph.StreamModeMessages},
+//	})
+func (r *StateRunnable[S]) StreamWithConfig(ctx context.Context, initialState S, config *Config, streamConfig StreamConfig) *StreamResult[S] {
+	if streamConfig.BufferSize <= 0 {
+		streamConfig.BufferSize = DefaultStreamConfig().BufferSize
+	}
+	if streamConfig.Mode == "" && len(streamConfig.Modes) == 0 {
+		streamConfig.Modes = []StreamMode{StreamModeValues}
+	}
+
+	eventChan := make(chan StreamEvent[S], streamConfig.BufferSize)
+	resultChan := make(chan S, 1)
+	errorChan := make(chan error, 1)
+	doneChan := make(chan struct{})
+
+	streamCtx, cancel := context.WithCancel(ctx)
+
+	// The executor emits values and updates, nodes emit messages and custom payloads
+	listener := NewStreamingListener(eventChan, streamConfig)
+	listener.blockCtx = streamCtx
+	runCtx := withStreamSink(streamCtx, listener.emitData)
+	runCtx = context.WithValue(runCtx, runStreamKey{}, runStream(listener))
+
+	go func() {
+		defer func() {
+			listener.Close()
+			close(eventChan)
+			close(resultChan)
+			close(errorChan)
+			close(doneChan)
+		}()
+
+		result, err := r.InvokeWithConfig(runCtx, initialState, config)
+		if err != nil {
+			select {
+			case errorChan <- err:
+			case <-streamCtx.Done():
+			}
+		} else {
+			select {
+			case resultChan <- result:
+			case <-streamCtx.Done():
+			}
+		}
+	}()
+
+	return &StreamResult[S]{
+		Events:  eventChan,
+		Result:  resultChan,
+		Errors:  errorChan,
+		Done:    doneChan,
+		Cancel:  cancel,
+		Dropped: listener.GetDroppedEventsCount,
 	}
 }
 
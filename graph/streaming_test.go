@@ -3,6 +3,7 @@ package graph
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -93,3 +94,107 @@ func TestStreamingModes(t *testing.T) {
 		assert.True(t, foundB)
 	})
 }
+
+func TestStateRunnableStream(t *testing.T) {
+	g := NewStateGraph[map[string]any]()
+	g.SetSchema(NewMapSchema())
+	g.AddNode("A", "A", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		return map[string]any{"a": 1}, nil
+	})
+	g.AddNode("B", "B", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		return map[string]any{"b": 2}, nil
+	})
+	g.AddNode("C", "C", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		return map[string]any{"c": 3}, nil
+	})
+	g.SetEntryPoint("A")
+	g.AddEdge("A", "B")
+	g.AddEdge("A", "C")
+	g.AddEdge("B", END)
+	g.AddEdge("C", END)
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	t.Run("Updates", func(t *testing.T) {
+		res := runnable.Stream(context.Background(), map[string]any{"input": "x"}, nil, StreamModeUpdates)
+
+		updates := map[string]StreamEvent[map[string]any]{}
+		for event := range res.Events {
+			assert.Equal(t, StreamModeUpdates, event.Mode)
+			assert.Equal(t, NodeEventComplete, event.Event)
+			updates[event.NodeName] = event
+		}
+		result := <-res.Result
+		assert.Equal(t, map[string]any{"input": "x", "a": 1, "b": 2, "c": 3}, result)
+
+		// Each update holds only what its node wrote, tagged with its superstep
+		assert.Len(t, updates, 3)
+		assert.Equal(t, 1, updates["A"].Step)
+		assert.Equal(t, map[string]any{"a": 1}, updates["A"].State)
+		assert.Equal(t, map[string]any{"a": 1}, updates["A"].Data)
+		assert.Equal(t, 2, updates["B"].Step)
+		assert.Equal(t, map[string]any{"b": 2}, updates["B"].Data)
+		assert.Equal(t, 2, updates["C"].Step)
+		assert.Equal(t, map[string]any{"c": 3}, updates["C"].Data)
+	})
+
+	t.Run("Values", func(t *testing.T) {
+		res := runnable.Stream(context.Background(), map[string]any{}, nil)
+
+		var events []StreamEvent[map[string]any]
+		for event := range res.Events {
+			events = append(events, event)
+		}
+		<-res.Done
+
+		// One event with the full state per superstep
+		assert.Len(t, events, 2)
+		assert.Equal(t, EventGraphStep, events[0].Event)
+		assert.Equal(t, StreamModeValues, events[0].Mode)
+		assert.Equal(t, 1, events[0].Step)
+		assert.Equal(t, "A", events[0].NodeName)
+		assert.Equal(t, map[string]any{"a": 1}, events[0].State)
+		assert.Equal(t, 2, events[1].Step)
+		assert.Equal(t, map[string]any{"a": 1, "b": 2, "c": 3}, events[1].State)
+		assert.NotEmpty(t, events[1].RunID)
+	})
+}
+
+func TestStateRunnableStream_SlowReader(t *testing.T) {
+	g := NewStateGraph[map[string]any]()
+	g.SetSchema(NewMapSchema())
+	g.AddNode("count", "count", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		n, _ := state["n"].(int)
+		return map[string]any{"n": n + 1}, nil
+	})
+	g.SetEntryPoint("count")
+	g.AddConditionalEdge("count", func(ctx context.Context, state map[string]any) string {
+		if state["n"].(int) < 50 {
+			return "count"
+		}
+		return END
+	})
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	// A buffer much smaller than the number of events loses none of them
+	res := runnable.StreamWithConfig(context.Background(), map[string]any{}, nil, StreamConfig{
+		BufferSize: 1,
+		Modes:      []StreamMode{StreamModeValues, StreamModeUpdates},
+	})
+	values, updates := 0, 0
+	for event := range res.Events {
+		time.Sleep(100 * time.Microsecond)
+		if event.Mode == StreamModeValues {
+			values++
+		} else {
+			updates++
+		}
+	}
+	assert.Equal(t, 50, values)
+	assert.Equal(t, 50, updates)
+	assert.Equal(t, 0, res.Dropped())
+	assert.Equal(t, 50, (<-res.Result)["n"])
+}
@@ -2,7 +2,11 @@ package graph
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+
+	"github.com/smallnest/langgraphgo/store"
 )
 
 // Subgraph represents a nested graph that can be used as a node
@@ -26,12 +30,24 @@ func NewSubgraph[S any](name string, graph *StateGraph[S]) (*Subgraph[S], error)
 	}, nil
 }
 
-// Execute runs the subgraph as a node
+// Execute runs the subgraph as a node.
+//
+// The subgraph inherits the callbacks, tags, metadata, configurable values,
+// resume value and timeout of the parent's Config, and its events, spans and checkpoints are
+// tagged with the namespace path of its nodes (see GetNamespace). An interrupt
+// raised inside the subgraph interrupts the parent; when the parent thread is
+// resumed, the subgraph resumes from its own checkpoint instead of starting over.
+// On an interrupt, Execute returns the state the subgraph reached with a
+// *NodeInterrupt.
 func (s *Subgraph[S]) Execute(ctx context.Context, state S) (S, error) {
-	result, err := s.runnable.Invoke(ctx, state)
+	result, err := runSubgraph(ctx, s.runnable, state, true)
 	if err != nil {
-		var zero S
-		return zero, fmt.Errorf("subgraph %s execution failed: %w", s.name, err)
+		var interrupt *NodeInterrupt
+		if !errors.As(err, &interrupt) {
+			var zero S
+			result = zero
+		}
+		return result, fmt.Errorf("subgraph %s execution failed: %w", s.name, err)
 	}
 	return result, nil
 }
@@ -49,6 +65,10 @@ func AddSubgraph[S, SubS any](g *StateGraph[S], name string, subgraph *StateGrap
 		subState := converter(state)
 		result, err := sg.Execute(ctx, subState)
 		if err != nil {
+			var interrupt *NodeInterrupt
+			if errors.As(err, &interrupt) {
+				return resultConverter(result), err
+			}
 			var zero S
 			return zero, err
 		}
@@ -168,7 +188,7 @@ func (rs *RecursiveSubgraph[S]) executeRecursive(ctx context.Context, state S, d
 		return zero, fmt.Errorf("failed to compile recursive subgraph at depth %d: %w", depth, err)
 	}
 
-	result, err := runnable.Invoke(ctx, state)
+	result, err := runSubgraph(ctx, runnable, state, false)
 	if err != nil {
 		var zero S
 		return zero, fmt.Errorf("recursive execution failed at depth %d: %w", depth, err)
@@ -237,7 +257,7 @@ func AddNestedConditionalSubgraph[S, SubS any](
 			return zero, fmt.Errorf("failed to compile subgraph %s: %w", subgraphName, err)
 		}
 
-		result, err := runnable.Invoke(ctx, subState)
+		result, err := runSubgraph(ctx, runnable, subState, true)
 		if err != nil {
 			var zero S
 			return zero, err
@@ -250,3 +270,80 @@ func AddNestedConditionalSubgraph[S, SubS any](
 	g.AddNode(name, "Nested conditional subgraph: "+name, wrappedFn)
 	return nil
 }
+
+// subgraphCheckpointer saves the checkpoints of subgraphs in their namespace of
+// the parent thread. CheckpointListener implements it.
+type subgraphCheckpointer interface {
+	resumeCheckpoint(ctx context.Context, namespace string) *store.Checkpoint
+	finishNamespace(ctx context.Context, namespace string, state any)
+}
+
+// subgraphConfig derives the config of a subgraph from the config of its parent.
+// Interrupt points and ResumeFrom name nodes of the parent, so they are not inherited.
+// The subgraph runs within the deadlines of the parent run and of the node that
+// runs it, which fail it with ErrGraphTimeout and ErrNodeTimeout; its own nodes
+// get the deadlines set on the subgraph with SetNodeTimeout and WithTimeout.
+func subgraphConfig(parent *Config) *Config {
+	if parent == nil {
+		return nil
+	}
+	return &Config{
+		Callbacks:      parent.Callbacks,
+		Metadata:       parent.Metadata,
+		Tags:           parent.Tags,
+		Configurable:   parent.Configurable,
+		RunName:        parent.RunName,
+		ResumeValue:    parent.ResumeValue,
+		RecursionLimit: parent.RecursionLimit,
+		Timeout:        parent.Timeout,
+	}
+}
+
+// runSubgraph invokes a subgraph from the node running with ctx. A resumable
+// subgraph continues from its checkpoint if its last run on the thread was
+// interrupted. A GraphInterrupt of the subgraph becomes a NodeInterrupt of the
+// parent node, keeping the namespace of the node that raised it.
+func runSubgraph[S any](ctx context.Context, runnable *StateRunnable[S], state S, resumable bool) (S, error) {
+	config := subgraphConfig(GetConfig(ctx))
+	namespace := GetNamespace(ctx)
+
+	var checkpointer subgraphCheckpointer
+	if config != nil && namespace != "" {
+		for _, cb := range config.Callbacks {
+			if c, ok := cb.(subgraphCheckpointer); ok {
+				checkpointer = c
+				break
+			}
+		}
+	}
+
+	if resumable && checkpointer != nil {
+		if cp := checkpointer.resumeCheckpoint(ctx, namespace); cp != nil {
+			next := resumeNodes(cp)
+			if saved, ok := stateAs[S](cp.State); ok && !slices.ContainsFunc(next, func(n string) bool { return !runnable.graph.hasNode(n) }) {
+				state = saved
+				config.ResumeFrom = next
+				if arrivals := joinArrivalsOf(cp.Metadata["joins"]); len(arrivals) > 0 {
+					ctx = context.WithValue(ctx, joinArrivalsKey{}, arrivals)
+				}
+				if sends := pendingSendsOf[S](cp.Metadata); len(sends) > 0 {
+					ctx = context.WithValue(ctx, pendingSendsKey{}, sends)
+				}
+			}
+		}
+	}
+
+	result, err := runnable.InvokeWithConfig(ctx, state, config)
+	if err != nil {
+		var interrupt *GraphInterrupt
+		if errors.As(err, &interrupt) {
+			return result, &NodeInterrupt{Value: interrupt.InterruptValue, Namespace: interrupt.Namespace}
+		}
+		return result, err
+	}
+
+	if checkpointer != nil {
+		checkpointer.finishNamespace(ctx, namespace, result)
+	}
+	return result, nil
+}
@@ -2,7 +2,10 @@ package graph
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -220,3 +223,208 @@ func TestRecursiveSubgraph_MaxDepth(t *testing.T) {
 	// Should not panic and should complete
 	assert.NotNil(t, result)
 }
+
+// buildNestedSubgraphs builds a three-level hierarchy outer -> middle -> inner
+// where the inner graph asks a human for approval. runs counts node executions.
+func buildNestedSubgraphs(t *testing.T, runs map[string]int) *CheckpointableStateGraph[map[string]any] {
+	var mu sync.Mutex
+	count := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		runs[name]++
+	}
+	identity := func(s map[string]any) map[string]any { return s }
+
+	inner := NewStateGraph[map[string]any]()
+	inner.SetSchema(NewMapSchema())
+	inner.AddNode("prepare", "prepare", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		count("prepare")
+		return map[string]any{"prepared": true}, nil
+	})
+	inner.AddNode("ask", "ask", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		count("ask")
+		answer, err := Interrupt(ctx, "approve?")
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"answer": answer, "namespace": GetNamespace(ctx)}, nil
+	})
+	inner.SetEntryPoint("prepare")
+	inner.AddEdge("prepare", "ask")
+	inner.AddEdge("ask", END)
+
+	middle := NewStateGraph[map[string]any]()
+	middle.SetSchema(NewMapSchema())
+	middle.AddNode("plan", "plan", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		count("plan")
+		return map[string]any{"planned": true}, nil
+	})
+	assert.NoError(t, AddSubgraph(middle, "inner", inner, identity, identity))
+	middle.SetEntryPoint("plan")
+	middle.AddEdge("plan", "inner")
+	middle.AddEdge("inner", END)
+
+	outer := NewCheckpointableStateGraph[map[string]any]()
+	outer.SetSchema(NewMapSchema())
+	sg, err := NewSubgraph("middle", middle)
+	assert.NoError(t, err)
+	outer.AddNode("middle", "middle", sg.Execute)
+	outer.AddNode("done", "done", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		count("done")
+		return map[string]any{"done": true}, nil
+	})
+	outer.SetEntryPoint("middle")
+	outer.AddEdge("middle", "done")
+	outer.AddEdge("done", END)
+	return outer
+}
+
+func TestSubgraph_NestedInterruptResumesFromParentThread(t *testing.T) {
+	runs := map[string]int{}
+	runnable, err := buildNestedSubgraphs(t, runs).CompileCheckpointable()
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = runnable.InvokeWithConfig(ctx, map[string]any{}, WithThreadID("t1"))
+
+	var interrupt *GraphInterrupt
+	assert.ErrorAs(t, err, &interrupt)
+	assert.Equal(t, "middle", interrupt.Node)
+	assert.Equal(t, "middle:inner:ask", interrupt.Namespace)
+	assert.Equal(t, "approve?", interrupt.InterruptValue)
+
+	// The subgraphs checkpointed their progress in their own namespaces
+	snapshot, err := runnable.GetState(ctx, &Config{Configurable: map[string]any{
+		"thread_id":     "t1",
+		"checkpoint_ns": "middle:inner",
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ask"}, snapshot.Next)
+	assert.Equal(t, "middle:inner", snapshot.Metadata["checkpoint_ns"])
+
+	// Resuming the parent thread resumes the innermost node
+	config := WithThreadID("t1")
+	config.ResumeValue = "yes"
+	result, err := runnable.InvokeWithConfig(ctx, map[string]any{}, config)
+	assert.NoError(t, err)
+	assert.Equal(t, "yes", result["answer"])
+	assert.Equal(t, "middle:inner:ask", result["namespace"])
+	assert.Equal(t, true, result["done"])
+
+	// Nodes that completed before the interrupt did not run again
+	assert.Equal(t, map[string]int{"plan": 1, "prepare": 1, "ask": 2, "done": 1}, runs)
+}
+
+func TestSubgraph_InterruptReturnsStateReached(t *testing.T) {
+	runs := map[string]int{}
+	middle := buildNestedSubgraphs(t, runs).nodes["middle"]
+
+	// The inner subgraph stops at ask, after plan and prepare ran
+	state, err := middle.Function(context.Background(), map[string]any{"topic": "go"})
+	var interrupt *NodeInterrupt
+	assert.ErrorAs(t, err, &interrupt)
+	assert.Equal(t, "approve?", interrupt.Value)
+	assert.Equal(t, map[string]any{"topic": "go", "planned": true, "prepared": true}, state)
+}
+
+func TestSubgraph_PropagatesConfigAndEvents(t *testing.T) {
+	runs := map[string]int{}
+	runnable, err := buildNestedSubgraphs(t, runs).Compile()
+	assert.NoError(t, err)
+
+	recorder := &namespaceRecorder{}
+	res := runnable.Stream(context.Background(), map[string]any{}, &Config{
+		Callbacks:   []CallbackHandler{recorder},
+		ResumeValue: "yes",
+	}, StreamModeUpdates)
+
+	var namespaces []string
+	for event := range res.Events {
+		namespaces = append(namespaces, event.Namespace)
+	}
+	assert.NoError(t, <-res.Errors)
+
+	// Updates of every level are streamed, tagged with their namespace path
+	assert.Equal(t, []string{
+		"middle:plan",
+		"middle:inner:prepare",
+		"middle:inner:ask",
+		"middle:inner",
+		"middle",
+		"done",
+	}, namespaces)
+
+	// Callbacks of the parent observe the steps of its subgraphs
+	assert.Equal(t, []string{
+		"middle/plan",
+		"middle:inner/prepare",
+		"middle:inner/ask",
+		"middle/inner",
+		"/middle",
+		"/done",
+	}, recorder.steps)
+	assert.Equal(t, 3, recorder.chainStarts)
+	assert.Equal(t, 2, recorder.nestedChainStarts)
+}
+
+func TestSubgraph_Deadlines(t *testing.T) {
+	blocking := func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	build := func(configure func(parent, child *StateGraph[map[string]any])) *StateRunnable[map[string]any] {
+		child := NewStateGraph[map[string]any]()
+		child.AddNode("wait", "wait", blocking)
+		child.SetEntryPoint("wait")
+		child.AddEdge("wait", END)
+
+		parent := NewStateGraph[map[string]any]()
+		configure(parent, child)
+		assert.NoError(t, AddSubgraph(parent, "child", child,
+			func(s map[string]any) map[string]any { return s },
+			func(s map[string]any) map[string]any { return s }))
+		parent.SetEntryPoint("child")
+		parent.AddEdge("child", END)
+
+		runnable, err := parent.Compile()
+		assert.NoError(t, err)
+		return runnable
+	}
+
+	// The timeout of the parent run applies to its subgraphs
+	timeout := 20 * time.Millisecond
+	runnable := build(func(parent, child *StateGraph[map[string]any]) {})
+	_, err := runnable.InvokeWithConfig(context.Background(), map[string]any{}, &Config{Timeout: &timeout})
+	assert.True(t, errors.Is(err, ErrGraphTimeout), "got %v", err)
+
+	// So do the node deadlines set on the subgraph
+	runnable = build(func(parent, child *StateGraph[map[string]any]) {
+		child.SetNodeTimeout(20 * time.Millisecond)
+	})
+	_, err = runnable.Invoke(context.Background(), map[string]any{})
+	assert.True(t, errors.Is(err, ErrNodeTimeout), "got %v", err)
+}
+
+type namespaceRecorder struct {
+	NoOpCallbackHandler
+	mu                sync.Mutex
+	steps             []string
+	chainStarts       int
+	nestedChainStarts int
+}
+
+func (r *namespaceRecorder) OnChainStart(ctx context.Context, serialized map[string]any, inputs map[string]any, runID string, parentRunID *string, tags []string, metadata map[string]any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.chainStarts++
+	if parentRunID != nil {
+		r.nestedChainStarts++
+	}
+}
+
+func (r *namespaceRecorder) OnGraphStep(ctx context.Context, stepNode string, state any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps = append(r.steps, GetNamespace(ctx)+"/"+stepNode)
+}
@@ -2,7 +2,11 @@ package graph
 
 import (
 	"context"
+	"maps"
+	"sync"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // TraceEvent represents different types of events in graph execution
@@ -24,6 +28,9 @@ const (
 	// TraceEventNodeError indicates an error occurred in node execution
 	TraceEventNodeError TraceEvent = "node_error"
 
+	// TraceEventNodeRetry indicates a failed node is about to be retried
+	TraceEventNodeRetry TraceEvent = "node_retry"
+
 	// TraceEventEdgeTraversal indicates traversal from one node to another
 	TraceEventEdgeTraversal TraceEvent = "edge_traversal"
 )
@@ -81,8 +88,10 @@ func (f TraceHookFunc) OnEvent(ctx context.Context, span *TraceSpan) {
 	f(ctx, span)
 }
 
-// Tracer manages trace collection and hooks
+// Tracer manages trace collection and hooks. It is safe for concurrent use by
+// the parallel nodes of a superstep.
 type Tracer struct {
+	mu    sync.RWMutex
 	hooks []TraceHook
 	spans map[string]*TraceSpan
 }
@@ -97,31 +106,66 @@ func NewTracer() *Tracer {
 
 // AddHook registers a new trace hook
 func (t *Tracer) AddHook(hook TraceHook) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.hooks = append(t.hooks, hook)
 }
 
+// record stores a span and notifies the hooks
+func (t *Tracer) record(ctx context.Context, span *TraceSpan) {
+	t.mu.Lock()
+	t.spans[span.ID] = span
+	hooks := t.hooks
+	t.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook.OnEvent(ctx, span)
+	}
+}
+
+// notify notifies the hooks of an updated span
+func (t *Tracer) notify(ctx context.Context, span *TraceSpan) {
+	t.mu.RLock()
+	hooks := t.hooks
+	t.mu.RUnlock()
+
+	for _, hook := range hooks {
+		hook.OnEvent(ctx, span)
+	}
+}
+
 // StartSpan creates a new trace span
 func (t *Tracer) StartSpan(ctx context.Context, event TraceEvent, nodeName string) *TraceSpan {
+	return t.startSpan(ctx, event, nodeName, nil, "")
+}
+
+// startSpan creates a span like StartSpan, setting its state and run ID before
+// the hooks see it.
+func (t *Tracer) startSpan(ctx context.Context, event TraceEvent, nodeName string, state any, runID string) *TraceSpan {
 	span := &TraceSpan{
 		ID:        generateSpanID(),
 		Event:     event,
 		NodeName:  nodeName,
 		StartTime: time.Now(),
+		State:     state,
 		Metadata:  make(map[string]any),
 	}
+	if runID != "" {
+		span.Metadata["run_id"] = runID
+	}
 
 	// Extract parent ID from context if available
 	if parentSpan := SpanFromContext(ctx); parentSpan != nil {
 		span.ParentID = parentSpan.ID
 	}
 
-	t.spans[span.ID] = span
-
-	// Notify hooks
-	for _, hook := range t.hooks {
-		hook.OnEvent(ctx, span)
+	// Tag spans with the namespace path of their node
+	if namespace := GetNamespace(ctx); namespace != "" {
+		span.Metadata["namespace"] = namespace
 	}
 
+	t.record(ctx, span)
+
 	return span
 }
 
@@ -141,10 +185,7 @@ func (t *Tracer) EndSpan(ctx context.Context, span *TraceSpan, state any, err er
 		span.Event = TraceEventGraphEnd
 	}
 
-	// Notify hooks
-	for _, hook := range t.hooks {
-		hook.OnEvent(ctx, span)
-	}
+	t.notify(ctx, span)
 }
 
 // TraceEdgeTraversal records an edge traversal event
@@ -165,21 +206,25 @@ func (t *Tracer) TraceEdgeTraversal(ctx context.Context, fromNode, toNode string
 		span.ParentID = parentSpan.ID
 	}
 
-	t.spans[span.ID] = span
-
-	// Notify hooks
-	for _, hook := range t.hooks {
-		hook.OnEvent(ctx, span)
+	// Tag edges with the namespace of the graph they belong to
+	if namespace := GetNamespace(ctx); namespace != "" {
+		span.Metadata["namespace"] = namespace
 	}
+
+	t.record(ctx, span)
 }
 
 // GetSpans returns all collected spans
 func (t *Tracer) GetSpans() map[string]*TraceSpan {
-	return t.spans
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return maps.Clone(t.spans)
 }
 
 // Clear removes all collected spans
 func (t *Tracer) Clear() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.spans = make(map[string]*TraceSpan)
 }
 
@@ -201,9 +246,10 @@ func SpanFromContext(ctx context.Context) *TraceSpan {
 	return nil
 }
 
-// generateSpanID creates a unique span identifier
+// generateSpanID creates a unique span identifier. Spans of parallel nodes start
+// within the same microsecond, so the ID cannot be derived from the time.
 func generateSpanID() string {
-	return time.Now().Format("20060102150405.000000")
+	return uuid.New().String()
 }
 
 // StateTracedRunnable[S] wraps a StateRunnable[S] with tracing capabilities
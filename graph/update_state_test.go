@@ -64,3 +64,81 @@ func TestUpdateState(t *testing.T) {
 	// Should be 11 (previous) + 5 (update) = 16
 	assert.Equal(t, 16, mSnap["count"])
 }
+
+func TestUpdateState_ResumesAfterAsNode(t *testing.T) {
+	g := NewCheckpointableStateGraph[map[string]any]()
+	g.SetSchema(NewMapSchema())
+
+	var ran []string
+	node := func(name string) func(context.Context, map[string]any) (map[string]any, error) {
+		return func(ctx context.Context, state map[string]any) (map[string]any, error) {
+			ran = append(ran, name)
+			return map[string]any{name: true}, nil
+		}
+	}
+	g.AddNode("draft", "draft", node("draft"))
+	g.AddNode("review", "review", node("review"))
+	g.AddNode("publish", "publish", node("publish"))
+	g.AddNode("revise", "revise", node("revise"))
+	g.SetEntryPoint("draft")
+	g.AddEdge("draft", "review")
+	g.AddConditionalEdge("review", func(ctx context.Context, state map[string]any) string {
+		if state["approved"] == true {
+			return "publish"
+		}
+		return "revise"
+	})
+	g.AddEdge("publish", END)
+	g.AddEdge("revise", END)
+
+	runnable, err := g.CompileCheckpointable()
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	config := WithThreadID("review-thread")
+	config.InterruptBefore = []string{"review"}
+	_, err = runnable.InvokeWithConfig(ctx, map[string]any{}, config)
+	var interrupt *GraphInterrupt
+	assert.ErrorAs(t, err, &interrupt)
+
+	// A human reviews the draft in place of the review node
+	updated, err := runnable.UpdateState(ctx, WithThreadID("review-thread"), "review", map[string]any{"approved": true})
+	assert.NoError(t, err)
+
+	snapshot, err := runnable.GetState(ctx, updated)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"publish"}, snapshot.Next)
+	assert.Equal(t, 2, metadataInt(snapshot.Metadata["step"]))
+
+	ran = nil
+	res, err := runnable.InvokeWithConfig(ctx, map[string]any{}, WithThreadID("review-thread"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"publish"}, ran)
+	assert.Equal(t, true, res["publish"])
+	assert.Nil(t, res["review"])
+}
+
+func TestUpdateState_KeepsSendsOfAsNode(t *testing.T) {
+	runnable := buildCheckpointedSends(t, NewMemoryCheckpointStore(), nil)
+
+	ctx := context.Background()
+	config := WithThreadID("update-sends-thread")
+	config.InterruptBefore = []string{"retrieve"}
+	_, err := runnable.InvokeWithConfig(ctx, map[string]any{}, config)
+	var interrupt *GraphInterrupt
+	assert.ErrorAs(t, err, &interrupt)
+
+	// The documents are provided in place of retrieve, whose router fans out
+	updated, err := runnable.UpdateState(ctx, WithThreadID("update-sends-thread"), "retrieve", map[string]any{"source": "manual"})
+	assert.NoError(t, err)
+
+	snapshot, err := runnable.GetState(ctx, updated)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"summarize"}, snapshot.Next)
+
+	result, err := runnable.InvokeWithConfig(ctx, map[string]any{}, WithThreadID("update-sends-thread"))
+	assert.NoError(t, err)
+	assert.Nil(t, result["docs"])
+	assert.Equal(t, "manual", result["source"])
+	assert.Equal(t, []string{"summary of a", "summary of b", "summary of c"}, summariesOf(result))
+}
@@ -0,0 +1,179 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrDuplicateNode is reported when two nodes are added with the same name.
+	ErrDuplicateNode = errors.New("duplicate node name")
+
+	// ErrUnreachableNode is reported when a node cannot be reached from the entry point.
+	ErrUnreachableNode = errors.New("node is unreachable from the entry point")
+
+	// ErrDeadEndNode is reported when a node has no outgoing edge, not even to END.
+	ErrDeadEndNode = errors.New("node has no outgoing edge")
+
+	// ErrMixedEdges is reported when a node has both a conditional edge and static edges.
+	// The conditional edge takes precedence and the static edges are never followed.
+	ErrMixedEdges = errors.New("node has both conditional and static edges")
+)
+
+// CompileOptions configures how a graph is validated when it is compiled.
+type CompileOptions struct {
+	// Strict makes validation warnings fail the build as well as errors.
+	Strict bool
+}
+
+// ValidationError reports the problems found when validating a graph.
+//
+// Errors make a graph impossible to run correctly: edges to undefined nodes,
+// an undefined entry point and duplicate node names. Warnings flag graphs that
+// may still be valid when nodes route with Command.Goto or Send: unreachable
+// nodes, dead-end nodes and nodes with both conditional and static edges.
+//
+// Each problem wraps one of the sentinel errors, so errors.Is(err, ErrNodeNotFound)
+// and similar checks work on the report.
+type ValidationError struct {
+	Errors   []error
+	Warnings []error
+}
+
+func (e *ValidationError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("graph validation failed with %d error(s) and %d warning(s)", len(e.Errors), len(e.Warnings)))
+	for _, err := range e.Errors {
+		sb.WriteString("\n  error: " + err.Error())
+	}
+	for _, err := range e.Warnings {
+		sb.WriteString("\n  warning: " + err.Error())
+	}
+	return sb.String()
+}
+
+// Unwrap returns every reported problem.
+func (e *ValidationError) Unwrap() []error {
+	return append(append([]error{}, e.Errors...), e.Warnings...)
+}
+
+// Validate checks the structure of the graph and returns a *ValidationError
+// describing every problem found, or nil if there is none.
+func (g *StateGraph[S]) Validate() error {
+	report := &ValidationError{}
+
+	// Node names added more than once
+	for _, name := range g.duplicateNodes {
+		report.Errors = append(report.Errors, fmt.Errorf("%w: %s", ErrDuplicateNode, name))
+	}
+
+	// Edges to and from undefined nodes
+	if g.entryPoint != "" && !g.hasNode(g.entryPoint) {
+		report.Errors = append(report.Errors, fmt.Errorf("%w: %s (entry point)", ErrNodeNotFound, g.entryPoint))
+	}
+	for _, edge := range g.edges {
+		if !g.hasNode(edge.From) {
+			report.Errors = append(report.Errors, fmt.Errorf("%w: %s (edge %s -> %s)", ErrNodeNotFound, edge.From, edge.From, edge.To))
+		}
+		if edge.To != END && !g.hasNode(edge.To) {
+			report.Errors = append(report.Errors, fmt.Errorf("%w: %s (edge %s -> %s)", ErrNodeNotFound, edge.To, edge.From, edge.To))
+		}
+	}
+	for _, from := range g.conditionalSources() {
+		if !g.hasNode(from) {
+			report.Errors = append(report.Errors, fmt.Errorf("%w: %s (conditional edge)", ErrNodeNotFound, from))
+		}
+		for _, b := range g.conditionalEdges[from].branches() {
+			if b.target != END && !g.hasNode(b.target) {
+				report.Errors = append(report.Errors, fmt.Errorf("%w: %s (path %q of conditional edge from %s)", ErrNodeNotFound, b.target, b.path, from))
+			}
+		}
+	}
+
+	// Dead ends and nodes whose static edges are shadowed by a conditional edge
+	names := make([]string, 0, len(g.nodes))
+	for name := range g.nodes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		_, hasConditional := g.conditionalEdges[name]
+		hasStatic := false
+		for _, edge := range g.edges {
+			if edge.From == name {
+				hasStatic = true
+				break
+			}
+		}
+
+		switch {
+		case hasConditional && hasStatic:
+			report.Warnings = append(report.Warnings, fmt.Errorf("%w: %s", ErrMixedEdges, name))
+		case !hasConditional && !hasStatic:
+			report.Warnings = append(report.Warnings, fmt.Errorf("%w: %s", ErrDeadEndNode, name))
+		}
+	}
+
+	// Unreachable nodes. A conditional edge without a path map may lead anywhere,
+	// so reachability can only be judged when every reachable branch is known.
+	if g.entryPoint != "" {
+		if reachable, known := g.reachableNodes(); known {
+			for _, name := range names {
+				if !reachable[name] {
+					report.Warnings = append(report.Warnings, fmt.Errorf("%w: %s", ErrUnreachableNode, name))
+				}
+			}
+		}
+	}
+
+	if len(report.Errors) == 0 && len(report.Warnings) == 0 {
+		return nil
+	}
+	return report
+}
+
+// hasNode reports whether a node with the given name was added to the graph.
+func (g *StateGraph[S]) hasNode(name string) bool {
+	_, ok := g.nodes[name]
+	return ok
+}
+
+// reachableNodes walks the graph from the entry point along static edges and
+// path map entries. It returns false if a reachable node has a conditional edge
+// whose branches are unknown.
+func (g *StateGraph[S]) reachableNodes() (map[string]bool, bool) {
+	reachable := map[string]bool{g.entryPoint: true}
+	queue := []string{g.entryPoint}
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+
+		var next []string
+		if condEdge, ok := g.conditionalEdges[name]; ok {
+			branches := condEdge.branches()
+			if len(branches) == 0 {
+				return nil, false
+			}
+			for _, b := range branches {
+				next = append(next, b.target)
+			}
+		}
+		for _, edge := range g.edges {
+			if edge.From == name {
+				next = append(next, edge.To)
+			}
+		}
+
+		for _, n := range next {
+			if n != END && !reachable[n] {
+				reachable[n] = true
+				queue = append(queue, n)
+			}
+		}
+	}
+	return reachable, true
+}
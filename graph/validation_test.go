@@ -0,0 +1,85 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func passThrough(ctx context.Context, state map[string]any) (map[string]any, error) {
+	return state, nil
+}
+
+func TestValidate_Errors(t *testing.T) {
+	g := NewStateGraph[map[string]any]()
+	g.AddNode("a", "a", passThrough)
+	g.AddNode("a", "a again", passThrough)
+	g.AddNode("b", "b", passThrough)
+	g.SetEntryPoint("a")
+	g.AddEdge("a", "b")
+	g.AddEdge("b", "missing")
+	g.AddEdge("ghost", END)
+
+	_, err := g.Compile()
+
+	var verr *ValidationError
+	assert.ErrorAs(t, err, &verr)
+	assert.Len(t, verr.Errors, 3)
+	assert.ErrorIs(t, err, ErrDuplicateNode)
+	assert.ErrorIs(t, err, ErrNodeNotFound)
+	assert.Contains(t, err.Error(), "node not found: missing (edge b -> missing)")
+	assert.Contains(t, err.Error(), "node not found: ghost (edge ghost -> END)")
+}
+
+func TestValidate_Warnings(t *testing.T) {
+	g := NewStateGraph[map[string]any]()
+	g.AddNode("start", "start", passThrough)
+	g.AddNode("router", "router", passThrough)
+	g.AddNode("done", "done", passThrough)
+	g.AddNode("orphan", "orphan", passThrough)
+	g.AddNode("stuck", "stuck", passThrough)
+	g.SetEntryPoint("start")
+	g.AddEdge("start", "router")
+	g.AddEdge("router", "done")
+	g.AddConditionalEdges("router", func(ctx context.Context, state map[string]any) []string {
+		return []string{"done"}
+	}, map[string]string{"done": "done", "stuck": "stuck"})
+	g.AddEdge("done", END)
+	g.AddEdge("orphan", END)
+
+	err := g.Validate()
+	var verr *ValidationError
+	assert.ErrorAs(t, err, &verr)
+	assert.Empty(t, verr.Errors)
+	assert.Len(t, verr.Warnings, 3)
+	assert.True(t, errors.Is(err, ErrMixedEdges))
+	assert.True(t, errors.Is(err, ErrDeadEndNode))
+	assert.True(t, errors.Is(err, ErrUnreachableNode))
+	assert.Contains(t, err.Error(), "warning: node is unreachable from the entry point: orphan")
+
+	// Warnings only fail the build in strict mode
+	_, err = g.Compile()
+	assert.NoError(t, err)
+
+	_, err = g.CompileWithOptions(CompileOptions{Strict: true})
+	assert.ErrorAs(t, err, &verr)
+}
+
+func TestValidate_DynamicRoutingSkipsReachability(t *testing.T) {
+	g := NewStateGraph[map[string]any]()
+	g.AddNode("start", "start", passThrough)
+	g.AddNode("maybe", "maybe", passThrough)
+	g.SetEntryPoint("start")
+	g.AddConditionalEdge("start", func(ctx context.Context, state map[string]any) string {
+		return "maybe"
+	})
+	g.AddEdge("maybe", END)
+
+	// Without a path map any node may be the router's target
+	assert.NoError(t, g.Validate())
+
+	_, err := g.CompileWithOptions(CompileOptions{Strict: true})
+	assert.NoError(t, err)
+}
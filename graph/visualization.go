@@ -2,6 +2,7 @@ package graph
 
 import (
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 )
@@ -31,21 +32,34 @@ func (ge *Exporter[S]) DrawMermaid() string {
 
 // DrawMermaidWithOptions generates a Mermaid diagram with custom options
 func (ge *Exporter[S]) DrawMermaidWithOptions(opts MermaidOptions) string {
-	var sb strings.Builder
+	return ge.Diagram().DrawMermaid(opts)
+}
 
-	// Start Mermaid flowchart
-	direction := opts.Direction
-	if direction == "" {
-		direction = "TD"
-	}
-	sb.WriteString(fmt.Sprintf("flowchart %s\n", direction))
+// Diagram is the structure of a graph as the exporters draw it: its nodes,
+// with START when it has an entry point and END when an edge leads to it, and
+// its edges in drawing order.
+type Diagram struct {
+	Entry string        `json:"entry,omitempty"`
+	Nodes []string      `json:"nodes"`
+	Edges []DiagramEdge `json:"edges"`
+}
 
-	// Add entry point styling
-	if ge.graph.entryPoint != "" {
-		sb.WriteString(fmt.Sprintf("    %s[[\"%s\"]]\n", ge.graph.entryPoint, ge.graph.entryPoint))
-		sb.WriteString(fmt.Sprintf("    %s --> %s\n", "START", ge.graph.entryPoint))
-		sb.WriteString("    START([\"START\"])\n")
-		sb.WriteString("    style START fill:#90EE90\n")
+// DiagramEdge is an edge of a Diagram.
+type DiagramEdge struct {
+	From string `json:"from"`
+	// To is empty for a conditional edge whose targets are unknown
+	To string `json:"to,omitempty"`
+	// Label is the path map key of a conditional edge, when it differs from To
+	Label       string `json:"label,omitempty"`
+	Conditional bool   `json:"conditional,omitempty"`
+}
+
+// Diagram returns the structure of the graph that DrawMermaid draws.
+func (ge *Exporter[S]) Diagram() Diagram {
+	d := Diagram{Entry: ge.graph.entryPoint, Nodes: []string{}, Edges: []DiagramEdge{}}
+	if d.Entry != "" {
+		d.Nodes = append(d.Nodes, "START", d.Entry)
+		d.Edges = append(d.Edges, DiagramEdge{From: "START", To: d.Entry})
 	}
 
 	// Get sorted node names for consistent output
@@ -56,40 +70,78 @@ func (ge *Exporter[S]) DrawMermaidWithOptions(opts MermaidOptions) string {
 		}
 	}
 	sort.Strings(nodeNames)
-
-	// Add regular nodes
-	for _, name := range nodeNames {
-		sb.WriteString(fmt.Sprintf("    %s[\"%s\"]\n", name, name))
+	d.Nodes = append(d.Nodes, nodeNames...)
+	if ge.referencesEnd() {
+		d.Nodes = append(d.Nodes, END)
 	}
 
-	// Add END node if referenced
-	hasEnd := false
 	for _, edge := range ge.graph.edges {
-		if edge.To == END {
-			hasEnd = true
-			break
+		d.Edges = append(d.Edges, DiagramEdge{From: edge.From, To: edge.To})
+	}
+
+	// One conditional edge per path map entry when the branches are known
+	for _, from := range ge.graph.conditionalSources() {
+		branches := ge.graph.conditionalEdges[from].branches()
+		if len(branches) == 0 {
+			d.Edges = append(d.Edges, DiagramEdge{From: from, Conditional: true})
+			continue
+		}
+		for _, b := range branches {
+			edge := DiagramEdge{From: from, To: b.target, Conditional: true}
+			if b.path != b.target {
+				edge.Label = b.path
+			}
+			d.Edges = append(d.Edges, edge)
 		}
 	}
+	return d
+}
 
-	if hasEnd {
-		sb.WriteString("    END([\"END\"])\n")
-		sb.WriteString("    style END fill:#FFB6C1\n")
+// DrawMermaid generates a Mermaid flowchart of the diagram
+func (d Diagram) DrawMermaid(opts MermaidOptions) string {
+	var sb strings.Builder
+
+	// Start Mermaid flowchart
+	direction := opts.Direction
+	if direction == "" {
+		direction = "TD"
 	}
+	sb.WriteString(fmt.Sprintf("flowchart %s\n", direction))
 
-	// Add edges
-	for _, edge := range ge.graph.edges {
-		sb.WriteString(fmt.Sprintf("    %s --> %s\n", edge.From, edge.To))
+	// Add nodes, START and END as rounded nodes and the entry point as a subroutine
+	for _, name := range d.Nodes {
+		switch name {
+		case "START":
+			sb.WriteString("    START([\"START\"])\n")
+			sb.WriteString("    style START fill:#90EE90\n")
+		case END:
+			sb.WriteString("    END([\"END\"])\n")
+			sb.WriteString("    style END fill:#FFB6C1\n")
+		case d.Entry:
+			sb.WriteString(fmt.Sprintf("    %s[[\"%s\"]]\n", name, name))
+		default:
+			sb.WriteString(fmt.Sprintf("    %s[\"%s\"]\n", name, name))
+		}
 	}
 
-	// Add conditional edges
-	for from := range ge.graph.conditionalEdges {
-		sb.WriteString(fmt.Sprintf("    %s -.-> %s_condition((?))\n", from, from))
-		sb.WriteString(fmt.Sprintf("    style %s_condition fill:#FFFFE0,stroke:#333,stroke-dasharray: 5 5\n", from))
+	// Add edges, with a placeholder for conditional edges whose targets are unknown
+	for _, edge := range d.Edges {
+		switch {
+		case !edge.Conditional:
+			sb.WriteString(fmt.Sprintf("    %s --> %s\n", edge.From, edge.To))
+		case edge.To == "":
+			sb.WriteString(fmt.Sprintf("    %s -.-> %s_condition((?))\n", edge.From, edge.From))
+			sb.WriteString(fmt.Sprintf("    style %s_condition fill:#FFFFE0,stroke:#333,stroke-dasharray: 5 5\n", edge.From))
+		case edge.Label == "":
+			sb.WriteString(fmt.Sprintf("    %s -.-> %s\n", edge.From, edge.To))
+		default:
+			sb.WriteString(fmt.Sprintf("    %s -.->|%s| %s\n", edge.From, edge.Label, edge.To))
+		}
 	}
 
 	// Style entry point
-	if ge.graph.entryPoint != "" {
-		sb.WriteString(fmt.Sprintf("    style %s fill:#87CEEB\n", ge.graph.entryPoint))
+	if d.Entry != "" {
+		sb.WriteString(fmt.Sprintf("    style %s fill:#87CEEB\n", d.Entry))
 	}
 
 	return sb.String()
@@ -115,15 +167,7 @@ func (ge *Exporter[S]) DrawDOT() string {
 	}
 
 	// Add END node styling if referenced
-	hasEnd := false
-	for _, edge := range ge.graph.edges {
-		if edge.To == END {
-			hasEnd = true
-			break
-		}
-	}
-
-	if hasEnd {
+	if ge.referencesEnd() {
 		sb.WriteString("    END [label=\"END\", shape=ellipse, style=filled, fillcolor=lightpink];\n")
 	}
 
@@ -132,10 +176,17 @@ func (ge *Exporter[S]) DrawDOT() string {
 		sb.WriteString(fmt.Sprintf("    %s -> %s;\n", edge.From, edge.To))
 	}
 
-	// Add conditional edges
-	for from := range ge.graph.conditionalEdges {
-		sb.WriteString(fmt.Sprintf("    %s -> %s_condition [style=dashed, label=\"?\"];\n", from, from))
-		sb.WriteString(fmt.Sprintf("    %s_condition [label=\"?\", shape=diamond, style=filled, fillcolor=lightyellow];\n", from))
+	// Add conditional edges, one dashed edge per path map entry when the branches are known
+	for _, from := range ge.graph.conditionalSources() {
+		branches := ge.graph.conditionalEdges[from].branches()
+		if len(branches) == 0 {
+			sb.WriteString(fmt.Sprintf("    %s -> %s_condition [style=dashed, label=\"?\"];\n", from, from))
+			sb.WriteString(fmt.Sprintf("    %s_condition [label=\"?\", shape=diamond, style=filled, fillcolor=lightyellow];\n", from))
+			continue
+		}
+		for _, b := range branches {
+			sb.WriteString(fmt.Sprintf("    %s -> %s [style=dashed, label=\"%s\"];\n", from, b.target, b.path))
+		}
 	}
 
 	sb.WriteString("}\n")
@@ -194,9 +245,17 @@ func (ge *Exporter[S]) drawASCIINode(nodeName string, prefix string, isLast bool
 		}
 	}
 
-	// Check for conditional edge
-	if _, ok := ge.graph.conditionalEdges[nodeName]; ok {
-		outgoingEdges = append(outgoingEdges, "(Conditional)")
+	// Check for conditional edge, following its path map when the branches are known
+	if condEdge, ok := ge.graph.conditionalEdges[nodeName]; ok {
+		branches := condEdge.branches()
+		if len(branches) == 0 {
+			outgoingEdges = append(outgoingEdges, "(Conditional)")
+		}
+		for _, b := range branches {
+			if !slices.Contains(outgoingEdges, b.target) {
+				outgoingEdges = append(outgoingEdges, b.target)
+			}
+		}
 	}
 
 	// Sort for consistent output
@@ -219,6 +278,33 @@ func (ge *Exporter[S]) drawASCIINode(nodeName string, prefix string, isLast bool
 	}
 }
 
+// referencesEnd reports whether any static edge or path map entry leads to END.
+func (ge *Exporter[S]) referencesEnd() bool {
+	for _, edge := range ge.graph.edges {
+		if edge.To == END {
+			return true
+		}
+	}
+	for _, condEdge := range ge.graph.conditionalEdges {
+		for _, b := range condEdge.branches() {
+			if b.target == END {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// conditionalSources returns the nodes with a conditional edge, sorted for consistent output.
+func (g *StateGraph[S]) conditionalSources() []string {
+	froms := make([]string, 0, len(g.conditionalEdges))
+	for from := range g.conditionalEdges {
+		froms = append(froms, from)
+	}
+	slices.Sort(froms)
+	return froms
+}
+
 // GetGraphForRunnable returns a Exporter for the compiled graph's visualization
 func GetGraphForRunnable(r *Runnable) *Exporter[map[string]any] {
 	return NewExporter[map[string]any](r.graph)
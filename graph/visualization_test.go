@@ -29,6 +29,7 @@ func TestVisualization(t *testing.T) {
 	assert.Contains(t, mermaid, "A --> B")
 	assert.Contains(t, mermaid, "B -.-> B_condition((?))")
 	assert.Contains(t, mermaid, "C --> END")
+	assert.Contains(t, exporter.Diagram().Edges, DiagramEdge{From: "B", Conditional: true})
 
 	// Test Mermaid with Options
 	mermaidLR := exporter.DrawMermaidWithOptions(MermaidOptions{Direction: "LR"})
@@ -49,3 +50,43 @@ func TestVisualization(t *testing.T) {
 	// C is not reachable via static edges from B, so it won't be shown under B.
 	// This is expected behavior for static visualization of dynamic graphs.
 }
+
+func TestVisualization_PathMap(t *testing.T) {
+	g := NewStateGraph[map[string]any]()
+	g.AddNode("plan", "plan", func(ctx context.Context, state map[string]any) (map[string]any, error) { return state, nil })
+	g.AddNode("search", "search", func(ctx context.Context, state map[string]any) (map[string]any, error) { return state, nil })
+	g.AddNode("code", "code", func(ctx context.Context, state map[string]any) (map[string]any, error) { return state, nil })
+
+	g.SetEntryPoint("plan")
+	g.AddConditionalEdges("plan", func(ctx context.Context, state map[string]any) []string {
+		return []string{"search", "code"}
+	}, map[string]string{"search": "search", "run": "code", "done": END})
+	g.AddEdge("search", END)
+	g.AddEdge("code", END)
+
+	exporter := NewExporter(g)
+
+	mermaid := exporter.DrawMermaid()
+	assert.Contains(t, mermaid, "plan -.-> search")
+	assert.Contains(t, mermaid, "plan -.->|run| code")
+	assert.Contains(t, mermaid, "plan -.->|done| END")
+	assert.NotContains(t, mermaid, "plan_condition")
+
+	// Mermaid draws the diagram of the graph
+	diagram := exporter.Diagram()
+	assert.Equal(t, "plan", diagram.Entry)
+	assert.Equal(t, []string{"START", "plan", "code", "search", END}, diagram.Nodes)
+	assert.Contains(t, diagram.Edges, DiagramEdge{From: "START", To: "plan"})
+	assert.Contains(t, diagram.Edges, DiagramEdge{From: "plan", To: "search", Conditional: true})
+	assert.Contains(t, diagram.Edges, DiagramEdge{From: "plan", To: "code", Label: "run", Conditional: true})
+	assert.Equal(t, mermaid, diagram.DrawMermaid(MermaidOptions{}))
+
+	dot := exporter.DrawDOT()
+	assert.Contains(t, dot, "plan -> code [style=dashed, label=\"run\"]")
+	assert.Contains(t, dot, "plan -> END [style=dashed, label=\"done\"]")
+
+	ascii := exporter.DrawASCII()
+	assert.Contains(t, ascii, "search")
+	assert.Contains(t, ascii, "code")
+	assert.NotContains(t, ascii, "(?)")
+}
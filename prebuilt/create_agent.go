@@ -175,7 +175,8 @@ func CreateAgentMap(model llms.Model, inputTools []tools.Tool, maxIterations int
 				},
 			}
 		} else {
-			resp, err = model.GenerateContent(ctx, msgsToSend, llms.WithTools(toolDefs), llms.WithToolChoice("auto"))
+			callOpts := append([]llms.CallOption{llms.WithTools(toolDefs), llms.WithToolChoice("auto")}, graph.GetStreamWriter(ctx).CallOptions()...)
+			resp, err = model.GenerateContent(ctx, msgsToSend, callOpts...)
 			if err != nil {
 				return nil, err
 			}
@@ -331,7 +332,8 @@ func CreateAgent[S any](
 				},
 			}
 		} else {
-			resp, err = model.GenerateContent(ctx, msgsToSend, llms.WithTools(toolDefs))
+			callOpts := append([]llms.CallOption{llms.WithTools(toolDefs)}, graph.GetStreamWriter(ctx).CallOptions()...)
+			resp, err = model.GenerateContent(ctx, msgsToSend, callOpts...)
 			if err != nil {
 				return state, err
 			}
@@ -67,8 +67,9 @@ func CreateReactAgentMap(model llms.Model, inputTools []tools.Tool, maxIteration
 			})
 		}
 
-		// Call model with tools
-		resp, err := model.GenerateContent(ctx, messages, llms.WithTools(toolDefs))
+		// Call model with tools, streaming tokens when the run is streamed
+		callOpts := append([]llms.CallOption{llms.WithTools(toolDefs)}, graph.GetStreamWriter(ctx).CallOptions()...)
+		resp, err := model.GenerateContent(ctx, messages, callOpts...)
 		if err != nil {
 			return nil, err
 		}
@@ -210,7 +211,8 @@ func CreateReactAgent[S any](
 		}
 
 		messages := getMessages(state)
-		resp, err := model.GenerateContent(ctx, messages, llms.WithTools(toolDefs))
+		callOpts := append([]llms.CallOption{llms.WithTools(toolDefs)}, graph.GetStreamWriter(ctx).CallOptions()...)
+		resp, err := model.GenerateContent(ctx, messages, callOpts...)
 		if err != nil {
 			return state, err
 		}
@@ -3,8 +3,10 @@ package prebuilt
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/smallnest/langgraphgo/graph"
 	"github.com/stretchr/testify/assert"
 	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/tools"
@@ -63,3 +65,59 @@ func TestReactAgentWithWeatherTool(t *testing.T) {
 	messages := res["messages"].([]llms.MessageContent)
 	assert.True(t, len(messages) >= 2)
 }
+
+// StreamingMockLLM streams its answer word by word when a streaming func is set
+type StreamingMockLLM struct {
+	answer string
+}
+
+func (m *StreamingMockLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	opts := llms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	if opts.StreamingFunc != nil {
+		for _, word := range strings.SplitAfter(m.answer, " ") {
+			if err := opts.StreamingFunc(ctx, []byte(word)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: m.answer}}}, nil
+}
+
+func (m *StreamingMockLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return m.answer, nil
+}
+
+func TestReactAgentStreamsTokens(t *testing.T) {
+	agent, err := CreateReactAgentMap(&StreamingMockLLM{answer: "Beijing is sunny."}, []tools.Tool{NewWeatherTool(25)}, 5)
+	assert.NoError(t, err)
+
+	// Run the agent as a node of a streamed graph
+	g := graph.NewStreamingStateGraphWithConfig[map[string]any](graph.StreamConfig{
+		BufferSize: 100,
+		Mode:       graph.StreamModeMessages,
+	})
+	g.AddNode("assistant", "assistant", func(ctx context.Context, state map[string]any) (map[string]any, error) {
+		return agent.Invoke(ctx, state)
+	})
+	g.SetEntryPoint("assistant")
+	g.AddEdge("assistant", graph.END)
+
+	runnable, err := g.CompileStreaming()
+	assert.NoError(t, err)
+
+	res := runnable.Stream(context.Background(), map[string]any{
+		"messages": []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "Weather in Beijing?")},
+	})
+
+	var text strings.Builder
+	for event := range res.Events {
+		assert.Equal(t, graph.EventToken, event.Event)
+		assert.Equal(t, "agent", event.NodeName)
+		assert.NotEmpty(t, event.RunID)
+		text.WriteString(event.Data.(string))
+	}
+	assert.Equal(t, "Beijing is sunny.", text.String())
+}
@@ -2,9 +2,14 @@ package store
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrListingNotSupported is returned by store wrappers implementing
+// CheckpointLister when the store they wrap does not
+var ErrListingNotSupported = errors.New("store does not support listing all checkpoints")
+
 // Checkpoint represents a saved state at a specific point in execution
 type Checkpoint struct {
 	ID        string         `json:"id"`
@@ -40,3 +45,11 @@ type CheckpointStore interface {
 	// Clear removes all checkpoints for an execution
 	Clear(ctx context.Context, executionID string) error
 }
+
+// CheckpointLister is implemented by checkpoint stores that can list every
+// checkpoint they hold, whether it belongs to a thread, a subgraph thread or
+// only an execution.
+type CheckpointLister interface {
+	// ListCheckpointIDs returns the IDs of all checkpoints of the store
+	ListCheckpointIDs(ctx context.Context) ([]string, error)
+}
@@ -0,0 +1,421 @@
+package codec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/smallnest/langgraphgo/store"
+)
+
+// ErrCheckpointTooLarge is returned by Save, wrapped in a *SizeError, when the
+// encoded state of a checkpoint exceeds the maximum size of the store.
+var ErrCheckpointTooLarge = errors.New("checkpoint too large")
+
+// SizeError reports a checkpoint whose encoded state exceeds the maximum size
+type SizeError struct {
+	CheckpointID string
+	Size         int
+	MaxSize      int
+}
+
+func (e *SizeError) Error() string {
+	return fmt.Sprintf("checkpoint %s too large: state is %d bytes after encoding, the limit is %d bytes",
+		e.CheckpointID, e.Size, e.MaxSize)
+}
+
+func (e *SizeError) Unwrap() error {
+	return ErrCheckpointTooLarge
+}
+
+// Compression is the algorithm compressing checkpoint states
+type Compression string
+
+const (
+	// NoCompression stores states uncompressed
+	NoCompression Compression = "none"
+	// Gzip compresses states with gzip
+	Gzip Compression = "gzip"
+	// Zstd compresses states with Zstandard
+	Zstd Compression = "zstd"
+)
+
+// Keys of the map that replaces the state of the checkpoints saved in the
+// wrapped store
+const (
+	sealedKey      = "_sealed"
+	compressionKey = "_compression"
+	keyIDKey       = "_key_id"
+)
+
+// CodecCheckpointStore is a CheckpointStore that compresses, and optionally
+// encrypts, the states of the checkpoints it saves in another store.
+//
+// The state is replaced by a map holding the encoded state as base64, which all
+// stores persist as is, and restored when checkpoints are loaded. Checkpoints
+// saved before the wrapper was used are returned unchanged. Metadata is kept
+// in clear, as stores index it.
+//
+// The store implements store.ThreadStore by forwarding to the wrapped store,
+// and returns store.ErrThreadsNotSupported if the wrapped store does not.
+type CodecCheckpointStore struct {
+	store       store.CheckpointStore
+	serializer  store.Serializer
+	compression Compression
+	keys        KeyProvider
+	maxSize     int
+
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+}
+
+var (
+	_ store.ThreadStore      = (*CodecCheckpointStore)(nil)
+	_ store.CheckpointLister = (*CodecCheckpointStore)(nil)
+)
+
+// Option configures a CodecCheckpointStore
+type Option func(*CodecCheckpointStore)
+
+// WithCompression sets the compression of states, Zstd by default. States are
+// decompressed with the algorithm they were saved with.
+func WithCompression(compression Compression) Option {
+	return func(s *CodecCheckpointStore) {
+		s.compression = compression
+	}
+}
+
+// WithEncryption encrypts states with AES-GCM, using the current key of keys.
+// States are decrypted with the key they were encrypted with.
+func WithEncryption(keys KeyProvider) Option {
+	return func(s *CodecCheckpointStore) {
+		s.keys = keys
+	}
+}
+
+// WithMaxSize limits the size of the encoded states, after compression,
+// encryption and base64 encoding. Save returns a *SizeError for larger states.
+func WithMaxSize(bytes int) Option {
+	return func(s *CodecCheckpointStore) {
+		s.maxSize = bytes
+	}
+}
+
+// WithSerializer sets the serializer that encodes states before compression,
+// store.DefaultSerializer by default.
+func WithSerializer(serializer store.Serializer) Option {
+	return func(s *CodecCheckpointStore) {
+		s.serializer = serializer
+	}
+}
+
+// NewCodecCheckpointStore wraps a checkpoint store
+func NewCodecCheckpointStore(inner store.CheckpointStore, opts ...Option) (*CodecCheckpointStore, error) {
+	s := &CodecCheckpointStore{
+		store:       inner,
+		serializer:  store.DefaultSerializer(),
+		compression: Zstd,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	switch s.compression {
+	case NoCompression, Gzip, Zstd:
+	default:
+		return nil, fmt.Errorf("unknown compression: %s", s.compression)
+	}
+
+	var err error
+	if s.zstdEncoder, err = zstd.NewWriter(nil); err != nil {
+		return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+	}
+	if s.zstdDecoder, err = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1)); err != nil {
+		return nil, fmt.Errorf("failed to create zstd decoder: %w", err)
+	}
+
+	return s, nil
+}
+
+// Save encodes the state of a checkpoint and saves it in the wrapped store
+func (s *CodecCheckpointStore) Save(ctx context.Context, checkpoint *store.Checkpoint) error {
+	sealed, err := s.seal(ctx, checkpoint)
+	if err != nil {
+		return err
+	}
+	return s.store.Save(ctx, sealed)
+}
+
+// Load retrieves a checkpoint by ID and decodes its state
+func (s *CodecCheckpointStore) Load(ctx context.Context, checkpointID string) (*store.Checkpoint, error) {
+	checkpoint, err := s.store.Load(ctx, checkpointID)
+	if err != nil {
+		return nil, err
+	}
+	return s.open(ctx, checkpoint)
+}
+
+// List returns all checkpoints for a given execution
+func (s *CodecCheckpointStore) List(ctx context.Context, executionID string) ([]*store.Checkpoint, error) {
+	checkpoints, err := s.store.List(ctx, executionID)
+	if err != nil {
+		return nil, err
+	}
+	return s.openAll(ctx, checkpoints)
+}
+
+// ListCheckpointIDs returns the IDs of all checkpoints of the wrapped store
+func (s *CodecCheckpointStore) ListCheckpointIDs(ctx context.Context) ([]string, error) {
+	lister, ok := s.store.(store.CheckpointLister)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", store.ErrListingNotSupported, s.store)
+	}
+	return lister.ListCheckpointIDs(ctx)
+}
+
+// ListByThread returns all checkpoints for a specific thread_id
+func (s *CodecCheckpointStore) ListByThread(ctx context.Context, threadID string) ([]*store.Checkpoint, error) {
+	checkpoints, err := s.store.ListByThread(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+	return s.openAll(ctx, checkpoints)
+}
+
+// GetLatestByThread returns the latest checkpoint for a thread_id
+func (s *CodecCheckpointStore) GetLatestByThread(ctx context.Context, threadID string) (*store.Checkpoint, error) {
+	checkpoint, err := s.store.GetLatestByThread(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+	return s.open(ctx, checkpoint)
+}
+
+// Delete removes a checkpoint
+func (s *CodecCheckpointStore) Delete(ctx context.Context, checkpointID string) error {
+	return s.store.Delete(ctx, checkpointID)
+}
+
+// Clear removes all checkpoints for an execution
+func (s *CodecCheckpointStore) Clear(ctx context.Context, executionID string) error {
+	return s.store.Clear(ctx, executionID)
+}
+
+// ListThreads lists the threads of the wrapped store, see store.ThreadStore
+func (s *CodecCheckpointStore) ListThreads(ctx context.Context, query store.ThreadQuery) ([]*store.Thread, error) {
+	threads, err := s.threads()
+	if err != nil {
+		return nil, err
+	}
+	return threads.ListThreads(ctx, query)
+}
+
+// GetThread returns a thread of the wrapped store
+func (s *CodecCheckpointStore) GetThread(ctx context.Context, threadID string) (*store.Thread, error) {
+	threads, err := s.threads()
+	if err != nil {
+		return nil, err
+	}
+	return threads.GetThread(ctx, threadID)
+}
+
+// UpdateThread merges metadata into a thread of the wrapped store. Thread
+// metadata is kept in clear, like checkpoint metadata.
+func (s *CodecCheckpointStore) UpdateThread(ctx context.Context, threadID string, metadata map[string]any) (*store.Thread, error) {
+	threads, err := s.threads()
+	if err != nil {
+		return nil, err
+	}
+	return threads.UpdateThread(ctx, threadID, metadata)
+}
+
+// DeleteThread removes a thread of the wrapped store with its checkpoints
+func (s *CodecCheckpointStore) DeleteThread(ctx context.Context, threadID string) error {
+	threads, err := s.threads()
+	if err != nil {
+		return err
+	}
+	return threads.DeleteThread(ctx, threadID)
+}
+
+// threads returns the wrapped store as a ThreadStore
+func (s *CodecCheckpointStore) threads() (store.ThreadStore, error) {
+	threads, ok := s.store.(store.ThreadStore)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", store.ErrThreadsNotSupported, s.store)
+	}
+	return threads, nil
+}
+
+// seal returns a copy of checkpoint with its state encoded
+func (s *CodecCheckpointStore) seal(ctx context.Context, checkpoint *store.Checkpoint) (*store.Checkpoint, error) {
+	data, err := s.serializer.Marshal(checkpoint.State)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	data, err = s.compress(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress state: %w", err)
+	}
+
+	sealed := map[string]any{
+		compressionKey: string(s.compression),
+	}
+	if s.keys != nil {
+		keyID, key, err := s.keys.CurrentKey(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get encryption key: %w", err)
+		}
+		if data, err = encrypt(key, data, []byte(checkpoint.ID)); err != nil {
+			return nil, fmt.Errorf("failed to encrypt state: %w", err)
+		}
+		sealed[keyIDKey] = keyID
+	}
+
+	// The size limit applies to what the wrapped store writes: the base64 text
+	if size := base64.StdEncoding.EncodedLen(len(data)); s.maxSize > 0 && size > s.maxSize {
+		return nil, &SizeError{CheckpointID: checkpoint.ID, Size: size, MaxSize: s.maxSize}
+	}
+	sealed[sealedKey] = base64.StdEncoding.EncodeToString(data)
+
+	copied := *checkpoint
+	copied.State = sealed
+	return &copied, nil
+}
+
+// open returns a copy of checkpoint with its state decoded. Checkpoints that
+// were not sealed are returned as they are.
+func (s *CodecCheckpointStore) open(ctx context.Context, checkpoint *store.Checkpoint) (*store.Checkpoint, error) {
+	sealed, ok := checkpoint.State.(map[string]any)
+	if !ok {
+		return checkpoint, nil
+	}
+	encoded, ok := sealed[sealedKey].(string)
+	if !ok {
+		return checkpoint, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode state of checkpoint %s: %w", checkpoint.ID, err)
+	}
+
+	if keyID, ok := sealed[keyIDKey].(string); ok {
+		if s.keys == nil {
+			return nil, fmt.Errorf("checkpoint %s is encrypted but no key provider is configured", checkpoint.ID)
+		}
+		key, err := s.keys.Key(ctx, keyID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get encryption key %s: %w", keyID, err)
+		}
+		if data, err = decrypt(key, data, []byte(checkpoint.ID)); err != nil {
+			return nil, fmt.Errorf("failed to decrypt state of checkpoint %s: %w", checkpoint.ID, err)
+		}
+	}
+
+	compression, _ := sealed[compressionKey].(string)
+	if data, err = s.decompress(Compression(compression), data); err != nil {
+		return nil, fmt.Errorf("failed to decompress state of checkpoint %s: %w", checkpoint.ID, err)
+	}
+
+	state, err := s.serializer.Unmarshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal state of checkpoint %s: %w", checkpoint.ID, err)
+	}
+
+	copied := *checkpoint
+	copied.State = state
+	return &copied, nil
+}
+
+func (s *CodecCheckpointStore) openAll(ctx context.Context, checkpoints []*store.Checkpoint) ([]*store.Checkpoint, error) {
+	opened := make([]*store.Checkpoint, len(checkpoints))
+	for i, checkpoint := range checkpoints {
+		var err error
+		if opened[i], err = s.open(ctx, checkpoint); err != nil {
+			return nil, err
+		}
+	}
+	return opened, nil
+}
+
+func (s *CodecCheckpointStore) compress(data []byte) ([]byte, error) {
+	switch s.compression {
+	case Zstd:
+		return s.zstdEncoder.EncodeAll(data, nil), nil
+	case Gzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return data, nil
+	}
+}
+
+func (s *CodecCheckpointStore) decompress(compression Compression, data []byte) ([]byte, error) {
+	switch compression {
+	case Zstd:
+		return s.zstdDecoder.DecodeAll(data, nil)
+	case Gzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	case NoCompression:
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unknown compression: %s", compression)
+	}
+}
+
+// encrypt seals data with AES-GCM, prefixing it with the nonce. The checkpoint
+// ID is authenticated with it, so that a state cannot be moved to another
+// checkpoint.
+func encrypt(key, data, checkpointID []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, checkpointID), nil
+}
+
+func decrypt(key, data, checkpointID []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, checkpointID)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,235 @@
+package codec
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/smallnest/langgraphgo/store"
+	"github.com/smallnest/langgraphgo/store/file"
+	"github.com/smallnest/langgraphgo/store/memory"
+	"github.com/smallnest/langgraphgo/store/redis"
+	"github.com/smallnest/langgraphgo/store/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type chatState struct {
+	Messages []string `json:"messages"`
+}
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func newCheckpoint(id string, state any) *store.Checkpoint {
+	return &store.Checkpoint{
+		ID:        id,
+		NodeName:  "chat",
+		State:     state,
+		Timestamp: time.Now(),
+		Version:   1,
+		Metadata:  map[string]any{"thread_id": "thread-1"},
+	}
+}
+
+func TestCodecCheckpointStore_Backends(t *testing.T) {
+	registry := store.NewTypeRegistry()
+	require.NoError(t, registry.RegisterTypeInternal(reflect.TypeOf(chatState{}), "chatState"))
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	sqliteStore, err := sqlite.NewSqliteCheckpointStore(sqlite.SqliteOptions{Path: ":memory:"})
+	require.NoError(t, err)
+	defer sqliteStore.Close()
+
+	fileStore, err := file.NewFileCheckpointStore(t.TempDir())
+	require.NoError(t, err)
+
+	backends := map[string]store.CheckpointStore{
+		"memory": memory.NewMemoryCheckpointStore(),
+		"file":   fileStore,
+		"sqlite": sqliteStore,
+		"redis":  redis.NewRedisCheckpointStore(redis.RedisOptions{Addr: mr.Addr()}),
+	}
+	for name, inner := range backends {
+		for _, compression := range []Compression{Zstd, Gzip, NoCompression} {
+			t.Run(name+"/"+string(compression), func(t *testing.T) {
+				s, err := NewCodecCheckpointStore(inner,
+					WithCompression(compression),
+					WithEncryption(NewStaticKey("k1", testKey)),
+					WithSerializer(store.NewJSONSerializer(registry)),
+				)
+				require.NoError(t, err)
+
+				ctx := context.Background()
+				id := "cp-" + string(compression)
+				state := chatState{Messages: []string{"secret question", "secret answer"}}
+				require.NoError(t, s.Save(ctx, newCheckpoint(id, state)))
+
+				// The wrapped store only sees the encrypted state
+				raw, err := inner.Load(ctx, id)
+				require.NoError(t, err)
+				sealed, ok := raw.State.(map[string]any)
+				require.True(t, ok, "state saved as %T", raw.State)
+				assert.Equal(t, "k1", sealed["_key_id"])
+				assert.NotContains(t, sealed["_sealed"], "secret")
+
+				loaded, err := s.Load(ctx, id)
+				require.NoError(t, err)
+				assert.Equal(t, state, loaded.State)
+				assert.Equal(t, "thread-1", loaded.Metadata["thread_id"])
+
+				latest, err := s.GetLatestByThread(ctx, "thread-1")
+				require.NoError(t, err)
+				assert.IsType(t, chatState{}, latest.State)
+
+				require.NoError(t, s.Delete(ctx, id))
+			})
+		}
+	}
+}
+
+func TestCodecCheckpointStore_Compresses(t *testing.T) {
+	inner := memory.NewMemoryCheckpointStore()
+	s, err := NewCodecCheckpointStore(inner)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	history := strings.Repeat("the same long message history ", 10000)
+	require.NoError(t, s.Save(ctx, newCheckpoint("cp-1", map[string]any{"history": history})))
+
+	raw, err := inner.Load(ctx, "cp-1")
+	require.NoError(t, err)
+	sealed := raw.State.(map[string]any)
+	assert.Equal(t, "zstd", sealed["_compression"])
+	assert.Less(t, len(sealed["_sealed"].(string)), len(history)/10)
+
+	loaded, err := s.Load(ctx, "cp-1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"history": history}, loaded.State)
+}
+
+func TestCodecCheckpointStore_MaxSize(t *testing.T) {
+	inner := memory.NewMemoryCheckpointStore()
+	s, err := NewCodecCheckpointStore(inner, WithCompression(NoCompression), WithMaxSize(64))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, s.Save(ctx, newCheckpoint("small", "hello")))
+
+	err = s.Save(ctx, newCheckpoint("large", strings.Repeat("x", 100)))
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrCheckpointTooLarge))
+	var sizeErr *SizeError
+	require.True(t, errors.As(err, &sizeErr))
+	assert.Equal(t, "large", sizeErr.CheckpointID)
+	assert.Equal(t, 64, sizeErr.MaxSize)
+	assert.Greater(t, sizeErr.Size, 100)
+
+	// Nothing is saved
+	_, err = inner.Load(ctx, "large")
+	assert.Error(t, err)
+
+	// The limit applies to the base64 text saved: 48 bytes encode to 64
+	err = s.Save(ctx, newCheckpoint("fits", strings.Repeat("x", 44)))
+	require.NoError(t, err)
+	saved, err := inner.Load(ctx, "fits")
+	require.NoError(t, err)
+	assert.Len(t, saved.State.(map[string]any)[sealedKey], 64)
+
+	err = s.Save(ctx, newCheckpoint("encoded_too_large", strings.Repeat("x", 48)))
+	require.True(t, errors.As(err, &sizeErr))
+	assert.Equal(t, 68, sizeErr.Size)
+}
+
+func TestCodecCheckpointStore_KeyRotation(t *testing.T) {
+	inner := memory.NewMemoryCheckpointStore()
+	keys := NewStaticKey("k1", testKey)
+	s, err := NewCodecCheckpointStore(inner, WithEncryption(keys))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, s.Save(ctx, newCheckpoint("old", "before rotation")))
+
+	keys.Keys["k2"] = []byte("fedcba9876543210")
+	keys.Current = "k2"
+	require.NoError(t, s.Save(ctx, newCheckpoint("new", "after rotation")))
+
+	checkpoints, err := s.ListByThread(ctx, "thread-1")
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 2)
+	assert.Equal(t, "before rotation", checkpoints[0].State)
+	assert.Equal(t, "after rotation", checkpoints[1].State)
+
+	// A retired key cannot be found
+	delete(keys.Keys, "k1")
+	_, err = s.Load(ctx, "old")
+	assert.ErrorContains(t, err, "unknown key: k1")
+
+	// States are bound to their checkpoint
+	raw, err := inner.Load(ctx, "new")
+	require.NoError(t, err)
+	moved := *raw
+	moved.ID = "moved"
+	require.NoError(t, inner.Save(ctx, &moved))
+	_, err = s.Load(ctx, "moved")
+	assert.ErrorContains(t, err, "failed to decrypt")
+}
+
+func TestCodecCheckpointStore_Passthrough(t *testing.T) {
+	inner := memory.NewMemoryCheckpointStore()
+	ctx := context.Background()
+
+	// Checkpoints saved before the wrapper are returned as they are
+	require.NoError(t, inner.Save(ctx, newCheckpoint("legacy", map[string]any{"step": "one"})))
+
+	s, err := NewCodecCheckpointStore(inner)
+	require.NoError(t, err)
+	loaded, err := s.Load(ctx, "legacy")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"step": "one"}, loaded.State)
+
+	// Encrypted states need a key provider
+	encrypting, err := NewCodecCheckpointStore(inner, WithEncryption(NewStaticKey("k1", testKey)))
+	require.NoError(t, err)
+	require.NoError(t, encrypting.Save(ctx, newCheckpoint("encrypted", "secret")))
+	_, err = s.Load(ctx, "encrypted")
+	assert.ErrorContains(t, err, "no key provider")
+
+	_, err = NewCodecCheckpointStore(inner, WithCompression("lz4"))
+	assert.ErrorContains(t, err, "unknown compression")
+}
+
+func TestCodecCheckpointStore_Threads(t *testing.T) {
+	s, err := NewCodecCheckpointStore(memory.NewMemoryCheckpointStore(), WithEncryption(NewStaticKey("k1", testKey)))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, s.Save(ctx, newCheckpoint("cp-1", "secret")))
+	_, err = s.UpdateThread(ctx, "thread-1", map[string]any{"user_id": "alice"})
+	require.NoError(t, err)
+
+	// The wrapper is a ThreadStore, with metadata passed through as is
+	var threads store.ThreadStore = s
+	listed, err := threads.ListThreads(ctx, store.ThreadQuery{Metadata: map[string]any{"user_id": "alice"}})
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, "thread-1", listed[0].ID)
+
+	require.NoError(t, threads.DeleteThread(ctx, "thread-1"))
+	_, err = s.Load(ctx, "cp-1")
+	assert.Error(t, err)
+	_, err = threads.GetThread(ctx, "thread-1")
+	assert.ErrorIs(t, err, store.ErrThreadNotFound)
+
+	// Stores without threads are reported
+	plain, err := NewCodecCheckpointStore(struct{ store.CheckpointStore }{memory.NewMemoryCheckpointStore()})
+	require.NoError(t, err)
+	_, err = plain.ListThreads(ctx, store.ThreadQuery{})
+	assert.ErrorIs(t, err, store.ErrThreadsNotSupported)
+}
@@ -0,0 +1,41 @@
+// Package codec provides a checkpoint store wrapper that compresses and
+// encrypts checkpoint states at rest.
+//
+// A CodecCheckpointStore wraps any store.CheckpointStore (memory, file,
+// SQLite, Redis, PostgreSQL...). It encodes the state of each checkpoint with a
+// store.Serializer, compresses it with zstd or gzip, optionally encrypts it with
+// AES-GCM and enforces a maximum size, then saves the checkpoint in the
+// wrapped store with the encoded state. Loaded checkpoints get their state back.
+//
+// # Usage
+//
+//	inner, err := postgres.NewPostgresCheckpointStore(ctx, postgres.PostgresOptions{
+//		ConnString: connString,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//
+//	checkpointStore, err := codec.NewCodecCheckpointStore(inner,
+//		codec.WithCompression(codec.Zstd),
+//		codec.WithEncryption(codec.NewStaticKey("2024-06", key)),
+//		codec.WithMaxSize(4<<20),
+//	)
+//
+//	g := graph.NewCheckpointableStateGraphWithConfig[MyState](graph.CheckpointConfig{
+//		Store:    checkpointStore,
+//		AutoSave: true,
+//	})
+//
+// Save returns an error wrapping ErrCheckpointTooLarge when the encoded state is
+// larger than the maximum size:
+//
+//	var sizeErr *codec.SizeError
+//	if errors.As(err, &sizeErr) {
+//		log.Printf("state of %s is %d bytes", sizeErr.CheckpointID, sizeErr.Size)
+//	}
+//
+// Implement KeyProvider to get keys from a key management service. States are
+// decrypted with the key whose ID was saved with them, so that the current key
+// can be rotated.
+package codec
@@ -0,0 +1,51 @@
+package codec
+
+import (
+	"context"
+	"fmt"
+)
+
+// KeyProvider provides the AES keys (16, 24 or 32 bytes) that encrypt
+// checkpoint states. Keys are identified by an ID saved with each state, so
+// that keys can be rotated while older checkpoints remain readable.
+type KeyProvider interface {
+	// CurrentKey returns the key that encrypts new states, and its ID
+	CurrentKey(ctx context.Context) (keyID string, key []byte, err error)
+
+	// Key returns the key with the given ID, to decrypt states
+	Key(ctx context.Context, keyID string) ([]byte, error)
+}
+
+// StaticKeys is a KeyProvider holding keys in memory
+type StaticKeys struct {
+	// Current is the ID of the key that encrypts new states
+	Current string
+	// Keys maps key IDs to keys
+	Keys map[string][]byte
+}
+
+// NewStaticKey creates a KeyProvider with a single key
+func NewStaticKey(keyID string, key []byte) *StaticKeys {
+	return &StaticKeys{
+		Current: keyID,
+		Keys:    map[string][]byte{keyID: key},
+	}
+}
+
+// CurrentKey implements KeyProvider
+func (k *StaticKeys) CurrentKey(ctx context.Context) (string, []byte, error) {
+	key, err := k.Key(ctx, k.Current)
+	if err != nil {
+		return "", nil, err
+	}
+	return k.Current, key, nil
+}
+
+// Key implements KeyProvider
+func (k *StaticKeys) Key(_ context.Context, keyID string) ([]byte, error) {
+	key, ok := k.Keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key: %s", keyID)
+	}
+	return key, nil
+}
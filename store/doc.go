@@ -199,10 +199,26 @@
 //
 // ## Serialization
 //
-// All stores use JSON serialization for checkpoint data. For optimal performance:
+// The file, SQLite, PostgreSQL and Redis stores encode checkpoint states with a
+// Serializer (the memory store keeps the values themselves). The default is
+// typed JSON: states of types registered with the global TypeRegistry are
+// loaded as that type again, so a CheckpointableRunnable[MyState] resumes with
+// a MyState instead of a map[string]any:
+//
+//	store.RegisterTypeWithValue(MyState{}, "MyState")
+//
+// NewGobSerializer and NewMsgpackSerializer use the registry the same way with
+// binary encodings, which the stores keep base64-encoded in their JSON columns:
+//
+//	s, err := sqlite.NewSqliteCheckpointStore(sqlite.SqliteOptions{
+//	    Path:       "./checkpoints.db",
+//	    Serializer: store.NewMsgpackSerializer(nil),
+//	})
+//
+// For optimal performance:
 //   - Keep state objects relatively small
 //   - Avoid storing large binary data in checkpoints
-//   - Consider compression for large state objects
+//   - Consider compression for large state objects (see store/codec)
 //
 // ## Batch Operations
 //
@@ -275,19 +291,58 @@
 //
 //	versions, err := versionedStore.ListVersions(ctx, checkpointID)
 //
-// ## Checkpoint Compression
+// ## State Schema Migrations
+//
+// Checkpoints saved by graphs record the registered type of their state and its
+// schema version in their metadata ("state_type" and "schema_version"). When a
+// state struct changes, register migrations of its JSON form with the type
+// registry; versions start at 1 and each migration moves one version up:
+//
+//	store.RegisterTypeWithValue(MyState{}, "MyState")
+//	store.RegisterMigration("MyState", 1, func(state map[string]any) (map[string]any, error) {
+//	    state["question"] = state["query"]
+//	    delete(state, "query")
+//	    return state, nil
+//	})
 //
-// For large state objects, consider compression:
+// States saved with an older version are migrated when they are loaded, by the
+// serializers of the stores and by CheckpointableRunnable when it resumes a
+// thread or returns its state. MigrateThreadCheckpoints rewrites the checkpoints
+// of threads, with those of their subgraphs, with the current version, and every
+// checkpoint of the store when no thread is given:
 //
-//	compressedStore := store.NewCompressedWrapper(store, gzip.BestCompression)
-//	err := compressedStore.Put(ctx, checkpoint)
+//	migrated, err := store.MigrateThreadCheckpoints(ctx, checkpointStore, "thread-1", "thread-2")
 //
-// ## Checkpoint Encryption
+// ## Checkpoint Compression and Encryption
 //
-// Encrypt sensitive checkpoint data:
+// The store/codec package wraps any store to compress states with zstd or gzip,
+// encrypt them with AES-GCM and limit their size:
+//
+//	codecStore, err := codec.NewCodecCheckpointStore(pgStore,
+//	    codec.WithCompression(codec.Zstd),
+//	    codec.WithEncryption(codec.NewStaticKey("key-1", encryptionKey)),
+//	    codec.WithMaxSize(4<<20),
+//	)
+//
+// ## Threads
+//
+// The memory, file, SQLite, Redis and PostgreSQL stores implement ThreadStore:
+// they record the thread of each checkpoint they save, to list the threads,
+// search them by metadata and delete them. Checkpoints of subgraphs belong to
+// the thread of their graph. The codec wrapper forwards ThreadStore to the store
+// it wraps.
+//
+//	threads := checkpointStore.(store.ThreadStore)
+//	threads.UpdateThread(ctx, "thread-1", map[string]any{"user_id": "alice", "title": "Trip to Rome"})
+//
+//	// The 20 most recently updated threads of alice
+//	sessions, err := threads.ListThreads(ctx, store.ThreadQuery{
+//	    Metadata: map[string]any{"user_id": "alice"},
+//	    Limit:    20,
+//	})
 //
-//	encryptedStore := store.NewEncryptedWrapper(store, encryptionKey)
-//	err := encryptedStore.Put(ctx, checkpoint)
+//	// Delete the threads idle for 30 days, with their checkpoints
+//	deleted, err := store.DeleteExpiredThreads(ctx, threads, 30*24*time.Hour)
 //
 // # Extending the Package
 //
@@ -3,19 +3,55 @@ package file
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/smallnest/langgraphgo/store"
 )
 
+var (
+	_ store.ThreadStore      = (*FileCheckpointStore)(nil)
+	_ store.CheckpointLister = (*FileCheckpointStore)(nil)
+)
+
 // FileCheckpointStore provides file-based checkpoint storage
 type FileCheckpointStore struct {
-	path  string
-	mutex sync.RWMutex
+	path       string
+	serializer store.Serializer
+	mutex      sync.RWMutex
+}
+
+// Option configures a FileCheckpointStore
+type Option func(*FileCheckpointStore)
+
+// WithSerializer sets the serializer of checkpoint states. The default is
+// store.DefaultSerializer.
+func WithSerializer(serializer store.Serializer) Option {
+	return func(f *FileCheckpointStore) {
+		f.serializer = serializer
+	}
+}
+
+// fileCheckpoint is the content of a checkpoint file, with the state encoded
+// by the serializer of the store
+type fileCheckpoint struct {
+	store.Checkpoint
+	State json.RawMessage `json:"state"`
+}
+
+// fileThread is the content of a thread file, with the subgraph threads whose
+// checkpoints belong to the thread
+type fileThread struct {
+	store.Thread
+	Subthreads []string `json:"subthreads,omitempty"`
 }
 
 // threadIndex represents the in-memory index for thread_id -> checkpoint IDs
@@ -24,7 +60,7 @@ type threadIndex struct {
 }
 
 // NewFileCheckpointStore creates a new file-based checkpoint store
-func NewFileCheckpointStore(path string) (store.CheckpointStore, error) {
+func NewFileCheckpointStore(path string, opts ...Option) (store.CheckpointStore, error) {
 	// Ensure directory exists
 	if err := os.MkdirAll(path, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create checkpoint directory: %w", err)
@@ -36,9 +72,27 @@ func NewFileCheckpointStore(path string) (store.CheckpointStore, error) {
 		return nil, fmt.Errorf("failed to create index directory: %w", err)
 	}
 
-	return &FileCheckpointStore{
-		path: path,
-	}, nil
+	f := &FileCheckpointStore{
+		path:       path,
+		serializer: store.DefaultSerializer(),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	// Ensure threads directory exists, recording the threads of checkpoints
+	// saved before threads were
+	threadsDir := filepath.Join(path, "threads")
+	if _, err := os.Stat(threadsDir); os.IsNotExist(err) {
+		if err := os.MkdirAll(threadsDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create threads directory: %w", err)
+		}
+		if err := f.indexThreads(); err != nil {
+			return nil, fmt.Errorf("failed to index threads: %w", err)
+		}
+	}
+
+	return f, nil
 }
 
 // Save implements CheckpointStore interface for file storage
@@ -49,7 +103,12 @@ func (f *FileCheckpointStore) Save(_ context.Context, checkpoint *store.Checkpoi
 	// Create filename from ID
 	filename := filepath.Join(f.path, fmt.Sprintf("%s.json", checkpoint.ID))
 
-	data, err := json.Marshal(checkpoint)
+	state, err := store.MarshalState(f.serializer, checkpoint.State)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	data, err := json.Marshal(fileCheckpoint{Checkpoint: *checkpoint, State: state})
 	if err != nil {
 		return fmt.Errorf("failed to marshal checkpoint: %w", err)
 	}
@@ -66,6 +125,11 @@ func (f *FileCheckpointStore) Save(_ context.Context, checkpoint *store.Checkpoi
 		}
 	}
 
+	// Record the checkpoint in its thread
+	if err := f.touchThread(checkpoint); err != nil {
+		return fmt.Errorf("failed to update thread: %w", err)
+	}
+
 	return nil
 }
 
@@ -84,13 +148,7 @@ func (f *FileCheckpointStore) Load(_ context.Context, checkpointID string) (*sto
 		return nil, fmt.Errorf("failed to read checkpoint file: %w", err)
 	}
 
-	var checkpoint store.Checkpoint
-	err = json.Unmarshal(data, &checkpoint)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal checkpoint: %w", err)
-	}
-
-	return &checkpoint, nil
+	return f.decodeCheckpoint(data)
 }
 
 // List implements CheckpointStore interface for file storage
@@ -117,8 +175,8 @@ func (f *FileCheckpointStore) List(_ context.Context, executionID string) ([]*st
 			continue
 		}
 
-		var checkpoint store.Checkpoint
-		if err := json.Unmarshal(data, &checkpoint); err != nil {
+		checkpoint, err := f.decodeCheckpoint(data)
+		if err != nil {
 			// Skip invalid files
 			continue
 		}
@@ -130,7 +188,7 @@ func (f *FileCheckpointStore) List(_ context.Context, executionID string) ([]*st
 		workflowID, _ := checkpoint.Metadata["workflow_id"].(string)
 
 		if execID == executionID || threadID == executionID || sessionID == executionID || workflowID == executionID {
-			checkpoints = append(checkpoints, &checkpoint)
+			checkpoints = append(checkpoints, checkpoint)
 		}
 	}
 
@@ -142,6 +200,26 @@ func (f *FileCheckpointStore) List(_ context.Context, executionID string) ([]*st
 	return checkpoints, nil
 }
 
+// ListCheckpointIDs returns the IDs of all checkpoint files
+func (f *FileCheckpointStore) ListCheckpointIDs(_ context.Context) ([]string, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	files, err := os.ReadDir(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint directory: %w", err)
+	}
+
+	var ids []string
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(file.Name(), ".json"))
+	}
+	return ids, nil
+}
+
 // ListByThread returns all checkpoints for a specific thread_id using index
 func (f *FileCheckpointStore) ListByThread(_ context.Context, threadID string) ([]*store.Checkpoint, error) {
 	f.mutex.RLock()
@@ -167,13 +245,13 @@ func (f *FileCheckpointStore) ListByThread(_ context.Context, threadID string) (
 			continue
 		}
 
-		var checkpoint store.Checkpoint
-		if err := json.Unmarshal(data, &checkpoint); err != nil {
+		checkpoint, err := f.decodeCheckpoint(data)
+		if err != nil {
 			// Skip invalid files
 			continue
 		}
 
-		checkpoints = append(checkpoints, &checkpoint)
+		checkpoints = append(checkpoints, checkpoint)
 	}
 
 	// Sort by version (ascending order)
@@ -215,7 +293,8 @@ func (f *FileCheckpointStore) Delete(_ context.Context, checkpointID string) err
 		return fmt.Errorf("failed to read checkpoint file: %w", err)
 	}
 
-	var checkpoint store.Checkpoint
+	// The state is not needed to update the index
+	var checkpoint fileCheckpoint
 	if err := json.Unmarshal(data, &checkpoint); err != nil {
 		return fmt.Errorf("failed to unmarshal checkpoint: %w", err)
 	}
@@ -257,10 +336,54 @@ func (f *FileCheckpointStore) Clear(ctx context.Context, executionID string) err
 	return nil
 }
 
+// decodeCheckpoint decodes the content of a checkpoint file
+func (f *FileCheckpointStore) decodeCheckpoint(data []byte) (*store.Checkpoint, error) {
+	var file fileCheckpoint
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal checkpoint: %w", err)
+	}
+
+	checkpoint := file.Checkpoint
+	if len(file.State) > 0 {
+		state, err := store.UnmarshalState(f.serializer, file.State)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal state: %w", err)
+		}
+		checkpoint.State = state
+	}
+
+	return &checkpoint, nil
+}
+
 // Helper functions for thread index management
 
 func (f *FileCheckpointStore) getThreadIndexPath(threadID string) string {
-	return filepath.Join(f.path, "by_thread", fmt.Sprintf("%s.json", threadID))
+	return f.threadFile("by_thread", threadID)
+}
+
+// threadFile returns the path of the file of a thread in dir. Thread IDs are
+// escaped, since subgraph thread IDs hold characters such as "|" and ":" that
+// not every file system accepts in names; a file that an earlier version saved
+// under the raw thread ID is still used.
+func (f *FileCheckpointStore) threadFile(dir, threadID string) string {
+	path := filepath.Join(f.path, dir, url.QueryEscape(threadID)+".json")
+	if legacy := filepath.Join(f.path, dir, threadID+".json"); legacy != path {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if _, err := os.Stat(legacy); err == nil {
+				return legacy
+			}
+		}
+	}
+	return path
+}
+
+// threadIDOfFile returns the thread ID of a file named by threadFile
+func threadIDOfFile(name string) string {
+	name = strings.TrimSuffix(name, ".json")
+	if id, err := url.QueryUnescape(name); err == nil {
+		return id
+	}
+	return name
 }
 
 func (f *FileCheckpointStore) loadThreadIndex(threadID string) ([]string, error) {
@@ -304,7 +427,10 @@ func (f *FileCheckpointStore) addToThreadIndex(threadID, checkpointID string) er
 		index.Threads = make(map[string][]string)
 	}
 
-	// Add checkpoint ID to index
+	// Add checkpoint ID to index, unless the checkpoint is saved again
+	if slices.Contains(index.Threads[threadID], checkpointID) {
+		return nil
+	}
 	index.Threads[threadID] = append(index.Threads[threadID], checkpointID)
 
 	// Write index back to disk
@@ -384,14 +510,14 @@ func (f *FileCheckpointStore) listByThreadScan(threadID string) ([]*store.Checkp
 			continue
 		}
 
-		var checkpoint store.Checkpoint
-		if err := json.Unmarshal(data, &checkpoint); err != nil {
+		checkpoint, err := f.decodeCheckpoint(data)
+		if err != nil {
 			continue
 		}
 
 		// Filter by thread_id
 		if cpThreadID, ok := checkpoint.Metadata["thread_id"].(string); ok && cpThreadID == threadID {
-			checkpoints = append(checkpoints, &checkpoint)
+			checkpoints = append(checkpoints, checkpoint)
 		}
 	}
 
@@ -402,3 +528,184 @@ func (f *FileCheckpointStore) listByThreadScan(threadID string) ([]*store.Checkp
 
 	return checkpoints, nil
 }
+
+// ListThreads implements store.ThreadStore
+func (f *FileCheckpointStore) ListThreads(_ context.Context, query store.ThreadQuery) ([]*store.Thread, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	files, err := os.ReadDir(filepath.Join(f.path, "threads"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read threads directory: %w", err)
+	}
+
+	var threads []*store.Thread
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		thread, err := f.loadThread(threadIDOfFile(file.Name()))
+		if err != nil {
+			// Skip unreadable files
+			continue
+		}
+		threads = append(threads, thread.Clone())
+	}
+
+	return query.Apply(threads), nil
+}
+
+// GetThread implements store.ThreadStore
+func (f *FileCheckpointStore) GetThread(_ context.Context, threadID string) (*store.Thread, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	thread, err := f.loadThread(threadID)
+	if err != nil {
+		return nil, err
+	}
+	return thread.Clone(), nil
+}
+
+// UpdateThread implements store.ThreadStore
+func (f *FileCheckpointStore) UpdateThread(_ context.Context, threadID string, metadata map[string]any) (*store.Thread, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	thread, err := f.loadThread(threadID)
+	if errors.Is(err, store.ErrThreadNotFound) {
+		thread = &fileThread{Thread: store.Thread{ID: threadID}}
+	} else if err != nil {
+		return nil, err
+	}
+
+	thread.MergeMetadata(metadata, time.Now())
+	if err := f.writeThread(thread); err != nil {
+		return nil, err
+	}
+	return thread.Clone(), nil
+}
+
+// DeleteThread implements store.ThreadStore
+func (f *FileCheckpointStore) DeleteThread(_ context.Context, threadID string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	threadIDs := []string{threadID}
+	thread, err := f.loadThread(threadID)
+	if err == nil {
+		threadIDs = append(threadIDs, thread.Subthreads...)
+	} else if !errors.Is(err, store.ErrThreadNotFound) {
+		return err
+	}
+
+	// Remove the checkpoints of the thread and its subgraphs, then their indexes
+	for _, id := range threadIDs {
+		checkpointIDs, err := f.loadThreadIndex(id)
+		if err != nil {
+			return fmt.Errorf("failed to read thread index: %w", err)
+		}
+		for _, checkpointID := range checkpointIDs {
+			filename := filepath.Join(f.path, fmt.Sprintf("%s.json", checkpointID))
+			if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to delete checkpoint file: %w", err)
+			}
+		}
+		if err := os.Remove(f.getThreadIndexPath(id)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete thread index: %w", err)
+		}
+	}
+
+	if err := os.Remove(f.getThreadPath(threadID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete thread file: %w", err)
+	}
+	return nil
+}
+
+// Helper functions for thread files
+
+func (f *FileCheckpointStore) getThreadPath(threadID string) string {
+	return f.threadFile("threads", threadID)
+}
+
+func (f *FileCheckpointStore) loadThread(threadID string) (*fileThread, error) {
+	data, err := os.ReadFile(f.getThreadPath(threadID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", store.ErrThreadNotFound, threadID)
+		}
+		return nil, fmt.Errorf("failed to read thread file: %w", err)
+	}
+
+	var thread fileThread
+	if err := json.Unmarshal(data, &thread); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal thread: %w", err)
+	}
+	return &thread, nil
+}
+
+func (f *FileCheckpointStore) writeThread(thread *fileThread) error {
+	data, err := json.Marshal(thread)
+	if err != nil {
+		return fmt.Errorf("failed to marshal thread: %w", err)
+	}
+	if err := os.WriteFile(f.getThreadPath(thread.ID), data, 0600); err != nil {
+		return fmt.Errorf("failed to write thread file: %w", err)
+	}
+	return nil
+}
+
+// touchThread records a saved checkpoint in the thread it belongs to
+func (f *FileCheckpointStore) touchThread(checkpoint *store.Checkpoint) error {
+	threadID := store.CheckpointThreadID(checkpoint)
+	if threadID == "" {
+		return nil
+	}
+
+	thread, err := f.loadThread(threadID)
+	if errors.Is(err, store.ErrThreadNotFound) {
+		thread = &fileThread{Thread: store.Thread{ID: threadID}}
+	} else if err != nil {
+		return err
+	}
+
+	thread.Touch(checkpoint.Timestamp)
+	if subthread, _ := checkpoint.Metadata["thread_id"].(string); subthread != "" && subthread != threadID &&
+		!slices.Contains(thread.Subthreads, subthread) {
+		thread.Subthreads = append(thread.Subthreads, subthread)
+	}
+	return f.writeThread(thread)
+}
+
+// indexThreads records the threads of the checkpoints in the thread indexes
+func (f *FileCheckpointStore) indexThreads() error {
+	files, err := os.ReadDir(filepath.Join(f.path, "by_thread"))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		checkpointIDs, err := f.loadThreadIndex(threadIDOfFile(file.Name()))
+		if err != nil {
+			continue
+		}
+		for _, id := range checkpointIDs {
+			data, err := os.ReadFile(filepath.Join(f.path, fmt.Sprintf("%s.json", id)))
+			if err != nil {
+				continue
+			}
+			// The state is not needed to record the thread
+			var checkpoint fileCheckpoint
+			if err := json.Unmarshal(data, &checkpoint); err != nil {
+				continue
+			}
+			if err := f.touchThread(&checkpoint.Checkpoint); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
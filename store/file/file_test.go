@@ -2,9 +2,13 @@ package file
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -582,3 +586,279 @@ func TestFileCheckpointStore_Concurrent(t *testing.T) {
 		t.Errorf("Expected %d checkpoint files, got %d", expectedTotal, jsonCount)
 	}
 }
+
+type typedState struct {
+	Query   string   `json:"query"`
+	Results []string `json:"results"`
+}
+
+func TestFileCheckpointStore_TypedState(t *testing.T) {
+	t.Parallel()
+
+	registry := store.NewTypeRegistry()
+	if err := registry.RegisterTypeInternal(reflect.TypeOf(typedState{}), "typedState"); err != nil {
+		t.Fatalf("Failed to register type: %v", err)
+	}
+
+	for name, serializer := range map[string]store.Serializer{
+		"json": store.NewJSONSerializer(registry),
+		"gob":  store.NewGobSerializer(registry),
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			fs, err := NewFileCheckpointStore(t.TempDir(), WithSerializer(serializer))
+			if err != nil {
+				t.Fatalf("Failed to create store: %v", err)
+			}
+
+			state := typedState{Query: "go", Results: []string{"a", "b"}}
+			err = fs.Save(ctx, &store.Checkpoint{
+				ID:        "cp-1",
+				State:     state,
+				Timestamp: time.Now(),
+				Version:   1,
+				Metadata:  map[string]any{"thread_id": "thread-1"},
+			})
+			if err != nil {
+				t.Fatalf("Failed to save: %v", err)
+			}
+
+			loaded, err := fs.GetLatestByThread(ctx, "thread-1")
+			if err != nil {
+				t.Fatalf("Failed to load: %v", err)
+			}
+			if !reflect.DeepEqual(loaded.State, state) {
+				t.Errorf("Expected state %#v, got %#v", state, loaded.State)
+			}
+
+			if err := fs.Delete(ctx, "cp-1"); err != nil {
+				t.Fatalf("Failed to delete: %v", err)
+			}
+		})
+	}
+}
+
+func TestFileCheckpointStore_Threads(t *testing.T) {
+	t.Parallel()
+
+	fs, err := NewFileCheckpointStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	threads := fs.(store.ThreadStore)
+	ctx := context.Background()
+	now := time.Now()
+
+	save := func(id, threadID string, age time.Duration, extra map[string]any) {
+		metadata := map[string]any{"thread_id": threadID}
+		for k, v := range extra {
+			metadata[k] = v
+		}
+		cp := &store.Checkpoint{ID: id, State: "state", Timestamp: now.Add(-age), Metadata: metadata}
+		if err := fs.Save(ctx, cp); err != nil {
+			t.Fatalf("Failed to save %s: %v", id, err)
+		}
+	}
+	save("cp-1", "alice-1", 3*time.Hour, nil)
+	save("cp-2", "bob-1", 2*time.Hour, nil)
+	save("cp-3", "carol-1", 48*time.Hour, nil)
+	save("cp-4", "alice-1|agent", time.Hour, map[string]any{"parent_thread_id": "alice-1"})
+
+	ids := func(query store.ThreadQuery) []string {
+		list, err := threads.ListThreads(ctx, query)
+		if err != nil {
+			t.Fatalf("Failed to list threads: %v", err)
+		}
+		var ids []string
+		for _, thread := range list {
+			ids = append(ids, thread.ID)
+		}
+		return ids
+	}
+
+	if got := ids(store.ThreadQuery{}); !slices.Equal(got, []string{"alice-1", "bob-1", "carol-1"}) {
+		t.Errorf("Expected threads by last update, got %v", got)
+	}
+	if got := ids(store.ThreadQuery{Limit: 2, Offset: 1}); !slices.Equal(got, []string{"bob-1", "carol-1"}) {
+		t.Errorf("Expected the second page, got %v", got)
+	}
+
+	thread, err := threads.UpdateThread(ctx, "bob-1", map[string]any{"user_id": "bob", "turns": 2})
+	if err != nil {
+		t.Fatalf("Failed to update thread: %v", err)
+	}
+	if !thread.CreatedAt.Equal(now.Add(-2 * time.Hour)) {
+		t.Errorf("Expected the thread to keep its creation time, got %v", thread.CreatedAt)
+	}
+	if got := ids(store.ThreadQuery{Metadata: map[string]any{"user_id": "bob", "turns": 2}}); !slices.Equal(got, []string{"bob-1"}) {
+		t.Errorf("Expected the threads of bob, got %v", got)
+	}
+	if _, err := threads.GetThread(ctx, "unknown"); !errors.Is(err, store.ErrThreadNotFound) {
+		t.Errorf("Expected ErrThreadNotFound, got %v", err)
+	}
+
+	deleted, err := store.DeleteExpiredThreads(ctx, threads, 24*time.Hour)
+	if err != nil || deleted != 1 {
+		t.Fatalf("Expected 1 expired thread, got %d (%v)", deleted, err)
+	}
+	if err := threads.DeleteThread(ctx, "alice-1"); err != nil {
+		t.Fatalf("Failed to delete thread: %v", err)
+	}
+	for _, id := range []string{"cp-1", "cp-3", "cp-4"} {
+		if _, err := fs.Load(ctx, id); err == nil {
+			t.Errorf("Expected checkpoint %s to be deleted", id)
+		}
+	}
+	if got := ids(store.ThreadQuery{}); !slices.Equal(got, []string{"bob-1"}) {
+		t.Errorf("Expected bob-1 only, got %v", got)
+	}
+}
+
+func TestFileCheckpointStore_IndexesExistingThreads(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fs, err := NewFileCheckpointStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	ctx := context.Background()
+	created := time.Now().Add(-time.Hour)
+	for i, threadID := range []string{"thread-1", "thread-1", "thread-2"} {
+		cp := &store.Checkpoint{
+			ID:        fmt.Sprintf("cp-%d", i),
+			Timestamp: created.Add(time.Duration(i) * time.Minute),
+			Metadata:  map[string]any{"thread_id": threadID},
+		}
+		if err := fs.Save(ctx, cp); err != nil {
+			t.Fatalf("Failed to save checkpoint: %v", err)
+		}
+	}
+
+	// A store created before threads were recorded
+	if err := os.RemoveAll(filepath.Join(dir, "threads")); err != nil {
+		t.Fatalf("Failed to remove threads: %v", err)
+	}
+	fs, err = NewFileCheckpointStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+
+	thread, err := fs.(store.ThreadStore).GetThread(ctx, "thread-1")
+	if err != nil {
+		t.Fatalf("Failed to get thread: %v", err)
+	}
+	if !thread.CreatedAt.Equal(created) || !thread.UpdatedAt.Equal(created.Add(time.Minute)) {
+		t.Errorf("Unexpected thread times %v - %v", thread.CreatedAt, thread.UpdatedAt)
+	}
+	list, err := fs.(store.ThreadStore).ListThreads(ctx, store.ThreadQuery{})
+	if err != nil || len(list) != 2 {
+		t.Errorf("Expected 2 threads, got %d (%v)", len(list), err)
+	}
+}
+
+func TestFileCheckpointStore_ListCheckpointIDs(t *testing.T) {
+	t.Parallel()
+
+	fs, err := NewFileCheckpointStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	ctx := context.Background()
+	for _, cp := range []*store.Checkpoint{
+		{ID: "cp-1", Metadata: map[string]any{"thread_id": "thread-1"}},
+		{ID: "cp-2", Metadata: map[string]any{"thread_id": "thread-1|research", "parent_thread_id": "thread-1"}},
+		{ID: "cp-3", Metadata: map[string]any{"execution_id": "exec-1"}},
+	} {
+		if err := fs.Save(ctx, cp); err != nil {
+			t.Fatalf("Failed to save %s: %v", cp.ID, err)
+		}
+	}
+
+	ids, err := fs.(store.CheckpointLister).ListCheckpointIDs(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list checkpoint IDs: %v", err)
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []string{"cp-1", "cp-2", "cp-3"}) {
+		t.Errorf("Expected every checkpoint, got %v", ids)
+	}
+}
+
+func TestFileCheckpointStore_SubgraphThreadFileNames(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fs, err := NewFileCheckpointStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	ctx := context.Background()
+
+	// The thread ID of a nested subgraph holds characters Windows rejects in file names
+	subthread := "thread-1|research:search"
+	cp := &store.Checkpoint{ID: "cp-1", Metadata: map[string]any{"thread_id": subthread, "parent_thread_id": "thread-1"}}
+	if err := fs.Save(ctx, cp); err != nil {
+		t.Fatalf("Failed to save checkpoint: %v", err)
+	}
+
+	for _, sub := range []string{"by_thread", "threads"} {
+		files, err := os.ReadDir(filepath.Join(dir, sub))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", sub, err)
+		}
+		for _, file := range files {
+			if strings.ContainsAny(file.Name(), `<>:"/\|?*`) {
+				t.Errorf("File name %s in %s is not portable", file.Name(), sub)
+			}
+		}
+	}
+
+	checkpoints, err := fs.ListByThread(ctx, subthread)
+	if err != nil || len(checkpoints) != 1 {
+		t.Fatalf("Expected the checkpoint of the subgraph thread, got %d (%v)", len(checkpoints), err)
+	}
+
+	// Threads are listed by their IDs, and deleted with their subgraphs
+	threads := fs.(store.ThreadStore)
+	list, err := threads.ListThreads(ctx, store.ThreadQuery{})
+	if err != nil || len(list) != 1 || list[0].ID != "thread-1" {
+		t.Fatalf("Expected thread-1, got %v (%v)", list, err)
+	}
+	if err := threads.DeleteThread(ctx, "thread-1"); err != nil {
+		t.Fatalf("Failed to delete thread: %v", err)
+	}
+	if checkpoints, _ := fs.ListByThread(ctx, subthread); len(checkpoints) != 0 {
+		t.Errorf("Expected the subgraph checkpoints to be deleted, got %d", len(checkpoints))
+	}
+}
+
+func TestFileCheckpointStore_ReadsUnescapedThreadFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fs, err := NewFileCheckpointStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	ctx := context.Background()
+	if err := fs.Save(ctx, &store.Checkpoint{ID: "cp-1", Metadata: map[string]any{"thread_id": "user:42"}}); err != nil {
+		t.Fatalf("Failed to save checkpoint: %v", err)
+	}
+
+	// A thread index written under the raw thread ID by an earlier version
+	escaped := filepath.Join(dir, "by_thread", "user%3A42.json")
+	if err := os.Rename(escaped, filepath.Join(dir, "by_thread", "user:42.json")); err != nil {
+		t.Fatalf("Failed to rename index: %v", err)
+	}
+
+	if err := fs.Save(ctx, &store.Checkpoint{ID: "cp-2", Version: 1, Metadata: map[string]any{"thread_id": "user:42"}}); err != nil {
+		t.Fatalf("Failed to save checkpoint: %v", err)
+	}
+	checkpoints, err := fs.ListByThread(ctx, "user:42")
+	if err != nil || len(checkpoints) != 2 {
+		t.Errorf("Expected both checkpoints of the thread, got %d (%v)", len(checkpoints), err)
+	}
+}
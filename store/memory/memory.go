@@ -3,17 +3,26 @@ package memory
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/smallnest/langgraphgo/store"
 )
 
+var (
+	_ store.ThreadStore      = (*MemoryCheckpointStore)(nil)
+	_ store.CheckpointLister = (*MemoryCheckpointStore)(nil)
+)
+
 // MemoryCheckpointStore provides in-memory checkpoint storage
 type MemoryCheckpointStore struct {
 	checkpoints    map[string]*store.Checkpoint // id -> checkpoint
 	threadIndex    map[string][]string          // thread_id -> []checkpoint IDs
 	executionIndex map[string][]string          // execution_id -> []checkpoint IDs
+	threads        map[string]*store.Thread     // thread_id -> thread
 	mutex          sync.RWMutex
 }
 
@@ -23,6 +32,7 @@ func NewMemoryCheckpointStore() store.CheckpointStore {
 		checkpoints:    make(map[string]*store.Checkpoint),
 		threadIndex:    make(map[string][]string),
 		executionIndex: make(map[string][]string),
+		threads:        make(map[string]*store.Thread),
 	}
 }
 
@@ -31,8 +41,22 @@ func (m *MemoryCheckpointStore) Save(_ context.Context, checkpoint *store.Checkp
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	// Store checkpoint
+	// Record the checkpoint in its thread
+	if threadID := store.CheckpointThreadID(checkpoint); threadID != "" {
+		thread, ok := m.threads[threadID]
+		if !ok {
+			thread = &store.Thread{ID: threadID, Metadata: map[string]any{}}
+			m.threads[threadID] = thread
+		}
+		thread.Touch(checkpoint.Timestamp)
+	}
+
+	// Store checkpoint; a checkpoint saved again is already indexed
+	_, exists := m.checkpoints[checkpoint.ID]
 	m.checkpoints[checkpoint.ID] = checkpoint
+	if exists {
+		return nil
+	}
 
 	// Update execution_id index
 	if execID, ok := checkpoint.Metadata["execution_id"].(string); ok && execID != "" {
@@ -86,6 +110,13 @@ func (m *MemoryCheckpointStore) List(_ context.Context, executionID string) ([]*
 	return checkpoints, nil
 }
 
+// ListCheckpointIDs returns the IDs of all checkpoints
+func (m *MemoryCheckpointStore) ListCheckpointIDs(_ context.Context) ([]string, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return slices.Sorted(maps.Keys(m.checkpoints)), nil
+}
+
 // ListByThread returns all checkpoints for a specific thread_id
 func (m *MemoryCheckpointStore) ListByThread(_ context.Context, threadID string) ([]*store.Checkpoint, error) {
 	m.mutex.RLock()
@@ -137,35 +168,11 @@ func (m *MemoryCheckpointStore) Delete(_ context.Context, checkpointID string) e
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	checkpoint, exists := m.checkpoints[checkpointID]
-	if !exists {
+	if _, exists := m.checkpoints[checkpointID]; !exists {
 		return nil
 	}
 
-	// Remove from indexes
-	if execID, ok := checkpoint.Metadata["execution_id"].(string); ok {
-		if ids, ok := m.executionIndex[execID]; ok {
-			for i, id := range ids {
-				if id == checkpointID {
-					m.executionIndex[execID] = append(ids[:i], ids[i+1:]...)
-					break
-				}
-			}
-		}
-	}
-
-	if threadID, ok := checkpoint.Metadata["thread_id"].(string); ok {
-		if ids, ok := m.threadIndex[threadID]; ok {
-			for i, id := range ids {
-				if id == checkpointID {
-					m.threadIndex[threadID] = append(ids[:i], ids[i+1:]...)
-					break
-				}
-			}
-		}
-	}
-
-	delete(m.checkpoints, checkpointID)
+	m.remove(checkpointID)
 	return nil
 }
 
@@ -221,3 +228,72 @@ func (m *MemoryCheckpointStore) Clear(_ context.Context, executionID string) err
 
 	return nil
 }
+
+// ListThreads implements store.ThreadStore
+func (m *MemoryCheckpointStore) ListThreads(_ context.Context, query store.ThreadQuery) ([]*store.Thread, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	threads := make([]*store.Thread, 0, len(m.threads))
+	for _, thread := range m.threads {
+		threads = append(threads, thread)
+	}
+
+	selected := query.Apply(threads)
+	for i, thread := range selected {
+		selected[i] = thread.Clone()
+	}
+	return selected, nil
+}
+
+// GetThread implements store.ThreadStore
+func (m *MemoryCheckpointStore) GetThread(_ context.Context, threadID string) (*store.Thread, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	thread, ok := m.threads[threadID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", store.ErrThreadNotFound, threadID)
+	}
+	return thread.Clone(), nil
+}
+
+// UpdateThread implements store.ThreadStore
+func (m *MemoryCheckpointStore) UpdateThread(_ context.Context, threadID string, metadata map[string]any) (*store.Thread, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	thread, ok := m.threads[threadID]
+	if !ok {
+		thread = &store.Thread{ID: threadID}
+		m.threads[threadID] = thread
+	}
+	thread.MergeMetadata(metadata, time.Now())
+	return thread.Clone(), nil
+}
+
+// DeleteThread implements store.ThreadStore
+func (m *MemoryCheckpointStore) DeleteThread(_ context.Context, threadID string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for id, checkpoint := range m.checkpoints {
+		if store.CheckpointThreadID(checkpoint) == threadID {
+			m.remove(id)
+		}
+	}
+	delete(m.threads, threadID)
+	return nil
+}
+
+// remove deletes a checkpoint and its index entries. The caller holds the lock.
+func (m *MemoryCheckpointStore) remove(checkpointID string) {
+	checkpoint := m.checkpoints[checkpointID]
+	if execID, ok := checkpoint.Metadata["execution_id"].(string); ok {
+		m.executionIndex[execID] = slices.DeleteFunc(m.executionIndex[execID], func(id string) bool { return id == checkpointID })
+	}
+	if threadID, ok := checkpoint.Metadata["thread_id"].(string); ok {
+		m.threadIndex[threadID] = slices.DeleteFunc(m.threadIndex[threadID], func(id string) bool { return id == checkpointID })
+	}
+	delete(m.checkpoints, checkpointID)
+}
@@ -2,7 +2,9 @@ package memory
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"testing"
 	"time"
 
@@ -495,3 +497,127 @@ func TestMemoryCheckpointStore_ThreadSafety(t *testing.T) {
 		}
 	}
 }
+
+func TestMemoryCheckpointStore_Threads(t *testing.T) {
+	t.Parallel()
+
+	ms := NewMemoryCheckpointStore()
+	threads := ms.(store.ThreadStore)
+	ctx := context.Background()
+	now := time.Now()
+
+	save := func(id, threadID string, age time.Duration, extra map[string]any) {
+		metadata := map[string]any{"thread_id": threadID}
+		for k, v := range extra {
+			metadata[k] = v
+		}
+		cp := &store.Checkpoint{ID: id, Timestamp: now.Add(-age), Metadata: metadata}
+		if err := ms.Save(ctx, cp); err != nil {
+			t.Fatalf("Failed to save %s: %v", id, err)
+		}
+	}
+	save("cp-1", "alice-1", 3*time.Hour, nil)
+	save("cp-2", "alice-1", time.Hour, nil)
+	save("cp-3", "bob-1", 2*time.Hour, nil)
+	save("cp-4", "carol-1", 48*time.Hour, nil)
+	// Checkpoints of subgraphs belong to the thread of their graph
+	save("cp-5", "alice-1|agent", 30*time.Minute, map[string]any{"parent_thread_id": "alice-1"})
+
+	ids := func(query store.ThreadQuery) []string {
+		list, err := threads.ListThreads(ctx, query)
+		if err != nil {
+			t.Fatalf("Failed to list threads: %v", err)
+		}
+		var ids []string
+		for _, thread := range list {
+			ids = append(ids, thread.ID)
+		}
+		return ids
+	}
+
+	if got := ids(store.ThreadQuery{}); !slices.Equal(got, []string{"alice-1", "bob-1", "carol-1"}) {
+		t.Errorf("Expected threads by last update, got %v", got)
+	}
+	if got := ids(store.ThreadQuery{Limit: 1, Offset: 1}); !slices.Equal(got, []string{"bob-1"}) {
+		t.Errorf("Expected the second page, got %v", got)
+	}
+
+	thread, err := threads.GetThread(ctx, "alice-1")
+	if err != nil {
+		t.Fatalf("Failed to get thread: %v", err)
+	}
+	if !thread.CreatedAt.Equal(now.Add(-3*time.Hour)) || !thread.UpdatedAt.Equal(now.Add(-30*time.Minute)) {
+		t.Errorf("Unexpected thread times %v - %v", thread.CreatedAt, thread.UpdatedAt)
+	}
+
+	// Metadata is merged, and searched
+	if _, err := threads.UpdateThread(ctx, "alice-1", map[string]any{"user_id": "alice", "title": "Trip"}); err != nil {
+		t.Fatalf("Failed to update thread: %v", err)
+	}
+	thread, err = threads.UpdateThread(ctx, "alice-1", map[string]any{"title": nil, "status": "done"})
+	if err != nil {
+		t.Fatalf("Failed to update thread: %v", err)
+	}
+	if len(thread.Metadata) != 2 || thread.Metadata["user_id"] != "alice" || thread.Metadata["status"] != "done" {
+		t.Errorf("Unexpected metadata %v", thread.Metadata)
+	}
+	if _, err := threads.UpdateThread(ctx, "bob-1", map[string]any{"user_id": "bob"}); err != nil {
+		t.Fatalf("Failed to update thread: %v", err)
+	}
+	if got := ids(store.ThreadQuery{Metadata: map[string]any{"user_id": "alice"}}); !slices.Equal(got, []string{"alice-1"}) {
+		t.Errorf("Expected the threads of alice, got %v", got)
+	}
+
+	if _, err := threads.GetThread(ctx, "unknown"); !errors.Is(err, store.ErrThreadNotFound) {
+		t.Errorf("Expected ErrThreadNotFound, got %v", err)
+	}
+
+	// Idle threads expire
+	deleted, err := store.DeleteExpiredThreads(ctx, threads, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to delete expired threads: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected 1 expired thread, got %d", deleted)
+	}
+	if _, err := ms.Load(ctx, "cp-4"); err == nil {
+		t.Error("Expected the checkpoints of the expired thread to be deleted")
+	}
+
+	// Deleting a thread deletes its checkpoints and those of its subgraphs
+	if err := threads.DeleteThread(ctx, "alice-1"); err != nil {
+		t.Fatalf("Failed to delete thread: %v", err)
+	}
+	for _, threadID := range []string{"alice-1", "alice-1|agent"} {
+		if checkpoints, _ := ms.ListByThread(ctx, threadID); len(checkpoints) != 0 {
+			t.Errorf("Expected no checkpoints left in %s, got %d", threadID, len(checkpoints))
+		}
+	}
+	if got := ids(store.ThreadQuery{}); !slices.Equal(got, []string{"bob-1"}) {
+		t.Errorf("Expected bob-1 only, got %v", got)
+	}
+}
+
+func TestMemoryCheckpointStore_ListCheckpointIDs(t *testing.T) {
+	t.Parallel()
+
+	ms := NewMemoryCheckpointStore()
+	ctx := context.Background()
+	for _, cp := range []*store.Checkpoint{
+		{ID: "cp-1", Metadata: map[string]any{"thread_id": "thread-1"}},
+		{ID: "cp-2", Metadata: map[string]any{"thread_id": "thread-1|sub", "parent_thread_id": "thread-1"}},
+		{ID: "cp-3", Metadata: map[string]any{"execution_id": "exec-1"}},
+	} {
+		if err := ms.Save(ctx, cp); err != nil {
+			t.Fatalf("Failed to save %s: %v", cp.ID, err)
+		}
+	}
+
+	ids, err := ms.(store.CheckpointLister).ListCheckpointIDs(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list checkpoint IDs: %v", err)
+	}
+	if !slices.Equal(ids, []string{"cp-1", "cp-2", "cp-3"}) {
+		t.Errorf("Expected every checkpoint, got %v", ids)
+	}
+}
@@ -0,0 +1,337 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// MigrationFunc migrates the JSON form of a state (its fields keyed by their
+// JSON names) from one schema version to the next.
+type MigrationFunc func(state map[string]any) (map[string]any, error)
+
+// RegisterMigration registers with the global type registry the migration of
+// the registered type typeName from schema version fromVersion to fromVersion+1.
+//
+// Example usage:
+//
+//	// Version 2 renamed Query to Question
+//	store.RegisterMigration("MyState", 1, func(state map[string]any) (map[string]any, error) {
+//		state["question"] = state["query"]
+//		delete(state, "query")
+//		return state, nil
+//	})
+func RegisterMigration(typeName string, fromVersion int, migrate MigrationFunc) error {
+	return globalTypeRegistry.RegisterMigration(typeName, fromVersion, migrate)
+}
+
+// RegisterMigration registers the migration of the registered type typeName
+// from schema version fromVersion to fromVersion+1. Schema versions start at 1
+// and the current version of a type is the one its last migration leads to.
+func (r *TypeRegistry) RegisterMigration(typeName string, fromVersion int, migrate MigrationFunc) error {
+	if fromVersion < 1 {
+		return fmt.Errorf("invalid schema version %d for type %s", fromVersion, typeName)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.typeNameToType[typeName]; !ok {
+		return fmt.Errorf("type %s not registered", typeName)
+	}
+	migrations := r.migrations[typeName]
+	if fromVersion != len(migrations)+1 {
+		return fmt.Errorf("type %s is at schema version %d, cannot migrate from version %d", typeName, len(migrations)+1, fromVersion)
+	}
+	if r.migrations == nil {
+		r.migrations = make(map[string][]MigrationFunc)
+	}
+	r.migrations[typeName] = append(migrations, migrate)
+
+	return nil
+}
+
+// SchemaVersion returns the current schema version of a type, which is 1 for
+// types without migrations.
+func (r *TypeRegistry) SchemaVersion(typeName string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.migrations[typeName]) + 1
+}
+
+// Migrate runs the migrations of a type on the JSON form of a state saved with
+// schema version fromVersion, up to the current version.
+func (r *TypeRegistry) Migrate(typeName string, fromVersion int, state map[string]any) (map[string]any, error) {
+	r.mu.RLock()
+	migrations := r.migrations[typeName]
+	r.mu.RUnlock()
+
+	if fromVersion < 1 {
+		fromVersion = 1
+	}
+	for version := fromVersion; version <= len(migrations); version++ {
+		migrated, err := migrations[version-1](state)
+		if err != nil {
+			return nil, fmt.Errorf("failed to migrate %s from schema version %d: %w", typeName, version, err)
+		}
+		state = migrated
+	}
+	return state, nil
+}
+
+// migrateValue migrates the JSON form of a state and decodes it as the
+// registered type typeName.
+func (r *TypeRegistry) migrateValue(typeName string, fromVersion int, state map[string]any) (any, error) {
+	migrated, err := r.Migrate(typeName, fromVersion, state)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(migrated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal migrated state: %w", err)
+	}
+
+	t, ok := r.GetTypeByName(typeName)
+	if !ok {
+		return nil, fmt.Errorf("unknown type: %s", typeName)
+	}
+
+	r.mu.RLock()
+	unmarshalFunc, hasCustomUnmarshaler := r.jsonUnmarshallers[t]
+	r.mu.RUnlock()
+	if hasCustomUnmarshaler {
+		return unmarshalFunc(data, nil)
+	}
+
+	return decodeValue(t, func(ptr any) error {
+		return json.Unmarshal(data, ptr)
+	})
+}
+
+// StampCheckpoint records in the metadata of a checkpoint the registered type
+// of its state ("state_type") and the current schema version of that type
+// ("schema_version"). A state that is a []any of values of one registered
+// type, such as the updates saved by a delta checkpoint, is stamped with that
+// type. States of unregistered types are stamped with version 1.
+func (r *TypeRegistry) StampCheckpoint(checkpoint *Checkpoint) {
+	if checkpoint.Metadata == nil {
+		checkpoint.Metadata = make(map[string]any)
+	}
+	version := 1
+	if name, ok := r.stateTypeName(checkpoint.State); ok {
+		checkpoint.Metadata["state_type"] = name
+		version = r.SchemaVersion(name)
+	}
+	checkpoint.Metadata["schema_version"] = version
+}
+
+// stateTypeName returns the registered type of a state, or of the non-nil
+// values of a []any state if they all have the same registered type.
+func (r *TypeRegistry) stateTypeName(state any) (string, bool) {
+	if state == nil {
+		return "", false
+	}
+	if name, ok := r.GetTypeName(reflect.TypeOf(state)); ok {
+		return name, true
+	}
+	values, ok := state.([]any)
+	if !ok {
+		return "", false
+	}
+	var t reflect.Type
+	for _, value := range values {
+		if value == nil {
+			continue
+		}
+		if t != nil && reflect.TypeOf(value) != t {
+			return "", false
+		}
+		t = reflect.TypeOf(value)
+	}
+	if t == nil {
+		return "", false
+	}
+	return r.GetTypeName(t)
+}
+
+// MigrateCheckpoint brings the state of a checkpoint stamped with an older
+// schema version up to the current version of its type, and reports whether
+// the checkpoint changed.
+//
+// States that a serializer of this registry decoded are already migrated and
+// only have their version updated; states that come back as plain JSON values
+// are migrated and converted to the registered type. The values of a []any
+// state stamped with the type of its values are migrated one by one.
+func (r *TypeRegistry) MigrateCheckpoint(checkpoint *Checkpoint) (bool, error) {
+	typeName, _ := checkpoint.Metadata["state_type"].(string)
+	if typeName == "" {
+		return false, nil
+	}
+	t, ok := r.GetTypeByName(typeName)
+	if !ok {
+		return false, nil
+	}
+	version := schemaVersionOf(checkpoint.Metadata)
+	current := r.SchemaVersion(typeName)
+	if version >= current {
+		return false, nil
+	}
+
+	if values, ok := checkpoint.State.([]any); ok && t != reflect.TypeOf(values) {
+		migrated := make([]any, len(values))
+		for i, value := range values {
+			var err error
+			if migrated[i], err = r.migrateState(checkpoint.ID, typeName, t, version, value); err != nil {
+				return false, err
+			}
+		}
+		checkpoint.State = migrated
+	} else {
+		migrated, err := r.migrateState(checkpoint.ID, typeName, t, version, checkpoint.State)
+		if err != nil {
+			return false, err
+		}
+		checkpoint.State = migrated
+	}
+
+	checkpoint.Metadata["schema_version"] = current
+	return true, nil
+}
+
+// migrateState migrates a state of checkpoint checkpointID saved with schema
+// version of the registered type typeName, unless it was decoded as t already.
+func (r *TypeRegistry) migrateState(checkpointID, typeName string, t reflect.Type, version int, state any) (any, error) {
+	if state == nil || reflect.TypeOf(state) == t {
+		return state, nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal state: %w", err)
+	}
+	var value map[string]any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("state of checkpoint %s is not a %s: %w", checkpointID, typeName, err)
+	}
+	return r.migrateValue(typeName, version, value)
+}
+
+// MigrateThreadCheckpoints migrates with the global type registry the
+// checkpoints of threads saved with an older schema version, see
+// TypeRegistry.MigrateThreadCheckpoints.
+func MigrateThreadCheckpoints(ctx context.Context, s CheckpointStore, threadIDs ...string) (int, error) {
+	return globalTypeRegistry.MigrateThreadCheckpoints(ctx, s, threadIDs...)
+}
+
+// MigrateThreadCheckpoints loads the checkpoints of threads from a store,
+// migrates those saved with an older schema version and saves them back, so
+// that their stored states no longer depend on the migrations. It returns the
+// number of checkpoints migrated.
+//
+// For a store implementing CheckpointLister, as the stores of this module do,
+// every checkpoint of the store is visited: the checkpoints of the threads,
+// including those their subgraphs saved under their own thread IDs, and with no
+// thread IDs all checkpoints, including those saved with only an execution ID.
+//
+// Other stores are asked for the checkpoints saved with one of the thread IDs
+// as "thread_id", and with no thread IDs for the threads they list if they
+// implement ThreadStore. The checkpoints of subgraphs and those saved without a
+// thread are then not reached; they are still migrated when they are loaded.
+func (r *TypeRegistry) MigrateThreadCheckpoints(ctx context.Context, s CheckpointStore, threadIDs ...string) (int, error) {
+	if lister, ok := s.(CheckpointLister); ok {
+		ids, err := lister.ListCheckpointIDs(ctx)
+		if err == nil {
+			return r.migrateListedCheckpoints(ctx, s, ids, threadIDs)
+		}
+		if !errors.Is(err, ErrListingNotSupported) {
+			return 0, fmt.Errorf("failed to list checkpoints: %w", err)
+		}
+	}
+
+	if len(threadIDs) == 0 {
+		threads, ok := s.(ThreadStore)
+		if !ok {
+			return 0, fmt.Errorf("%w: pass the IDs of the threads to migrate", ErrThreadsNotSupported)
+		}
+		listed, err := threads.ListThreads(ctx, ThreadQuery{})
+		if err != nil {
+			return 0, fmt.Errorf("failed to list threads: %w", err)
+		}
+		for _, thread := range listed {
+			threadIDs = append(threadIDs, thread.ID)
+		}
+	}
+
+	migrated := 0
+	for _, threadID := range threadIDs {
+		checkpoints, err := s.ListByThread(ctx, threadID)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to list checkpoints of thread %s: %w", threadID, err)
+		}
+		for _, checkpoint := range checkpoints {
+			changed, err := r.migrateAndSave(ctx, s, checkpoint)
+			if err != nil {
+				return migrated, err
+			}
+			if changed {
+				migrated++
+			}
+		}
+	}
+	return migrated, nil
+}
+
+// migrateListedCheckpoints migrates the checkpoints with the given IDs that
+// belong to one of the threads, or all of them with no thread IDs.
+func (r *TypeRegistry) migrateListedCheckpoints(ctx context.Context, s CheckpointStore, ids, threadIDs []string) (int, error) {
+	migrated := 0
+	for _, id := range ids {
+		checkpoint, err := s.Load(ctx, id)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to load checkpoint %s: %w", id, err)
+		}
+		if len(threadIDs) > 0 && !slices.Contains(threadIDs, CheckpointThreadID(checkpoint)) {
+			continue
+		}
+		changed, err := r.migrateAndSave(ctx, s, checkpoint)
+		if err != nil {
+			return migrated, err
+		}
+		if changed {
+			migrated++
+		}
+	}
+	return migrated, nil
+}
+
+// migrateAndSave migrates a checkpoint and saves it back if it changed.
+func (r *TypeRegistry) migrateAndSave(ctx context.Context, s CheckpointStore, checkpoint *Checkpoint) (bool, error) {
+	changed, err := r.MigrateCheckpoint(checkpoint)
+	if err != nil {
+		return false, fmt.Errorf("failed to migrate checkpoint %s: %w", checkpoint.ID, err)
+	}
+	if !changed {
+		return false, nil
+	}
+	if err := s.Save(ctx, checkpoint); err != nil {
+		return false, fmt.Errorf("failed to save checkpoint %s: %w", checkpoint.ID, err)
+	}
+	return true, nil
+}
+
+// schemaVersionOf returns the schema version stamped in checkpoint metadata,
+// which is 1 for checkpoints saved before versions were recorded. Stores
+// returning metadata decoded from JSON give numbers as float64.
+func schemaVersionOf(metadata map[string]any) int {
+	switch v := metadata["schema_version"].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 1
+}
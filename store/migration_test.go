@@ -0,0 +1,316 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Version 1 of a state, before Query was renamed to Question and Lang added
+type searchStateV1 struct {
+	Query string `json:"query"`
+}
+
+type searchStateV2 struct {
+	Question string `json:"question"`
+	Lang     string `json:"lang"`
+}
+
+func renameQuery(state map[string]any) (map[string]any, error) {
+	state["question"] = state["query"]
+	delete(state, "query")
+	return state, nil
+}
+
+func addLang(state map[string]any) (map[string]any, error) {
+	if lang, _ := state["lang"].(string); lang == "" {
+		state["lang"] = "en"
+	}
+	return state, nil
+}
+
+// upgrade registers version 2 of the state under the same name, as a new
+// deploy would
+func upgrade(t *testing.T, registry *TypeRegistry) {
+	t.Helper()
+	require.NoError(t, registry.RegisterTypeInternal(reflect.TypeOf(searchStateV2{}), "searchState"))
+	require.NoError(t, registry.RegisterMigration("searchState", 1, renameQuery))
+	require.NoError(t, registry.RegisterMigration("searchState", 2, addLang))
+}
+
+func TestTypeRegistry_RegisterMigration(t *testing.T) {
+	registry := NewTypeRegistry()
+	assert.ErrorContains(t, registry.RegisterMigration("searchState", 1, renameQuery), "not registered")
+
+	require.NoError(t, registry.RegisterTypeInternal(reflect.TypeOf(searchStateV2{}), "searchState"))
+	assert.Equal(t, 1, registry.SchemaVersion("searchState"))
+	assert.Error(t, registry.RegisterMigration("searchState", 2, renameQuery))
+	assert.Error(t, registry.RegisterMigration("searchState", 0, renameQuery))
+
+	require.NoError(t, registry.RegisterMigration("searchState", 1, renameQuery))
+	require.NoError(t, registry.RegisterMigration("searchState", 2, addLang))
+	assert.Equal(t, 3, registry.SchemaVersion("searchState"))
+
+	migrated, err := registry.Migrate("searchState", 2, map[string]any{"question": "go"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"question": "go", "lang": "en"}, migrated)
+}
+
+func TestSerializers_MigrateOnLoad(t *testing.T) {
+	serializers := map[string]func(*TypeRegistry) Serializer{
+		"json":    NewJSONSerializer,
+		"msgpack": NewMsgpackSerializer,
+	}
+	for name, newSerializer := range serializers {
+		t.Run(name, func(t *testing.T) {
+			registry := NewTypeRegistry()
+			require.NoError(t, registry.RegisterTypeInternal(reflect.TypeOf(searchStateV1{}), "searchState"))
+			data, err := newSerializer(registry).Marshal(searchStateV1{Query: "go"})
+			require.NoError(t, err)
+
+			upgrade(t, registry)
+			state, err := newSerializer(registry).Unmarshal(data)
+			require.NoError(t, err)
+			assert.Equal(t, searchStateV2{Question: "go", Lang: "en"}, state)
+
+			// States saved at the current version are not migrated again
+			data, err = newSerializer(registry).Marshal(searchStateV2{Question: "go", Lang: "fr"})
+			require.NoError(t, err)
+			state, err = newSerializer(registry).Unmarshal(data)
+			require.NoError(t, err)
+			assert.Equal(t, searchStateV2{Question: "go", Lang: "fr"}, state)
+		})
+	}
+
+	t.Run("gob", func(t *testing.T) {
+		// gob decodes into the current type, so only added fields are migrated
+		registry := NewTypeRegistry()
+		require.NoError(t, registry.RegisterTypeInternal(reflect.TypeOf(searchStateV2{}), "searchState"))
+		data, err := NewGobSerializer(registry).Marshal(searchStateV2{Question: "go"})
+		require.NoError(t, err)
+
+		require.NoError(t, registry.RegisterMigration("searchState", 1, addLang))
+		state, err := NewGobSerializer(registry).Unmarshal(data)
+		require.NoError(t, err)
+		assert.Equal(t, searchStateV2{Question: "go", Lang: "en"}, state)
+	})
+}
+
+func TestTypeRegistry_MigrateCheckpoint(t *testing.T) {
+	registry := NewTypeRegistry()
+	require.NoError(t, registry.RegisterTypeInternal(reflect.TypeOf(searchStateV1{}), "searchState"))
+
+	checkpoint := &Checkpoint{ID: "cp-1", State: searchStateV1{Query: "go"}}
+	registry.StampCheckpoint(checkpoint)
+	assert.Equal(t, "searchState", checkpoint.Metadata["state_type"])
+	assert.Equal(t, 1, checkpoint.Metadata["schema_version"])
+
+	upgrade(t, registry)
+
+	// A state decoded as a plain JSON value is migrated to the current type
+	checkpoint.State = map[string]any{"query": "go"}
+	checkpoint.Metadata["schema_version"] = float64(1)
+	changed, err := registry.MigrateCheckpoint(checkpoint)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, searchStateV2{Question: "go", Lang: "en"}, checkpoint.State)
+	assert.Equal(t, 3, checkpoint.Metadata["schema_version"])
+
+	changed, err = registry.MigrateCheckpoint(checkpoint)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	// Unregistered states are left alone
+	unregistered := &Checkpoint{ID: "cp-2", State: map[string]any{"query": "go"}}
+	registry.StampCheckpoint(unregistered)
+	assert.Equal(t, 1, unregistered.Metadata["schema_version"])
+	changed, err = registry.MigrateCheckpoint(unregistered)
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestTypeRegistry_MigrateCheckpointOfStateList(t *testing.T) {
+	registry := NewTypeRegistry()
+	require.NoError(t, registry.RegisterTypeInternal(reflect.TypeOf(searchStateV1{}), "searchState"))
+
+	checkpoint := &Checkpoint{ID: "cp-1", State: []any{searchStateV1{Query: "go"}, nil, searchStateV1{Query: "rust"}}}
+	registry.StampCheckpoint(checkpoint)
+	assert.Equal(t, "searchState", checkpoint.Metadata["state_type"])
+
+	upgrade(t, registry)
+
+	// Each value is migrated, whether decoded as a plain JSON value or not
+	checkpoint.State = []any{map[string]any{"query": "go"}, nil, searchStateV2{Question: "rust", Lang: "fr"}}
+	changed, err := registry.MigrateCheckpoint(checkpoint)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, []any{searchStateV2{Question: "go", Lang: "en"}, nil, searchStateV2{Question: "rust", Lang: "fr"}}, checkpoint.State)
+	assert.Equal(t, 3, checkpoint.Metadata["schema_version"])
+
+	// Lists mixing types are not stamped with a type
+	mixed := &Checkpoint{ID: "cp-2", State: []any{searchStateV2{Question: "go"}, map[string]any{"query": "go"}}}
+	registry.StampCheckpoint(mixed)
+	assert.Nil(t, mixed.Metadata["state_type"])
+}
+
+// listStore is a minimal CheckpointStore keeping checkpoints in a slice
+type listStore struct {
+	CheckpointStore
+	checkpoints []*Checkpoint
+	saved       int
+}
+
+func (s *listStore) ListByThread(_ context.Context, threadID string) ([]*Checkpoint, error) {
+	var checkpoints []*Checkpoint
+	for _, cp := range s.checkpoints {
+		if cp.Metadata["thread_id"] == threadID {
+			checkpoints = append(checkpoints, cp)
+		}
+	}
+	return checkpoints, nil
+}
+
+func (s *listStore) Save(_ context.Context, checkpoint *Checkpoint) error {
+	s.saved++
+	return nil
+}
+
+func TestTypeRegistry_MigrateThreadCheckpoints(t *testing.T) {
+	registry := NewTypeRegistry()
+	require.NoError(t, registry.RegisterTypeInternal(reflect.TypeOf(searchStateV1{}), "searchState"))
+
+	s := &listStore{}
+	for i, threadID := range []string{"thread-1", "thread-1", "thread-2"} {
+		cp := &Checkpoint{
+			ID:       string(rune('a' + i)),
+			State:    map[string]any{"query": "go"},
+			Metadata: map[string]any{"thread_id": threadID, "state_type": "searchState"},
+		}
+		s.checkpoints = append(s.checkpoints, cp)
+	}
+
+	upgrade(t, registry)
+	migrated, err := registry.MigrateThreadCheckpoints(context.Background(), s, "thread-1", "thread-2")
+	require.NoError(t, err)
+	assert.Equal(t, 3, migrated)
+	assert.Equal(t, 3, s.saved)
+	for _, cp := range s.checkpoints {
+		assert.Equal(t, searchStateV2{Question: "go", Lang: "en"}, cp.State)
+	}
+
+	// Migrated checkpoints are not saved again
+	migrated, err = registry.MigrateThreadCheckpoints(context.Background(), s, "thread-1", "thread-2")
+	require.NoError(t, err)
+	assert.Equal(t, 0, migrated)
+}
+
+func TestTypeRegistry_MigrateThreadCheckpointsOfAllThreads(t *testing.T) {
+	registry := NewTypeRegistry()
+	require.NoError(t, registry.RegisterTypeInternal(reflect.TypeOf(searchStateV1{}), "searchState"))
+
+	s := &threadListStore{listStore: &listStore{}}
+	for i, threadID := range []string{"thread-1", "thread-2"} {
+		s.checkpoints = append(s.checkpoints, &Checkpoint{
+			ID:       string(rune('a' + i)),
+			State:    map[string]any{"query": "go"},
+			Metadata: map[string]any{"thread_id": threadID, "state_type": "searchState"},
+		})
+	}
+
+	upgrade(t, registry)
+	migrated, err := registry.MigrateThreadCheckpoints(context.Background(), s)
+	require.NoError(t, err)
+	assert.Equal(t, 2, migrated)
+
+	// Stores without threads need the thread IDs
+	_, err = registry.MigrateThreadCheckpoints(context.Background(), s.listStore)
+	assert.ErrorIs(t, err, ErrThreadsNotSupported)
+}
+
+func TestTypeRegistry_MigrateThreadCheckpointsOfSubgraphs(t *testing.T) {
+	registry := NewTypeRegistry()
+	require.NoError(t, registry.RegisterTypeInternal(reflect.TypeOf(searchStateV1{}), "searchState"))
+
+	s := &checkpointListStore{listStore: &listStore{}}
+	for _, metadata := range []map[string]any{
+		{"thread_id": "thread-1"},
+		// Saved by the subgraph run by the node "research" of thread-1
+		{"thread_id": "thread-1|research", "parent_thread_id": "thread-1", "checkpoint_ns": "research"},
+		{"thread_id": "thread-2"},
+		// Saved without a thread
+		{"execution_id": "exec-1"},
+	} {
+		metadata["state_type"] = "searchState"
+		s.checkpoints = append(s.checkpoints, &Checkpoint{
+			ID:       string(rune('a' + len(s.checkpoints))),
+			State:    map[string]any{"query": "go"},
+			Metadata: metadata,
+		})
+	}
+
+	upgrade(t, registry)
+	migrated, err := registry.MigrateThreadCheckpoints(context.Background(), s, "thread-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, migrated)
+	assert.Equal(t, searchStateV2{Question: "go", Lang: "en"}, s.checkpoints[1].State)
+	assert.Equal(t, map[string]any{"query": "go"}, s.checkpoints[2].State)
+
+	// With no thread IDs, every checkpoint is migrated
+	migrated, err = registry.MigrateThreadCheckpoints(context.Background(), s)
+	require.NoError(t, err)
+	assert.Equal(t, 2, migrated)
+	for _, cp := range s.checkpoints {
+		assert.Equal(t, searchStateV2{Question: "go", Lang: "en"}, cp.State)
+	}
+}
+
+// checkpointListStore lists the checkpoints of a listStore
+type checkpointListStore struct {
+	*listStore
+}
+
+func (s *checkpointListStore) ListCheckpointIDs(context.Context) ([]string, error) {
+	var ids []string
+	for _, cp := range s.checkpoints {
+		ids = append(ids, cp.ID)
+	}
+	return ids, nil
+}
+
+func (s *checkpointListStore) Load(_ context.Context, checkpointID string) (*Checkpoint, error) {
+	for _, cp := range s.checkpoints {
+		if cp.ID == checkpointID {
+			return cp, nil
+		}
+	}
+	return nil, fmt.Errorf("checkpoint not found: %s", checkpointID)
+}
+
+// threadListStore lists the threads of the checkpoints of a listStore
+type threadListStore struct {
+	*listStore
+}
+
+func (s *threadListStore) ListThreads(_ context.Context, query ThreadQuery) ([]*Thread, error) {
+	var threads []*Thread
+	for _, cp := range s.checkpoints {
+		threads = append(threads, &Thread{ID: CheckpointThreadID(cp)})
+	}
+	return query.Apply(threads), nil
+}
+
+func (s *threadListStore) GetThread(context.Context, string) (*Thread, error) {
+	return nil, ErrThreadNotFound
+}
+
+func (s *threadListStore) UpdateThread(context.Context, string, map[string]any) (*Thread, error) {
+	return nil, ErrThreadsNotSupported
+}
+
+func (s *threadListStore) DeleteThread(context.Context, string) error {
+	return ErrThreadsNotSupported
+}
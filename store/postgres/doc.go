@@ -147,6 +147,10 @@
 //
 // # Migration and Schema Management
 //
+// InitSchema and MigrateSchema create the "<table>_threads" table of threads,
+// see store.ThreadStore, recording the threads of the checkpoints saved before
+// it existed.
+//
 // ## Automatic Migration
 //
 //	// The store can automatically handle schema migrations
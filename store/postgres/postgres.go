@@ -3,12 +3,16 @@ package postgres
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/smallnest/langgraphgo/graph"
+	"github.com/smallnest/langgraphgo/store"
 )
 
 // DBPool defines the interface for database connection pool
@@ -16,19 +20,27 @@ type DBPool interface {
 	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
 	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
 	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
 	Close()
 }
 
+var (
+	_ store.ThreadStore      = (*PostgresCheckpointStore)(nil)
+	_ store.CheckpointLister = (*PostgresCheckpointStore)(nil)
+)
+
 // PostgresCheckpointStore implements graph.CheckpointStore using PostgreSQL
 type PostgresCheckpointStore struct {
-	pool      DBPool
-	tableName string
+	pool       DBPool
+	tableName  string
+	serializer store.Serializer
 }
 
 // PostgresOptions configuration for Postgres connection
 type PostgresOptions struct {
 	ConnString string
-	TableName  string // Default "checkpoints"
+	TableName  string           // Default "checkpoints", threads are kept in "<TableName>_threads"
+	Serializer store.Serializer // Serializer of checkpoint states, default store.DefaultSerializer()
 }
 
 // NewPostgresCheckpointStore creates a new Postgres checkpoint store
@@ -43,9 +55,15 @@ func NewPostgresCheckpointStore(ctx context.Context, opts PostgresOptions) (*Pos
 		tableName = "checkpoints"
 	}
 
+	serializer := opts.Serializer
+	if serializer == nil {
+		serializer = store.DefaultSerializer()
+	}
+
 	return &PostgresCheckpointStore{
-		pool:      pool,
-		tableName: tableName,
+		pool:       pool,
+		tableName:  tableName,
+		serializer: serializer,
 	}, nil
 }
 
@@ -56,12 +74,19 @@ func NewPostgresCheckpointStoreWithPool(pool DBPool, tableName string) *Postgres
 		tableName = "checkpoints"
 	}
 	return &PostgresCheckpointStore{
-		pool:      pool,
-		tableName: tableName,
+		pool:       pool,
+		tableName:  tableName,
+		serializer: store.DefaultSerializer(),
 	}
 }
 
-// InitSchema creates the necessary table if it doesn't exist
+// SetSerializer sets the serializer of checkpoint states
+func (s *PostgresCheckpointStore) SetSerializer(serializer store.Serializer) {
+	s.serializer = serializer
+}
+
+// InitSchema creates the necessary tables if they don't exist. The threads of
+// the checkpoints saved before the threads table existed are recorded in it.
 func (s *PostgresCheckpointStore) InitSchema(ctx context.Context) error {
 	query := fmt.Sprintf(`
 		CREATE TABLE IF NOT EXISTS %s (
@@ -83,9 +108,49 @@ func (s *PostgresCheckpointStore) InitSchema(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to create schema: %w", err)
 	}
+	return s.initThreadsSchema(ctx)
+}
+
+// initThreadsSchema creates the threads table if it doesn't exist, recording
+// the threads of the checkpoints saved so far
+func (s *PostgresCheckpointStore) initThreadsSchema(ctx context.Context) error {
+	query := fmt.Sprintf(`
+		DO $$
+		BEGIN
+			IF NOT EXISTS (
+				SELECT 1 FROM information_schema.tables WHERE table_name = '%s'
+			) THEN
+				CREATE TABLE %s (
+					thread_id TEXT PRIMARY KEY,
+					metadata JSONB NOT NULL DEFAULT '{}',
+					created_at TIMESTAMPTZ NOT NULL,
+					updated_at TIMESTAMPTZ NOT NULL
+				);
+				CREATE INDEX idx_%s_updated_at ON %s (updated_at);
+				CREATE INDEX idx_%s_metadata ON %s USING GIN (metadata);
+
+				INSERT INTO %s (thread_id, created_at, updated_at)
+				SELECT COALESCE(metadata->>'parent_thread_id', thread_id), MIN(timestamp), MAX(timestamp)
+				FROM %s
+				WHERE thread_id <> ''
+				GROUP BY 1;
+			END IF;
+		END $$;
+	`, s.threadsTable(), s.threadsTable(), s.threadsTable(), s.threadsTable(), s.threadsTable(), s.threadsTable(),
+		s.threadsTable(), s.tableName)
+
+	_, err := s.pool.Exec(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to create threads schema: %w", err)
+	}
 	return nil
 }
 
+// threadsTable is the table of threads
+func (s *PostgresCheckpointStore) threadsTable() string {
+	return s.tableName + "_threads"
+}
+
 // MigrateSchema adds the thread_id column if it doesn't exist (for existing installations)
 func (s *PostgresCheckpointStore) MigrateSchema(ctx context.Context) error {
 	// Add thread_id column if it doesn't exist
@@ -117,7 +182,7 @@ func (s *PostgresCheckpointStore) MigrateSchema(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to migrate schema: %w", err)
 	}
-	return nil
+	return s.initThreadsSchema(ctx)
 }
 
 // Close closes the connection pool
@@ -127,7 +192,7 @@ func (s *PostgresCheckpointStore) Close() {
 
 // Save stores a checkpoint
 func (s *PostgresCheckpointStore) Save(ctx context.Context, checkpoint *graph.Checkpoint) error {
-	stateJSON, err := json.Marshal(checkpoint.State)
+	stateJSON, err := store.MarshalState(s.serializer, checkpoint.State)
 	if err != nil {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
@@ -160,7 +225,14 @@ func (s *PostgresCheckpointStore) Save(ctx context.Context, checkpoint *graph.Ch
 			version = EXCLUDED.version
 	`, s.tableName)
 
-	_, err = s.pool.Exec(ctx, query,
+	// The checkpoint and its thread are saved together
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, query,
 		checkpoint.ID,
 		executionID,
 		threadID,
@@ -175,6 +247,23 @@ func (s *PostgresCheckpointStore) Save(ctx context.Context, checkpoint *graph.Ch
 		return fmt.Errorf("failed to save checkpoint: %w", err)
 	}
 
+	// Record the checkpoint in its thread
+	if threadID := store.CheckpointThreadID(checkpoint); threadID != "" {
+		query = fmt.Sprintf(`
+			INSERT INTO %s (thread_id, created_at, updated_at)
+			VALUES ($1, $2, $2)
+			ON CONFLICT (thread_id) DO UPDATE SET
+				created_at = LEAST(%s.created_at, EXCLUDED.created_at),
+				updated_at = GREATEST(%s.updated_at, EXCLUDED.updated_at)
+		`, s.threadsTable(), s.threadsTable(), s.threadsTable())
+		if _, err := tx.Exec(ctx, query, threadID, checkpoint.Timestamp); err != nil {
+			return fmt.Errorf("failed to update thread: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit checkpoint: %w", err)
+	}
 	return nil
 }
 
@@ -206,7 +295,7 @@ func (s *PostgresCheckpointStore) Load(ctx context.Context, checkpointID string)
 		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
 	}
 
-	if err := json.Unmarshal(stateJSON, &cp.State); err != nil {
+	if err := s.unmarshalState(stateJSON, &cp); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
 	}
 
@@ -252,7 +341,7 @@ func (s *PostgresCheckpointStore) List(ctx context.Context, executionID string)
 			return nil, fmt.Errorf("failed to scan checkpoint row: %w", err)
 		}
 
-		if err := json.Unmarshal(stateJSON, &cp.State); err != nil {
+		if err := s.unmarshalState(stateJSON, &cp); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal state: %w", err)
 		}
 
@@ -272,6 +361,30 @@ func (s *PostgresCheckpointStore) List(ctx context.Context, executionID string)
 	return checkpoints, nil
 }
 
+// ListCheckpointIDs returns the IDs of all checkpoints
+func (s *PostgresCheckpointStore) ListCheckpointIDs(ctx context.Context) ([]string, error) {
+	query := fmt.Sprintf("SELECT id FROM %s ORDER BY id", s.tableName)
+
+	rows, err := s.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan checkpoint row: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating checkpoint rows: %w", err)
+	}
+	return ids, nil
+}
+
 // ListByThread returns all checkpoints for a specific thread_id
 func (s *PostgresCheckpointStore) ListByThread(ctx context.Context, threadID string) ([]*graph.Checkpoint, error) {
 	query := fmt.Sprintf(`
@@ -305,7 +418,7 @@ func (s *PostgresCheckpointStore) ListByThread(ctx context.Context, threadID str
 			return nil, fmt.Errorf("failed to scan checkpoint row: %w", err)
 		}
 
-		if err := json.Unmarshal(stateJSON, &cp.State); err != nil {
+		if err := s.unmarshalState(stateJSON, &cp); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal state: %w", err)
 		}
 
@@ -355,7 +468,7 @@ func (s *PostgresCheckpointStore) GetLatestByThread(ctx context.Context, threadI
 		return nil, fmt.Errorf("failed to get latest checkpoint by thread: %w", err)
 	}
 
-	if err := json.Unmarshal(stateJSON, &cp.State); err != nil {
+	if err := s.unmarshalState(stateJSON, &cp); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
 	}
 
@@ -387,3 +500,162 @@ func (s *PostgresCheckpointStore) Clear(ctx context.Context, executionID string)
 	}
 	return nil
 }
+
+// unmarshalState decodes a state column into the checkpoint
+func (s *PostgresCheckpointStore) unmarshalState(stateJSON []byte, cp *graph.Checkpoint) error {
+	state, err := store.UnmarshalState(s.serializer, stateJSON)
+	if err != nil {
+		return err
+	}
+	cp.State = state
+	return nil
+}
+
+// ListThreads implements store.ThreadStore
+func (s *PostgresCheckpointStore) ListThreads(ctx context.Context, query store.ThreadQuery) ([]*store.Thread, error) {
+	var conditions []string
+	var args []any
+	if !query.UpdatedBefore.IsZero() {
+		args = append(args, query.UpdatedBefore)
+		conditions = append(conditions, fmt.Sprintf("updated_at < $%d", len(args)))
+	}
+	if len(query.Metadata) > 0 {
+		filter, err := json.Marshal(query.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal metadata filter: %w", err)
+		}
+		args = append(args, filter)
+		conditions = append(conditions, fmt.Sprintf("metadata @> $%d", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	var limit any // NULL is no limit
+	if query.Limit > 0 {
+		limit = query.Limit
+	}
+	args = append(args, limit, query.Offset)
+
+	sqlQuery := fmt.Sprintf(`
+		SELECT thread_id, metadata, created_at, updated_at
+		FROM %s
+		%s
+		ORDER BY updated_at DESC, thread_id ASC
+		LIMIT $%d OFFSET $%d
+	`, s.threadsTable(), where, len(args)-1, len(args))
+
+	rows, err := s.pool.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list threads: %w", err)
+	}
+	defer rows.Close()
+
+	threads := []*store.Thread{}
+	for rows.Next() {
+		thread, err := scanThread(rows)
+		if err != nil {
+			return nil, err
+		}
+		threads = append(threads, thread)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating thread rows: %w", err)
+	}
+
+	return threads, nil
+}
+
+// GetThread implements store.ThreadStore
+func (s *PostgresCheckpointStore) GetThread(ctx context.Context, threadID string) (*store.Thread, error) {
+	query := fmt.Sprintf(`
+		SELECT thread_id, metadata, created_at, updated_at
+		FROM %s
+		WHERE thread_id = $1
+	`, s.threadsTable())
+
+	thread, err := scanThread(s.pool.QueryRow(ctx, query, threadID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", store.ErrThreadNotFound, threadID)
+	}
+	return thread, err
+}
+
+// UpdateThread implements store.ThreadStore
+func (s *PostgresCheckpointStore) UpdateThread(ctx context.Context, threadID string, metadata map[string]any) (*store.Thread, error) {
+	// Keys set to nil are removed, the others are merged
+	set := make(map[string]any, len(metadata))
+	removed := []string{}
+	for key, value := range metadata {
+		if value == nil {
+			removed = append(removed, key)
+		} else {
+			set[key] = value
+		}
+	}
+	setJSON, err := json.Marshal(set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal thread metadata: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (thread_id, metadata, created_at, updated_at)
+		VALUES ($1, $2, $3, $3)
+		ON CONFLICT (thread_id) DO UPDATE SET
+			metadata = (%s.metadata || EXCLUDED.metadata) - $4::text[],
+			updated_at = GREATEST(%s.updated_at, EXCLUDED.updated_at)
+		RETURNING thread_id, metadata, created_at, updated_at
+	`, s.threadsTable(), s.threadsTable(), s.threadsTable())
+
+	thread, err := scanThread(s.pool.QueryRow(ctx, query, threadID, setJSON, time.Now(), removed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update thread: %w", err)
+	}
+	return thread, nil
+}
+
+// DeleteThread implements store.ThreadStore
+func (s *PostgresCheckpointStore) DeleteThread(ctx context.Context, threadID string) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE thread_id = $1 OR metadata->>'parent_thread_id' = $1", s.tableName)
+	if _, err := tx.Exec(ctx, query, threadID); err != nil {
+		return fmt.Errorf("failed to delete checkpoints of thread: %w", err)
+	}
+
+	query = fmt.Sprintf("DELETE FROM %s WHERE thread_id = $1", s.threadsTable())
+	if _, err := tx.Exec(ctx, query, threadID); err != nil {
+		return fmt.Errorf("failed to delete thread: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit thread deletion: %w", err)
+	}
+	return nil
+}
+
+// scanThread scans a thread row
+func scanThread(row pgx.Row) (*store.Thread, error) {
+	var thread store.Thread
+	var metadataJSON []byte
+	if err := row.Scan(&thread.ID, &metadataJSON, &thread.CreatedAt, &thread.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan thread row: %w", err)
+	}
+	if len(metadataJSON) > 0 {
+		if err := json.Unmarshal(metadataJSON, &thread.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal thread metadata: %w", err)
+		}
+	}
+	if thread.Metadata == nil {
+		thread.Metadata = map[string]any{}
+	}
+	return &thread, nil
+}
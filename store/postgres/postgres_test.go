@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"reflect"
 	"regexp"
 	"testing"
 	"time"
@@ -11,6 +12,7 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/pashagolub/pgxmock/v3"
 	"github.com/smallnest/langgraphgo/graph"
+	"github.com/smallnest/langgraphgo/store"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -36,6 +38,7 @@ func TestPostgresCheckpointStore_Save(t *testing.T) {
 	metadataJSON, _ := json.Marshal(cp.Metadata)
 
 	// Expect INSERT
+	mock.ExpectBegin()
 	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO checkpoints")).
 		WithArgs(
 			cp.ID,
@@ -48,6 +51,7 @@ func TestPostgresCheckpointStore_Save(t *testing.T) {
 			cp.Version,
 		).
 		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
 
 	err = store.Save(context.Background(), cp)
 	assert.NoError(t, err)
@@ -111,6 +115,7 @@ func TestPostgresCheckpointStore_Save_WithoutExecutionID(t *testing.T) {
 	metadataJSON, _ := json.Marshal(cp.Metadata)
 
 	// Expect INSERT with empty execution_id
+	mock.ExpectBegin()
 	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO checkpoints")).
 		WithArgs(
 			cp.ID,
@@ -123,6 +128,7 @@ func TestPostgresCheckpointStore_Save_WithoutExecutionID(t *testing.T) {
 			cp.Version,
 		).
 		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
 
 	err = store.Save(context.Background(), cp)
 	assert.NoError(t, err)
@@ -515,6 +521,8 @@ func TestPostgresCheckpointStore_InitSchema(t *testing.T) {
 		CREATE INDEX IF NOT EXISTS idx_checkpoints_execution_thread ON checkpoints (execution_id, thread_id);
 	`)).
 		WillReturnResult(pgxmock.NewResult("CREATE", 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE checkpoints_threads")).
+		WillReturnResult(pgxmock.NewResult("DO", 0))
 
 	err = store.InitSchema(context.Background())
 	assert.NoError(t, err)
@@ -546,6 +554,8 @@ func TestPostgresCheckpointStore_InitSchema_CustomTable(t *testing.T) {
 		CREATE INDEX IF NOT EXISTS idx_custom_checkpoints_execution_thread ON custom_checkpoints (execution_id, thread_id);
 	`)).
 		WillReturnResult(pgxmock.NewResult("CREATE", 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE custom_checkpoints_threads")).
+		WillReturnResult(pgxmock.NewResult("DO", 0))
 
 	err = store.InitSchema(context.Background())
 	assert.NoError(t, err)
@@ -618,6 +628,7 @@ func TestPostgresCheckpointStore_Save_Conflict(t *testing.T) {
 	metadataJSON, _ := json.Marshal(cp.Metadata)
 
 	// Expect UPDATE due to conflict
+	mock.ExpectBegin()
 	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO checkpoints")).
 		WithArgs(
 			cp.ID,
@@ -630,6 +641,7 @@ func TestPostgresCheckpointStore_Save_Conflict(t *testing.T) {
 			cp.Version,
 		).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
 
 	err = store.Save(context.Background(), cp)
 	assert.NoError(t, err)
@@ -659,6 +671,7 @@ func TestPostgresCheckpointStore_Save_DatabaseError(t *testing.T) {
 	metadataJSON, _ := json.Marshal(cp.Metadata)
 
 	dbError := errors.New("database connection failed")
+	mock.ExpectBegin()
 	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO checkpoints")).
 		WithArgs(
 			cp.ID,
@@ -671,6 +684,7 @@ func TestPostgresCheckpointStore_Save_DatabaseError(t *testing.T) {
 			cp.Version,
 		).
 		WillReturnError(dbError)
+	mock.ExpectRollback()
 
 	err = store.Save(context.Background(), cp)
 	assert.Error(t, err)
@@ -714,3 +728,150 @@ func TestNewPostgresCheckpointStore_InvalidConnection(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "unable to create connection pool")
 }
+
+type typedState struct {
+	Query   string   `json:"query"`
+	Results []string `json:"results"`
+}
+
+func TestPostgresCheckpointStore_TypedState(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	registry := store.NewTypeRegistry()
+	assert.NoError(t, registry.RegisterTypeInternal(reflect.TypeOf(typedState{}), "typedState"))
+	serializer := store.NewMsgpackSerializer(registry)
+
+	s := NewPostgresCheckpointStoreWithPool(mock, "checkpoints")
+	s.SetSerializer(serializer)
+
+	state := typedState{Query: "go", Results: []string{"a", "b"}}
+	cp := &graph.Checkpoint{
+		ID:        "cp-1",
+		NodeName:  "node-a",
+		State:     state,
+		Timestamp: time.Now(),
+		Version:   1,
+		Metadata:  map[string]any{"thread_id": "thread-1"},
+	}
+
+	// The binary state is kept in a JSON document for the JSONB column
+	stateJSON, err := store.MarshalState(serializer, state)
+	assert.NoError(t, err)
+	assert.True(t, json.Valid(stateJSON))
+	metadataJSON, _ := json.Marshal(cp.Metadata)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO checkpoints")).
+		WithArgs(cp.ID, "", "thread-1", cp.NodeName, stateJSON, metadataJSON, cp.Timestamp, cp.Version).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO checkpoints_threads")).
+		WithArgs("thread-1", cp.Timestamp).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+	assert.NoError(t, s.Save(context.Background(), cp))
+
+	rows := pgxmock.NewRows([]string{"id", "node_name", "state", "metadata", "timestamp", "version"}).
+		AddRow(cp.ID, cp.NodeName, stateJSON, metadataJSON, cp.Timestamp, 1)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, node_name, state, metadata, timestamp, version FROM checkpoints WHERE id = $1")).
+		WithArgs(cp.ID).
+		WillReturnRows(rows)
+
+	loaded, err := s.Load(context.Background(), cp.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, state, loaded.State)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresCheckpointStore_Threads(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	s := NewPostgresCheckpointStoreWithPool(mock, "checkpoints")
+	ctx := context.Background()
+	now := time.Now()
+	columns := []string{"thread_id", "metadata", "created_at", "updated_at"}
+
+	// Metadata is searched with JSONB containment, pages are selected by the database
+	before := now.Add(-time.Hour)
+	mock.ExpectQuery(regexp.QuoteMeta("FROM checkpoints_threads WHERE updated_at < $1 AND metadata @> $2 ORDER BY updated_at DESC, thread_id ASC LIMIT $3 OFFSET $4")).
+		WithArgs(before, []byte(`{"user_id":"alice"}`), 10, 20).
+		WillReturnRows(pgxmock.NewRows(columns).
+			AddRow("alice-1", []byte(`{"user_id":"alice"}`), now.Add(-3*time.Hour), now.Add(-2*time.Hour)))
+	threads, err := s.ListThreads(ctx, store.ThreadQuery{
+		Metadata:      map[string]any{"user_id": "alice"},
+		UpdatedBefore: before,
+		Limit:         10,
+		Offset:        20,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, threads, 1)
+	assert.Equal(t, "alice-1", threads[0].ID)
+	assert.Equal(t, map[string]any{"user_id": "alice"}, threads[0].Metadata)
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM checkpoints_threads ORDER BY updated_at DESC, thread_id ASC LIMIT $1 OFFSET $2")).
+		WithArgs(nil, 0).
+		WillReturnRows(pgxmock.NewRows(columns))
+	threads, err = s.ListThreads(ctx, store.ThreadQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, threads)
+
+	// Keys set to nil are removed from the metadata
+	mock.ExpectQuery(regexp.QuoteMeta("metadata = (checkpoints_threads.metadata || EXCLUDED.metadata) - $4::text[]")).
+		WithArgs("alice-1", []byte(`{"status":"done"}`), pgxmock.AnyArg(), []string{"title"}).
+		WillReturnRows(pgxmock.NewRows(columns).
+			AddRow("alice-1", []byte(`{"user_id":"alice","status":"done"}`), now.Add(-3*time.Hour), now))
+	thread, err := s.UpdateThread(ctx, "alice-1", map[string]any{"status": "done", "title": nil})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"user_id": "alice", "status": "done"}, thread.Metadata)
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM checkpoints_threads WHERE thread_id = $1")).
+		WithArgs("unknown").
+		WillReturnError(pgx.ErrNoRows)
+	_, err = s.GetThread(ctx, "unknown")
+	assert.ErrorIs(t, err, store.ErrThreadNotFound)
+
+	// The checkpoints of the thread and its subgraphs are deleted with it
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM checkpoints WHERE thread_id = $1 OR metadata->>'parent_thread_id' = $1")).
+		WithArgs("alice-1").
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM checkpoints_threads WHERE thread_id = $1")).
+		WithArgs("alice-1").
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectCommit()
+	assert.NoError(t, s.DeleteThread(ctx, "alice-1"))
+
+	// A failed step rolls the deletion back
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM checkpoints WHERE thread_id = $1 OR metadata->>'parent_thread_id' = $1")).
+		WithArgs("alice-2").
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM checkpoints_threads WHERE thread_id = $1")).
+		WithArgs("alice-2").
+		WillReturnError(errors.New("connection lost"))
+	mock.ExpectRollback()
+	assert.Error(t, s.DeleteThread(ctx, "alice-2"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresCheckpointStore_ListCheckpointIDs(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	store := NewPostgresCheckpointStoreWithPool(mock, "checkpoints")
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM checkpoints ORDER BY id")).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("cp-1").AddRow("cp-2"))
+
+	ids, err := store.ListCheckpointIDs(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cp-1", "cp-2"}, ids)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -145,6 +145,12 @@
 //	// List all checkpoints for a thread
 //	keys, err := store.client.Keys(ctx, "langgraph:thread:xyz789:checkpoint:*")
 //
+//	// Threads, scored by the Unix time in milliseconds they were last updated
+//	// Format: {prefix}threads
+//
+//	// Creation time and metadata of a thread, and its subgraph threads
+//	// Format: {prefix}thread_info:{thread_id} and {prefix}thread:{thread_id}:subthreads
+//
 // ## Custom TTL per Checkpoint
 //
 //	// Override default TTL for specific checkpoint
@@ -5,17 +5,25 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/smallnest/langgraphgo/graph"
+	"github.com/smallnest/langgraphgo/store"
+)
+
+var (
+	_ store.ThreadStore      = (*RedisCheckpointStore)(nil)
+	_ store.CheckpointLister = (*RedisCheckpointStore)(nil)
 )
 
 // RedisCheckpointStore implements graph.CheckpointStore using Redis
 type RedisCheckpointStore struct {
-	client *redis.Client
-	prefix string
-	ttl    time.Duration
+	client     *redis.Client
+	prefix     string
+	ttl        time.Duration
+	serializer store.Serializer
 }
 
 // RedisOptions configuration for Redis connection
@@ -25,6 +33,16 @@ type RedisOptions struct {
 	DB       int
 	Prefix   string        // Key prefix, default "langgraph:"
 	TTL      time.Duration // Expiration for checkpoints, default 0 (no expiration)
+
+	// Serializer of checkpoint states, default store.DefaultSerializer()
+	Serializer store.Serializer
+}
+
+// redisCheckpoint is the value stored for a checkpoint, with the state encoded
+// by the serializer of the store
+type redisCheckpoint struct {
+	graph.Checkpoint
+	State json.RawMessage `json:"state"`
 }
 
 // NewRedisCheckpointStore creates a new Redis checkpoint store
@@ -40,10 +58,16 @@ func NewRedisCheckpointStore(opts RedisOptions) *RedisCheckpointStore {
 		prefix = "langgraph:"
 	}
 
+	serializer := opts.Serializer
+	if serializer == nil {
+		serializer = store.DefaultSerializer()
+	}
+
 	return &RedisCheckpointStore{
-		client: client,
-		prefix: prefix,
-		ttl:    opts.TTL,
+		client:     client,
+		prefix:     prefix,
+		ttl:        opts.TTL,
+		serializer: serializer,
 	}
 }
 
@@ -59,9 +83,30 @@ func (s *RedisCheckpointStore) threadKey(id string) string {
 	return fmt.Sprintf("%sthread:%s:checkpoints", s.prefix, id)
 }
 
+// threadsKey is the sorted set of threads, scored by the Unix time in
+// milliseconds they were last updated
+func (s *RedisCheckpointStore) threadsKey() string {
+	return fmt.Sprintf("%sthreads", s.prefix)
+}
+
+// threadInfoKey is the hash holding the creation time and metadata of a thread
+func (s *RedisCheckpointStore) threadInfoKey(id string) string {
+	return fmt.Sprintf("%sthread_info:%s", s.prefix, id)
+}
+
+// subthreadsKey is the set of the subgraph threads of a thread
+func (s *RedisCheckpointStore) subthreadsKey(id string) string {
+	return fmt.Sprintf("%sthread:%s:subthreads", s.prefix, id)
+}
+
 // Save stores a checkpoint
 func (s *RedisCheckpointStore) Save(ctx context.Context, checkpoint *graph.Checkpoint) error {
-	data, err := json.Marshal(checkpoint)
+	state, err := store.MarshalState(s.serializer, checkpoint.State)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	data, err := json.Marshal(redisCheckpoint{Checkpoint: *checkpoint, State: state})
 	if err != nil {
 		return fmt.Errorf("failed to marshal checkpoint: %w", err)
 	}
@@ -89,6 +134,17 @@ func (s *RedisCheckpointStore) Save(ctx context.Context, checkpoint *graph.Check
 		}
 	}
 
+	// Record the checkpoint in its thread
+	if threadID := store.CheckpointThreadID(checkpoint); threadID != "" {
+		s.touchThread(ctx, pipe, threadID, checkpoint.Timestamp)
+		if subthread, _ := checkpoint.Metadata["thread_id"].(string); subthread != "" && subthread != threadID {
+			pipe.SAdd(ctx, s.subthreadsKey(threadID), subthread)
+			if s.ttl > 0 {
+				pipe.Expire(ctx, s.subthreadsKey(threadID), s.ttl)
+			}
+		}
+	}
+
 	_, err = pipe.Exec(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to save checkpoint to redis: %w", err)
@@ -108,12 +164,7 @@ func (s *RedisCheckpointStore) Load(ctx context.Context, checkpointID string) (*
 		return nil, fmt.Errorf("failed to load checkpoint from redis: %w", err)
 	}
 
-	var checkpoint graph.Checkpoint
-	if err := json.Unmarshal(data, &checkpoint); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal checkpoint: %w", err)
-	}
-
-	return &checkpoint, nil
+	return s.decodeCheckpoint(data)
 }
 
 // List returns all checkpoints for a given execution
@@ -152,12 +203,12 @@ func (s *RedisCheckpointStore) List(ctx context.Context, executionID string) ([]
 			continue
 		}
 
-		var checkpoint graph.Checkpoint
-		if err := json.Unmarshal([]byte(strData), &checkpoint); err != nil {
+		checkpoint, err := s.decodeCheckpoint([]byte(strData))
+		if err != nil {
 			// Log error or skip? Skipping for now
 			continue
 		}
-		checkpoints = append(checkpoints, &checkpoint)
+		checkpoints = append(checkpoints, checkpoint)
 
 		// Sanity check ID - should match if order is preserved
 		// If mismatch occurs, it indicates a Redis ordering issue
@@ -168,6 +219,20 @@ func (s *RedisCheckpointStore) List(ctx context.Context, executionID string) ([]
 	return checkpoints, nil
 }
 
+// ListCheckpointIDs returns the IDs of all checkpoints, scanning their keys
+func (s *RedisCheckpointStore) ListCheckpointIDs(ctx context.Context) ([]string, error) {
+	prefix := s.checkpointKey("")
+	var ids []string
+	iter := s.client.Scan(ctx, 0, prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		ids = append(ids, strings.TrimPrefix(iter.Val(), prefix))
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan checkpoint keys: %w", err)
+	}
+	return ids, nil
+}
+
 // ListByThread returns all checkpoints for a specific thread_id
 func (s *RedisCheckpointStore) ListByThread(ctx context.Context, threadID string) ([]*graph.Checkpoint, error) {
 	threadKey := s.threadKey(threadID)
@@ -202,11 +267,11 @@ func (s *RedisCheckpointStore) ListByThread(ctx context.Context, threadID string
 			continue
 		}
 
-		var checkpoint graph.Checkpoint
-		if err := json.Unmarshal([]byte(strData), &checkpoint); err != nil {
+		checkpoint, err := s.decodeCheckpoint([]byte(strData))
+		if err != nil {
 			continue
 		}
-		checkpoints = append(checkpoints, &checkpoint)
+		checkpoints = append(checkpoints, checkpoint)
 	}
 
 	return checkpoints, nil
@@ -236,12 +301,7 @@ func (s *RedisCheckpointStore) GetLatestByThread(ctx context.Context, threadID s
 		return nil, fmt.Errorf("failed to load checkpoint %s: %w", latestCheckpointID, err)
 	}
 
-	var checkpoint graph.Checkpoint
-	if err := json.Unmarshal([]byte(data), &checkpoint); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal checkpoint: %w", err)
-	}
-
-	return &checkpoint, nil
+	return s.decodeCheckpoint([]byte(data))
 }
 
 // Delete removes a checkpoint
@@ -304,3 +364,212 @@ func (s *RedisCheckpointStore) Clear(ctx context.Context, executionID string) er
 
 	return nil
 }
+
+// decodeCheckpoint decodes a stored checkpoint
+func (s *RedisCheckpointStore) decodeCheckpoint(data []byte) (*graph.Checkpoint, error) {
+	var stored redisCheckpoint
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal checkpoint: %w", err)
+	}
+
+	checkpoint := stored.Checkpoint
+	if len(stored.State) > 0 {
+		state, err := store.UnmarshalState(s.serializer, stored.State)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal state: %w", err)
+		}
+		checkpoint.State = state
+	}
+
+	return &checkpoint, nil
+}
+
+// ListThreads implements store.ThreadStore. Threads whose keys expired are
+// removed from the list of threads.
+func (s *RedisCheckpointStore) ListThreads(ctx context.Context, query store.ThreadQuery) ([]*store.Thread, error) {
+	rangeBy := &redis.ZRangeBy{Min: "-inf", Max: "+inf"}
+	if !query.UpdatedBefore.IsZero() {
+		rangeBy.Max = fmt.Sprintf("(%d", query.UpdatedBefore.UnixMilli())
+	}
+	// Without a metadata filter, the page is selected by Redis
+	if len(query.Metadata) == 0 {
+		if query.Offset > 0 || query.Limit > 0 {
+			rangeBy.Offset = int64(query.Offset)
+			rangeBy.Count = -1
+			if query.Limit > 0 {
+				rangeBy.Count = int64(query.Limit)
+			}
+		}
+		query.Offset, query.Limit = 0, 0
+	}
+
+	entries, err := s.client.ZRevRangeByScoreWithScores(ctx, s.threadsKey(), rangeBy).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list threads: %w", err)
+	}
+	if len(entries) == 0 {
+		return []*store.Thread{}, nil
+	}
+
+	pipe := s.client.Pipeline()
+	infos := make([]*redis.MapStringStringCmd, len(entries))
+	for i, entry := range entries {
+		infos[i] = pipe.HGetAll(ctx, s.threadInfoKey(entry.Member.(string)))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to fetch threads: %w", err)
+	}
+
+	threads := make([]*store.Thread, 0, len(entries))
+	var expired []any
+	for i, entry := range entries {
+		info := infos[i].Val()
+		if len(info) == 0 {
+			expired = append(expired, entry.Member)
+			continue
+		}
+		thread, err := decodeThread(entry.Member.(string), entry.Score, info)
+		if err != nil {
+			return nil, err
+		}
+		threads = append(threads, thread)
+	}
+	if len(expired) > 0 {
+		s.client.ZRem(ctx, s.threadsKey(), expired...)
+	}
+
+	return query.Apply(threads), nil
+}
+
+// GetThread implements store.ThreadStore
+func (s *RedisCheckpointStore) GetThread(ctx context.Context, threadID string) (*store.Thread, error) {
+	return s.getThread(ctx, s.client, threadID)
+}
+
+// UpdateThread implements store.ThreadStore
+func (s *RedisCheckpointStore) UpdateThread(ctx context.Context, threadID string, metadata map[string]any) (*store.Thread, error) {
+	var thread *store.Thread
+	update := func(tx *redis.Tx) error {
+		var err error
+		thread, err = s.getThread(ctx, tx, threadID)
+		if errors.Is(err, store.ErrThreadNotFound) {
+			thread = &store.Thread{ID: threadID}
+		} else if err != nil {
+			return err
+		}
+		thread.MergeMetadata(metadata, time.Now())
+
+		metadataJSON, err := json.Marshal(thread.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to marshal thread metadata: %w", err)
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			s.touchThread(ctx, pipe, threadID, thread.UpdatedAt)
+			pipe.HSet(ctx, s.threadInfoKey(threadID), "metadata", metadataJSON)
+			return nil
+		})
+		return err
+	}
+
+	// Retry when the thread changed while it was updated
+	for range 10 {
+		err := s.client.Watch(ctx, update, s.threadInfoKey(threadID))
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to update thread: %w", err)
+		}
+		return thread.Clone(), nil
+	}
+	return nil, fmt.Errorf("failed to update thread %s: too many concurrent updates", threadID)
+}
+
+// DeleteThread implements store.ThreadStore
+func (s *RedisCheckpointStore) DeleteThread(ctx context.Context, threadID string) error {
+	subthreads, err := s.client.SMembers(ctx, s.subthreadsKey(threadID)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to list subgraph threads: %w", err)
+	}
+
+	pipe := s.client.Pipeline()
+	for _, id := range append([]string{threadID}, subthreads...) {
+		threadKey := s.threadKey(id)
+		checkpointIDs, err := s.client.ZRange(ctx, threadKey, 0, -1).Result()
+		if err != nil {
+			return fmt.Errorf("failed to list checkpoints for thread %s: %w", id, err)
+		}
+		for _, checkpointID := range checkpointIDs {
+			key := s.checkpointKey(checkpointID)
+			// The state is not needed to update the execution index
+			data, err := s.client.Get(ctx, key).Bytes()
+			if err == nil {
+				var stored redisCheckpoint
+				if json.Unmarshal(data, &stored) == nil {
+					if execID, ok := stored.Metadata["execution_id"].(string); ok && execID != "" {
+						pipe.ZRem(ctx, s.executionKey(execID), checkpointID)
+					}
+				}
+			} else if err != redis.Nil {
+				return fmt.Errorf("failed to load checkpoint %s: %w", checkpointID, err)
+			}
+			pipe.Del(ctx, key)
+		}
+		pipe.Del(ctx, threadKey)
+	}
+	pipe.Del(ctx, s.threadInfoKey(threadID), s.subthreadsKey(threadID))
+	pipe.ZRem(ctx, s.threadsKey(), threadID)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete thread: %w", err)
+	}
+	return nil
+}
+
+// touchThread records in pipe that the thread was updated at updatedAt
+func (s *RedisCheckpointStore) touchThread(ctx context.Context, pipe redis.Pipeliner, threadID string, updatedAt time.Time) {
+	infoKey := s.threadInfoKey(threadID)
+	pipe.HSetNX(ctx, infoKey, "created_at", updatedAt.Format(time.RFC3339Nano))
+	pipe.ZAddGT(ctx, s.threadsKey(), redis.Z{Score: float64(updatedAt.UnixMilli()), Member: threadID})
+	if s.ttl > 0 {
+		pipe.Expire(ctx, infoKey, s.ttl)
+	}
+}
+
+// getThread loads a thread with client, which may be a transaction
+func (s *RedisCheckpointStore) getThread(ctx context.Context, client redis.Cmdable, threadID string) (*store.Thread, error) {
+	info, err := client.HGetAll(ctx, s.threadInfoKey(threadID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load thread: %w", err)
+	}
+	if len(info) == 0 {
+		return nil, fmt.Errorf("%w: %s", store.ErrThreadNotFound, threadID)
+	}
+	score, err := client.ZScore(ctx, s.threadsKey(), threadID).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to load thread: %w", err)
+	}
+	return decodeThread(threadID, score, info)
+}
+
+// decodeThread decodes a thread from its hash and its score in the list of threads
+func decodeThread(threadID string, score float64, info map[string]string) (*store.Thread, error) {
+	thread := &store.Thread{
+		ID:        threadID,
+		Metadata:  map[string]any{},
+		UpdatedAt: time.UnixMilli(int64(score)),
+	}
+	if createdAt, ok := info["created_at"]; ok {
+		t, err := time.Parse(time.RFC3339Nano, createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid creation time of thread %s: %w", threadID, err)
+		}
+		thread.CreatedAt = t
+	}
+	if metadata, ok := info["metadata"]; ok {
+		if err := json.Unmarshal([]byte(metadata), &thread.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal metadata of thread %s: %w", threadID, err)
+		}
+	}
+	return thread, nil
+}
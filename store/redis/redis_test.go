@@ -2,12 +2,15 @@ package redis
 
 import (
 	"context"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/alicebob/miniredis/v2"
 	"github.com/smallnest/langgraphgo/graph"
+	"github.com/smallnest/langgraphgo/store"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRedisCheckpointStore(t *testing.T) {
@@ -86,3 +89,180 @@ func TestRedisCheckpointStore(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, list, 0)
 }
+
+type typedState struct {
+	Query   string   `json:"query"`
+	Results []string `json:"results"`
+}
+
+func TestRedisCheckpointStore_TypedState(t *testing.T) {
+	mr, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer mr.Close()
+
+	registry := store.NewTypeRegistry()
+	assert.NoError(t, registry.RegisterTypeInternal(reflect.TypeOf(&typedState{}), "typedState"))
+
+	s := NewRedisCheckpointStore(RedisOptions{
+		Addr:       mr.Addr(),
+		Serializer: store.NewGobSerializer(registry),
+	})
+
+	ctx := context.Background()
+	state := &typedState{Query: "go", Results: []string{"a", "b"}}
+	err = s.Save(ctx, &graph.Checkpoint{
+		ID:        "cp-1",
+		State:     state,
+		Timestamp: time.Now(),
+		Version:   1,
+		Metadata:  map[string]any{"thread_id": "thread-1"},
+	})
+	assert.NoError(t, err)
+
+	loaded, err := s.Load(ctx, "cp-1")
+	assert.NoError(t, err)
+	assert.Equal(t, state, loaded.State)
+
+	list, err := s.ListByThread(ctx, "thread-1")
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, state, list[0].State)
+
+	assert.NoError(t, s.Delete(ctx, "cp-1"))
+}
+
+func TestRedisCheckpointStore_Threads(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	s := NewRedisCheckpointStore(RedisOptions{Addr: mr.Addr()})
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Millisecond)
+	save := func(id, threadID string, age time.Duration, extra map[string]any) {
+		metadata := map[string]any{"thread_id": threadID, "execution_id": "exec-" + threadID}
+		for k, v := range extra {
+			metadata[k] = v
+		}
+		require.NoError(t, s.Save(ctx, &graph.Checkpoint{ID: id, State: "state", Timestamp: now.Add(-age), Metadata: metadata}))
+	}
+	save("cp-1", "alice-1", 3*time.Hour, nil)
+	save("cp-2", "bob-1", 2*time.Hour, nil)
+	save("cp-3", "carol-1", 48*time.Hour, nil)
+	save("cp-4", "alice-1|agent", time.Hour, map[string]any{"parent_thread_id": "alice-1"})
+
+	ids := func(query store.ThreadQuery) []string {
+		threads, err := s.ListThreads(ctx, query)
+		require.NoError(t, err)
+		ids := []string{}
+		for _, thread := range threads {
+			ids = append(ids, thread.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"alice-1", "bob-1", "carol-1"}, ids(store.ThreadQuery{}))
+	assert.Equal(t, []string{"bob-1", "carol-1"}, ids(store.ThreadQuery{Limit: 2, Offset: 1}))
+	assert.Equal(t, []string{"carol-1"}, ids(store.ThreadQuery{Offset: 2}))
+
+	thread, err := s.GetThread(ctx, "alice-1")
+	require.NoError(t, err)
+	assert.True(t, thread.CreatedAt.Equal(now.Add(-3*time.Hour)), thread.CreatedAt)
+	assert.True(t, thread.UpdatedAt.Equal(now.Add(-time.Hour)), thread.UpdatedAt)
+
+	_, err = s.UpdateThread(ctx, "bob-1", map[string]any{"user_id": "bob", "title": "Trip"})
+	require.NoError(t, err)
+	thread, err = s.UpdateThread(ctx, "bob-1", map[string]any{"title": nil})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"user_id": "bob"}, thread.Metadata)
+	assert.Equal(t, []string{"bob-1"}, ids(store.ThreadQuery{Metadata: map[string]any{"user_id": "bob"}}))
+
+	_, err = s.GetThread(ctx, "unknown")
+	assert.ErrorIs(t, err, store.ErrThreadNotFound)
+
+	deleted, err := store.DeleteExpiredThreads(ctx, s, 24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	require.NoError(t, s.DeleteThread(ctx, "alice-1"))
+	for _, id := range []string{"cp-1", "cp-3", "cp-4"} {
+		_, err := s.Load(ctx, id)
+		assert.Error(t, err, id)
+	}
+	checkpoints, err := s.List(ctx, "exec-alice-1")
+	require.NoError(t, err)
+	assert.Empty(t, checkpoints)
+	assert.Equal(t, []string{"bob-1"}, ids(store.ThreadQuery{}))
+}
+
+func TestRedisCheckpointStore_ThreadsExpire(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	s := NewRedisCheckpointStore(RedisOptions{Addr: mr.Addr(), TTL: time.Hour})
+	ctx := context.Background()
+	require.NoError(t, s.Save(ctx, &graph.Checkpoint{
+		ID:        "cp-1",
+		State:     "state",
+		Timestamp: time.Now(),
+		Metadata:  map[string]any{"thread_id": "thread-1"},
+	}))
+
+	threads, err := s.ListThreads(ctx, store.ThreadQuery{})
+	require.NoError(t, err)
+	assert.Len(t, threads, 1)
+
+	// Threads expire with their checkpoints
+	mr.FastForward(2 * time.Hour)
+	threads, err = s.ListThreads(ctx, store.ThreadQuery{})
+	require.NoError(t, err)
+	assert.Empty(t, threads)
+	assert.False(t, mr.Exists("langgraph:threads"))
+}
+
+func TestRedisCheckpointStore_ListCheckpointIDs(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	s := NewRedisCheckpointStore(RedisOptions{Addr: mr.Addr()})
+	ctx := context.Background()
+	for _, cp := range []*graph.Checkpoint{
+		{ID: "cp-1", State: "state", Metadata: map[string]any{"thread_id": "thread-1"}},
+		{ID: "cp-2", State: "state", Metadata: map[string]any{"thread_id": "thread-1|sub", "parent_thread_id": "thread-1"}},
+		{ID: "cp-3", State: "state", Metadata: map[string]any{"execution_id": "exec-1"}},
+	} {
+		require.NoError(t, s.Save(ctx, cp))
+	}
+
+	ids, err := s.ListCheckpointIDs(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"cp-1", "cp-2", "cp-3"}, ids)
+}
+
+func TestRedisCheckpointStore_ThreadKeysDoNotCollide(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	s := NewRedisCheckpointStore(RedisOptions{Addr: mr.Addr()})
+	ctx := context.Background()
+
+	// The info of thread "a:checkpoints" must not land on the checkpoints of thread "a"
+	for _, threadID := range []string{"a", "a:checkpoints", "a:subthreads"} {
+		require.NoError(t, s.Save(ctx, &graph.Checkpoint{
+			ID:        "cp-" + threadID,
+			State:     "state",
+			Timestamp: time.Now(),
+			Metadata:  map[string]any{"thread_id": threadID},
+		}))
+		thread, err := s.UpdateThread(ctx, threadID, map[string]any{"name": threadID})
+		require.NoError(t, err, threadID)
+		assert.Equal(t, map[string]any{"name": threadID}, thread.Metadata)
+	}
+
+	checkpoint, err := s.GetLatestByThread(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "cp-a", checkpoint.ID)
+}
@@ -0,0 +1,276 @@
+package store
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Serializer converts checkpoint states to bytes and back.
+//
+// Stores use a Serializer for the State of the checkpoints they persist, so
+// that a state saved as a registered type is loaded as that type again.
+type Serializer interface {
+	// Marshal encodes a state
+	Marshal(state any) ([]byte, error)
+
+	// Unmarshal decodes a state encoded by Marshal
+	Unmarshal(data []byte) (any, error)
+}
+
+func init() {
+	// Values of unregistered states are gob encoded as interfaces, which
+	// requires their concrete types to be registered
+	gob.Register(map[string]any{})
+	gob.Register([]any{})
+}
+
+// DefaultSerializer returns the serializer stores use when none is configured:
+// typed JSON with the global type registry.
+func DefaultSerializer() Serializer {
+	return NewJSONSerializer(nil)
+}
+
+// NewJSONSerializer creates a serializer that encodes states as JSON. States of
+// types registered with registry are wrapped with their type name and decoded
+// as that type; other states are decoded as plain JSON values (maps, slices,
+// strings, float64...). A nil registry uses the global type registry.
+func NewJSONSerializer(registry *TypeRegistry) Serializer {
+	return &jsonSerializer{registry: registryOrGlobal(registry)}
+}
+
+// NewGobSerializer creates a serializer that encodes states with encoding/gob.
+// States of types registered with registry are decoded as that type; other
+// states are encoded as interface values, so their concrete types must be
+// registered with gob.Register. A nil registry uses the global type registry.
+func NewGobSerializer(registry *TypeRegistry) Serializer {
+	return &binarySerializer{
+		registry: registryOrGlobal(registry),
+		marshal: func(v any) ([]byte, error) {
+			var buf bytes.Buffer
+			if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+				return nil, err
+			}
+			return buf.Bytes(), nil
+		},
+		unmarshal: func(data []byte, v any) error {
+			return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+		},
+		marshalAny: func(v any) ([]byte, error) {
+			var buf bytes.Buffer
+			if err := gob.NewEncoder(&buf).Encode(&v); err != nil {
+				return nil, err
+			}
+			return buf.Bytes(), nil
+		},
+		// gob only decodes structs into their type, so the JSON form of
+		// outdated states lacks the fields that type no longer has
+		unmarshalMap: func(t reflect.Type, data []byte) (map[string]any, error) {
+			value, err := decodeValue(t, func(ptr any) error {
+				return gob.NewDecoder(bytes.NewReader(data)).Decode(ptr)
+			})
+			if err != nil {
+				return nil, err
+			}
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			var state map[string]any
+			err = json.Unmarshal(encoded, &state)
+			return state, err
+		},
+	}
+}
+
+// NewMsgpackSerializer creates a serializer that encodes states with
+// MessagePack, naming struct fields after their json tags. States of types
+// registered with registry are decoded as that type; other states are decoded
+// as plain values (map[string]any, []any...). A nil registry uses the global
+// type registry.
+func NewMsgpackSerializer(registry *TypeRegistry) Serializer {
+	marshal := func(v any) ([]byte, error) {
+		var buf bytes.Buffer
+		enc := msgpack.NewEncoder(&buf)
+		enc.SetCustomStructTag("json")
+		if err := enc.Encode(v); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	unmarshal := func(data []byte, v any) error {
+		dec := msgpack.NewDecoder(bytes.NewReader(data))
+		dec.SetCustomStructTag("json")
+		return dec.Decode(v)
+	}
+	return &binarySerializer{
+		registry:   registryOrGlobal(registry),
+		marshal:    marshal,
+		unmarshal:  unmarshal,
+		marshalAny: marshal,
+		unmarshalMap: func(_ reflect.Type, data []byte) (map[string]any, error) {
+			var state map[string]any
+			err := unmarshal(data, &state)
+			return state, err
+		},
+	}
+}
+
+func registryOrGlobal(registry *TypeRegistry) *TypeRegistry {
+	if registry == nil {
+		return GlobalTypeRegistry()
+	}
+	return registry
+}
+
+// jsonSerializer encodes states with the typed JSON of a TypeRegistry
+type jsonSerializer struct {
+	registry *TypeRegistry
+}
+
+func (s *jsonSerializer) Marshal(state any) ([]byte, error) {
+	return s.registry.Marshal(state)
+}
+
+func (s *jsonSerializer) Unmarshal(data []byte) (any, error) {
+	return s.registry.Unmarshal(data)
+}
+
+// typedData is the envelope of binary encoded states
+type typedData struct {
+	Type    string `json:"type"`
+	Version int    `json:"version,omitempty"`
+	Data    []byte `json:"data"`
+}
+
+// binarySerializer wraps a binary encoding with the type names and schema
+// versions of a TypeRegistry. The envelope is encoded with the same encoding.
+type binarySerializer struct {
+	registry   *TypeRegistry
+	marshal    func(any) ([]byte, error)
+	unmarshal  func([]byte, any) error
+	marshalAny func(any) ([]byte, error)
+
+	// unmarshalMap decodes the JSON form of a state of type t, to migrate it
+	unmarshalMap func(t reflect.Type, data []byte) (map[string]any, error)
+}
+
+func (s *binarySerializer) Marshal(state any) ([]byte, error) {
+	var envelope typedData
+	if state != nil {
+		var err error
+		if name, ok := s.registry.GetTypeName(reflect.TypeOf(state)); ok {
+			envelope.Type = name
+			envelope.Version = s.registry.SchemaVersion(name)
+			envelope.Data, err = s.marshal(state)
+		} else {
+			envelope.Data, err = s.marshalAny(state)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode state: %w", err)
+		}
+	}
+	return s.marshal(envelope)
+}
+
+func (s *binarySerializer) Unmarshal(data []byte) (any, error) {
+	var envelope typedData
+	if err := s.unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode state: %w", err)
+	}
+	if len(envelope.Data) == 0 {
+		return nil, nil
+	}
+
+	if envelope.Type == "" {
+		var state any
+		if err := s.unmarshal(envelope.Data, &state); err != nil {
+			return nil, fmt.Errorf("failed to decode state: %w", err)
+		}
+		return state, nil
+	}
+
+	t, ok := s.registry.GetTypeByName(envelope.Type)
+	if !ok {
+		return nil, fmt.Errorf("unknown type: %s", envelope.Type)
+	}
+
+	// States saved with an older schema version are migrated first
+	if version := max(envelope.Version, 1); version < s.registry.SchemaVersion(envelope.Type) {
+		value, err := s.unmarshalMap(t, envelope.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode state: %w", err)
+		}
+		return s.registry.migrateValue(envelope.Type, version, value)
+	}
+
+	state, err := decodeValue(t, func(ptr any) error {
+		return s.unmarshal(envelope.Data, ptr)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode state: %w", err)
+	}
+	return state, nil
+}
+
+// decodeValue returns a value of type t filled by decode, which receives a
+// pointer to decode into.
+func decodeValue(t reflect.Type, decode func(ptr any) error) (any, error) {
+	if t.Kind() == reflect.Ptr {
+		ptr := reflect.New(t.Elem())
+		if err := decode(ptr.Interface()); err != nil {
+			return nil, err
+		}
+		return ptr.Interface(), nil
+	}
+
+	ptr := reflect.New(t)
+	if err := decode(ptr.Interface()); err != nil {
+		return nil, err
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+// encodedState holds the output of a binary serializer in a JSON document
+type encodedState struct {
+	Bytes []byte `json:"_bytes"`
+}
+
+// MarshalState encodes a state with serializer for stores that keep states in
+// JSON documents or columns. The output of binary serializers is kept as
+// base64. A nil serializer uses DefaultSerializer.
+func MarshalState(serializer Serializer, state any) ([]byte, error) {
+	if serializer == nil {
+		serializer = DefaultSerializer()
+	}
+	data, err := serializer.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	if json.Valid(data) {
+		return data, nil
+	}
+	return json.Marshal(encodedState{Bytes: data})
+}
+
+// UnmarshalState decodes a state encoded by MarshalState.
+func UnmarshalState(serializer Serializer, data []byte) (any, error) {
+	if serializer == nil {
+		serializer = DefaultSerializer()
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err == nil && len(fields) == 1 {
+		if _, ok := fields["_bytes"]; ok {
+			var encoded encodedState
+			if err := json.Unmarshal(data, &encoded); err != nil {
+				return nil, err
+			}
+			return serializer.Unmarshal(encoded.Bytes)
+		}
+	}
+	return serializer.Unmarshal(data)
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSerializers_RoundTrip(t *testing.T) {
+	registry := newTestRegistry()
+	require.NoError(t, registry.RegisterTypeInternal(reflect.TypeOf(TestState{}), "TestState"))
+	require.NoError(t, registry.RegisterTypeInternal(reflect.TypeOf(&AnotherState{}), "AnotherState"))
+
+	serializers := map[string]Serializer{
+		"json":    NewJSONSerializer(registry),
+		"gob":     NewGobSerializer(registry),
+		"msgpack": NewMsgpackSerializer(registry),
+	}
+
+	for name, serializer := range serializers {
+		t.Run(name, func(t *testing.T) {
+			// Registered types come back as their type
+			data, err := serializer.Marshal(TestState{Name: "typed", Count: 3})
+			require.NoError(t, err)
+			state, err := serializer.Unmarshal(data)
+			require.NoError(t, err)
+			assert.Equal(t, TestState{Name: "typed", Count: 3}, state)
+
+			data, err = serializer.Marshal(&AnotherState{ID: 7, Value: "pointer"})
+			require.NoError(t, err)
+			state, err = serializer.Unmarshal(data)
+			require.NoError(t, err)
+			assert.Equal(t, &AnotherState{ID: 7, Value: "pointer"}, state)
+
+			// Other states come back as plain values
+			data, err = serializer.Marshal(map[string]any{"foo": "bar"})
+			require.NoError(t, err)
+			state, err = serializer.Unmarshal(data)
+			require.NoError(t, err)
+			assert.Equal(t, map[string]any{"foo": "bar"}, state)
+
+			data, err = serializer.Marshal(nil)
+			require.NoError(t, err)
+			state, err = serializer.Unmarshal(data)
+			require.NoError(t, err)
+			assert.Nil(t, state)
+		})
+	}
+}
+
+func TestSerializers_UnknownType(t *testing.T) {
+	registry := newTestRegistry()
+	require.NoError(t, registry.RegisterTypeInternal(reflect.TypeOf(TestState{}), "TestState"))
+
+	data, err := NewMsgpackSerializer(registry).Marshal(TestState{Name: "typed"})
+	require.NoError(t, err)
+
+	_, err = NewMsgpackSerializer(newTestRegistry()).Unmarshal(data)
+	assert.ErrorContains(t, err, "unknown type: TestState")
+}
+
+func TestMarshalState(t *testing.T) {
+	registry := newTestRegistry()
+	require.NoError(t, registry.RegisterTypeInternal(reflect.TypeOf(TestState{}), "TestState"))
+
+	// JSON serializers keep the state as it is
+	data, err := MarshalState(NewJSONSerializer(registry), TestState{Name: "typed", Count: 1})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"_type":"TestState","_value":{"name":"typed","count":1}}`, string(data))
+
+	// Binary serializers are wrapped in a JSON document
+	gobSerializer := NewGobSerializer(registry)
+	data, err = MarshalState(gobSerializer, TestState{Name: "typed", Count: 1})
+	require.NoError(t, err)
+	assert.True(t, json.Valid(data))
+	assert.Contains(t, string(data), `"_bytes"`)
+
+	state, err := UnmarshalState(gobSerializer, data)
+	require.NoError(t, err)
+	assert.Equal(t, TestState{Name: "typed", Count: 1}, state)
+
+	// A nil serializer uses the default one
+	data, err = MarshalState(nil, map[string]any{"foo": "bar"})
+	require.NoError(t, err)
+	state, err = UnmarshalState(nil, data)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"foo": "bar"}, state)
+}
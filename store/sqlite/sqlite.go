@@ -4,23 +4,36 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"sort"
+	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/smallnest/langgraphgo/graph"
+	"github.com/smallnest/langgraphgo/store"
+)
+
+var (
+	_ store.ThreadStore      = (*SqliteCheckpointStore)(nil)
+	_ store.CheckpointLister = (*SqliteCheckpointStore)(nil)
 )
 
 // SqliteCheckpointStore implements graph.CheckpointStore using SQLite
 type SqliteCheckpointStore struct {
-	db        *sql.DB
-	tableName string
+	db         *sql.DB
+	tableName  string
+	serializer store.Serializer
 }
 
 // SqliteOptions configuration for SQLite connection
 type SqliteOptions struct {
-	Path      string
-	TableName string // Default "checkpoints"
+	Path       string
+	TableName  string           // Default "checkpoints", threads are kept in "<TableName>_threads"
+	Serializer store.Serializer // Serializer of checkpoint states, default store.DefaultSerializer()
 }
 
 // NewSqliteCheckpointStore creates a new SQLite checkpoint store
@@ -35,21 +48,36 @@ func NewSqliteCheckpointStore(opts SqliteOptions) (*SqliteCheckpointStore, error
 		tableName = "checkpoints"
 	}
 
-	store := &SqliteCheckpointStore{
-		db:        db,
-		tableName: tableName,
+	serializer := opts.Serializer
+	if serializer == nil {
+		serializer = store.DefaultSerializer()
+	}
+
+	s := &SqliteCheckpointStore{
+		db:         db,
+		tableName:  tableName,
+		serializer: serializer,
 	}
 
-	if err := store.InitSchema(context.Background()); err != nil {
+	if err := s.InitSchema(context.Background()); err != nil {
 		db.Close()
 		return nil, err
 	}
 
-	return store, nil
+	return s, nil
 }
 
-// InitSchema creates the necessary table if it doesn't exist
+// InitSchema creates the necessary tables if they don't exist. The threads of
+// the checkpoints saved before the threads table existed are recorded in it.
 func (s *SqliteCheckpointStore) InitSchema(ctx context.Context) error {
+	var count int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?",
+		s.threadsTable()).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to check schema: %w", err)
+	}
+	hasThreads := count > 0
+
 	query := fmt.Sprintf(`
 		CREATE TABLE IF NOT EXISTS %s (
 			id TEXT PRIMARY KEY,
@@ -63,15 +91,42 @@ func (s *SqliteCheckpointStore) InitSchema(ctx context.Context) error {
 		);
 		CREATE INDEX IF NOT EXISTS idx_%s_execution_id ON %s (execution_id);
 		CREATE INDEX IF NOT EXISTS idx_%s_thread_id ON %s (thread_id);
-	`, s.tableName, s.tableName, s.tableName, s.tableName, s.tableName)
+		CREATE TABLE IF NOT EXISTS %s (
+			thread_id TEXT PRIMARY KEY,
+			metadata TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_%s_updated_at ON %s (updated_at);
+	`, s.tableName, s.tableName, s.tableName, s.tableName, s.tableName,
+		s.threadsTable(), s.threadsTable(), s.threadsTable())
 
-	_, err := s.db.ExecContext(ctx, query)
+	_, err = s.db.ExecContext(ctx, query)
 	if err != nil {
 		return fmt.Errorf("failed to create schema: %w", err)
 	}
+
+	if !hasThreads {
+		// nolint:gosec // G201: Table name cannot be parameterized
+		query = fmt.Sprintf(`
+			INSERT OR IGNORE INTO %s (thread_id, metadata, created_at, updated_at)
+			SELECT COALESCE(json_extract(metadata, '$.parent_thread_id'), thread_id), '{}', MIN(timestamp), MAX(timestamp)
+			FROM %s
+			WHERE thread_id != ''
+			GROUP BY 1
+		`, s.threadsTable(), s.tableName)
+		if _, err := s.db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to record threads: %w", err)
+		}
+	}
 	return nil
 }
 
+// threadsTable is the table of threads
+func (s *SqliteCheckpointStore) threadsTable() string {
+	return s.tableName + "_threads"
+}
+
 // Close closes the database connection
 func (s *SqliteCheckpointStore) Close() error {
 	return s.db.Close()
@@ -79,7 +134,7 @@ func (s *SqliteCheckpointStore) Close() error {
 
 // Save stores a checkpoint
 func (s *SqliteCheckpointStore) Save(ctx context.Context, checkpoint *graph.Checkpoint) error {
-	stateJSON, err := json.Marshal(checkpoint.State)
+	stateJSON, err := store.MarshalState(s.serializer, checkpoint.State)
 	if err != nil {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
@@ -113,7 +168,14 @@ func (s *SqliteCheckpointStore) Save(ctx context.Context, checkpoint *graph.Chec
 			version = excluded.version
 	`, s.tableName)
 
-	_, err = s.db.ExecContext(ctx, query,
+	// The checkpoint and its thread are saved together
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, query,
 		checkpoint.ID,
 		executionID,
 		threadID,
@@ -128,6 +190,25 @@ func (s *SqliteCheckpointStore) Save(ctx context.Context, checkpoint *graph.Chec
 		return fmt.Errorf("failed to save checkpoint: %w", err)
 	}
 
+	// Record the checkpoint in its thread
+	if threadID := store.CheckpointThreadID(checkpoint); threadID != "" {
+		// nolint:gosec // G201: Table name cannot be parameterized, but all values use parameterized queries
+		query = fmt.Sprintf(`
+			INSERT INTO %s (thread_id, metadata, created_at, updated_at)
+			VALUES (?, '{}', ?, ?)
+			ON CONFLICT(thread_id) DO UPDATE SET
+				created_at = min(created_at, excluded.created_at),
+				updated_at = max(updated_at, excluded.updated_at)
+		`, s.threadsTable())
+		timestamp := checkpoint.Timestamp.UTC()
+		if _, err := tx.ExecContext(ctx, query, threadID, timestamp, timestamp); err != nil {
+			return fmt.Errorf("failed to update thread: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit checkpoint: %w", err)
+	}
 	return nil
 }
 
@@ -160,7 +241,7 @@ func (s *SqliteCheckpointStore) Load(ctx context.Context, checkpointID string) (
 		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
 	}
 
-	if err := json.Unmarshal([]byte(stateJSON), &cp.State); err != nil {
+	if err := s.unmarshalState(stateJSON, &cp); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
 	}
 
@@ -207,7 +288,7 @@ func (s *SqliteCheckpointStore) List(ctx context.Context, executionID string) ([
 			return nil, fmt.Errorf("failed to scan checkpoint row: %w", err)
 		}
 
-		if err := json.Unmarshal([]byte(stateJSON), &cp.State); err != nil {
+		if err := s.unmarshalState(stateJSON, &cp); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal state: %w", err)
 		}
 
@@ -227,6 +308,31 @@ func (s *SqliteCheckpointStore) List(ctx context.Context, executionID string) ([
 	return checkpoints, nil
 }
 
+// ListCheckpointIDs returns the IDs of all checkpoints
+func (s *SqliteCheckpointStore) ListCheckpointIDs(ctx context.Context) ([]string, error) {
+	// nolint:gosec // G201: Table name cannot be parameterized
+	query := fmt.Sprintf("SELECT id FROM %s ORDER BY id", s.tableName)
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan checkpoint row: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating checkpoint rows: %w", err)
+	}
+	return ids, nil
+}
+
 // Delete removes a checkpoint
 func (s *SqliteCheckpointStore) Delete(ctx context.Context, checkpointID string) error {
 	// nolint:gosec // G201: Table name cannot be parameterized, but all values use parameterized queries
@@ -283,7 +389,7 @@ func (s *SqliteCheckpointStore) ListByThread(ctx context.Context, threadID strin
 			return nil, fmt.Errorf("failed to scan checkpoint row: %w", err)
 		}
 
-		if err := json.Unmarshal([]byte(stateJSON), &cp.State); err != nil {
+		if err := s.unmarshalState(stateJSON, &cp); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal state: %w", err)
 		}
 
@@ -322,3 +428,180 @@ func (s *SqliteCheckpointStore) GetLatestByThread(ctx context.Context, threadID
 	// Return the last one (highest version due to sorting)
 	return checkpoints[len(checkpoints)-1], nil
 }
+
+// unmarshalState decodes a state column into the checkpoint
+func (s *SqliteCheckpointStore) unmarshalState(stateJSON string, cp *graph.Checkpoint) error {
+	state, err := store.UnmarshalState(s.serializer, []byte(stateJSON))
+	if err != nil {
+		return err
+	}
+	cp.State = state
+	return nil
+}
+
+// ListThreads implements store.ThreadStore
+func (s *SqliteCheckpointStore) ListThreads(ctx context.Context, query store.ThreadQuery) ([]*store.Thread, error) {
+	var conditions []string
+	var args []any
+	if !query.UpdatedBefore.IsZero() {
+		conditions = append(conditions, "updated_at < ?")
+		args = append(args, query.UpdatedBefore.UTC())
+	}
+	for _, key := range slices.Sorted(maps.Keys(query.Metadata)) {
+		value, err := json.Marshal(query.Metadata[key])
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal metadata filter: %w", err)
+		}
+		// Both sides are decoded from JSON, so that values compare as SQL values
+		conditions = append(conditions, "json_extract(metadata, ?) = json_extract(?, '$')")
+		args = append(args, fmt.Sprintf("$.%q", key), string(value))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	limit := -1
+	if query.Limit > 0 {
+		limit = query.Limit
+	}
+	args = append(args, limit, query.Offset)
+
+	// nolint:gosec // G201: Table name cannot be parameterized, but all values use parameterized queries
+	sqlQuery := fmt.Sprintf(`
+		SELECT thread_id, metadata, created_at, updated_at
+		FROM %s
+		%s
+		ORDER BY updated_at DESC, thread_id ASC
+		LIMIT ? OFFSET ?
+	`, s.threadsTable(), where)
+
+	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list threads: %w", err)
+	}
+	defer rows.Close()
+
+	threads := []*store.Thread{}
+	for rows.Next() {
+		thread, err := scanThread(rows)
+		if err != nil {
+			return nil, err
+		}
+		threads = append(threads, thread)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating thread rows: %w", err)
+	}
+
+	return threads, nil
+}
+
+// GetThread implements store.ThreadStore
+func (s *SqliteCheckpointStore) GetThread(ctx context.Context, threadID string) (*store.Thread, error) {
+	return s.getThread(ctx, s.db, threadID)
+}
+
+// UpdateThread implements store.ThreadStore
+func (s *SqliteCheckpointStore) UpdateThread(ctx context.Context, threadID string, metadata map[string]any) (*store.Thread, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	thread, err := s.getThread(ctx, tx, threadID)
+	if errors.Is(err, store.ErrThreadNotFound) {
+		thread = &store.Thread{ID: threadID}
+	} else if err != nil {
+		return nil, err
+	}
+	thread.MergeMetadata(metadata, time.Now().UTC())
+
+	metadataJSON, err := json.Marshal(thread.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal thread metadata: %w", err)
+	}
+
+	// nolint:gosec // G201: Table name cannot be parameterized, but all values use parameterized queries
+	query := fmt.Sprintf(`
+		INSERT INTO %s (thread_id, metadata, created_at, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(thread_id) DO UPDATE SET
+			metadata = excluded.metadata,
+			updated_at = excluded.updated_at
+	`, s.threadsTable())
+	if _, err := tx.ExecContext(ctx, query, threadID, string(metadataJSON), thread.CreatedAt, thread.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("failed to update thread: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit thread update: %w", err)
+	}
+	return thread.Clone(), nil
+}
+
+// DeleteThread implements store.ThreadStore
+func (s *SqliteCheckpointStore) DeleteThread(ctx context.Context, threadID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// nolint:gosec // G201: Table name cannot be parameterized, but all values use parameterized queries
+	query := fmt.Sprintf("DELETE FROM %s WHERE thread_id = ? OR json_extract(metadata, '$.parent_thread_id') = ?", s.tableName)
+	if _, err := tx.ExecContext(ctx, query, threadID, threadID); err != nil {
+		return fmt.Errorf("failed to delete checkpoints of thread: %w", err)
+	}
+
+	// nolint:gosec // G201: Table name cannot be parameterized, but all values use parameterized queries
+	query = fmt.Sprintf("DELETE FROM %s WHERE thread_id = ?", s.threadsTable())
+	if _, err := tx.ExecContext(ctx, query, threadID); err != nil {
+		return fmt.Errorf("failed to delete thread: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit thread deletion: %w", err)
+	}
+	return nil
+}
+
+// queryRower is implemented by *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (s *SqliteCheckpointStore) getThread(ctx context.Context, db queryRower, threadID string) (*store.Thread, error) {
+	// nolint:gosec // G201: Table name cannot be parameterized, but all values use parameterized queries
+	query := fmt.Sprintf(`
+		SELECT thread_id, metadata, created_at, updated_at
+		FROM %s
+		WHERE thread_id = ?
+	`, s.threadsTable())
+
+	thread, err := scanThread(db.QueryRowContext(ctx, query, threadID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", store.ErrThreadNotFound, threadID)
+	}
+	return thread, err
+}
+
+// scanThread scans a thread row
+func scanThread(row interface{ Scan(dest ...any) error }) (*store.Thread, error) {
+	var thread store.Thread
+	var metadataJSON string
+	if err := row.Scan(&thread.ID, &metadataJSON, &thread.CreatedAt, &thread.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan thread row: %w", err)
+	}
+	if err := json.Unmarshal([]byte(metadataJSON), &thread.Metadata); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal thread metadata: %w", err)
+	}
+	if thread.Metadata == nil {
+		thread.Metadata = map[string]any{}
+	}
+	return &thread, nil
+}
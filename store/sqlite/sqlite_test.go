@@ -2,11 +2,16 @@ package sqlite
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/smallnest/langgraphgo/graph"
+	"github.com/smallnest/langgraphgo/store"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSqliteCheckpointStore(t *testing.T) {
@@ -80,3 +85,154 @@ func TestSqliteCheckpointStore(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, list, 0)
 }
+
+type typedState struct {
+	Query   string   `json:"query"`
+	Results []string `json:"results"`
+}
+
+func TestSqliteCheckpointStore_TypedState(t *testing.T) {
+	registry := store.NewTypeRegistry()
+	assert.NoError(t, registry.RegisterTypeInternal(reflect.TypeOf(typedState{}), "typedState"))
+
+	for name, serializer := range map[string]store.Serializer{
+		"json":    store.NewJSONSerializer(registry),
+		"msgpack": store.NewMsgpackSerializer(registry),
+	} {
+		t.Run(name, func(t *testing.T) {
+			s, err := NewSqliteCheckpointStore(SqliteOptions{
+				Path:       ":memory:",
+				Serializer: serializer,
+			})
+			assert.NoError(t, err)
+			defer s.Close()
+
+			ctx := context.Background()
+			state := typedState{Query: "go", Results: []string{"a", "b"}}
+			err = s.Save(ctx, &graph.Checkpoint{
+				ID:        "cp-1",
+				State:     state,
+				Timestamp: time.Now(),
+				Version:   1,
+				Metadata:  map[string]any{"thread_id": "thread-1"},
+			})
+			assert.NoError(t, err)
+
+			loaded, err := s.GetLatestByThread(ctx, "thread-1")
+			assert.NoError(t, err)
+			assert.Equal(t, state, loaded.State)
+		})
+	}
+}
+
+func TestSqliteCheckpointStore_Threads(t *testing.T) {
+	s, err := NewSqliteCheckpointStore(SqliteOptions{Path: filepath.Join(t.TempDir(), "checkpoints.db")})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	save := func(id, threadID string, age time.Duration, extra map[string]any) {
+		metadata := map[string]any{"thread_id": threadID}
+		for k, v := range extra {
+			metadata[k] = v
+		}
+		require.NoError(t, s.Save(ctx, &graph.Checkpoint{ID: id, State: "state", Timestamp: now.Add(-age), Metadata: metadata}))
+	}
+	save("cp-1", "alice-1", 3*time.Hour, nil)
+	save("cp-2", "bob-1", 2*time.Hour, nil)
+	save("cp-3", "carol-1", 48*time.Hour, nil)
+	save("cp-4", "alice-1|agent", time.Hour, map[string]any{"parent_thread_id": "alice-1"})
+
+	ids := func(query store.ThreadQuery) []string {
+		threads, err := s.ListThreads(ctx, query)
+		require.NoError(t, err)
+		ids := []string{}
+		for _, thread := range threads {
+			ids = append(ids, thread.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"alice-1", "bob-1", "carol-1"}, ids(store.ThreadQuery{}))
+	assert.Equal(t, []string{"bob-1", "carol-1"}, ids(store.ThreadQuery{Limit: 2, Offset: 1}))
+	assert.Equal(t, []string{"carol-1"}, ids(store.ThreadQuery{UpdatedBefore: now.Add(-24 * time.Hour)}))
+
+	thread, err := s.GetThread(ctx, "alice-1")
+	require.NoError(t, err)
+	assert.True(t, thread.CreatedAt.Equal(now.Add(-3*time.Hour)), thread.CreatedAt)
+	assert.True(t, thread.UpdatedAt.Equal(now.Add(-time.Hour)), thread.UpdatedAt)
+
+	_, err = s.UpdateThread(ctx, "bob-1", map[string]any{"user_id": "bob", "turns": 2, "title": "Trip"})
+	require.NoError(t, err)
+	thread, err = s.UpdateThread(ctx, "bob-1", map[string]any{"title": nil})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"user_id": "bob", "turns": float64(2)}, thread.Metadata)
+	assert.True(t, thread.CreatedAt.Equal(now.Add(-2*time.Hour)), thread.CreatedAt)
+	assert.Equal(t, []string{"bob-1"}, ids(store.ThreadQuery{Metadata: map[string]any{"user_id": "bob", "turns": 2}}))
+	assert.Empty(t, ids(store.ThreadQuery{Metadata: map[string]any{"user_id": "alice"}}))
+
+	_, err = s.GetThread(ctx, "unknown")
+	assert.ErrorIs(t, err, store.ErrThreadNotFound)
+
+	deleted, err := store.DeleteExpiredThreads(ctx, s, 24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	require.NoError(t, s.DeleteThread(ctx, "alice-1"))
+	for _, id := range []string{"cp-1", "cp-3", "cp-4"} {
+		_, err := s.Load(ctx, id)
+		assert.Error(t, err, id)
+	}
+	assert.Equal(t, []string{"bob-1"}, ids(store.ThreadQuery{}))
+}
+
+func TestSqliteCheckpointStore_RecordsExistingThreads(t *testing.T) {
+	s, err := NewSqliteCheckpointStore(SqliteOptions{Path: filepath.Join(t.TempDir(), "checkpoints.db")})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+	created := time.Now().Add(-time.Hour)
+	for i, threadID := range []string{"thread-1", "thread-1", "thread-2"} {
+		require.NoError(t, s.Save(ctx, &graph.Checkpoint{
+			ID:        fmt.Sprintf("cp-%d", i),
+			State:     "state",
+			Timestamp: created.Add(time.Duration(i) * time.Minute),
+			Metadata:  map[string]any{"thread_id": threadID},
+		}))
+	}
+
+	// A database created before threads were recorded
+	_, err = s.db.ExecContext(ctx, "DROP TABLE checkpoints_threads")
+	require.NoError(t, err)
+	require.NoError(t, s.InitSchema(ctx))
+
+	thread, err := s.GetThread(ctx, "thread-1")
+	require.NoError(t, err)
+	assert.True(t, thread.CreatedAt.Equal(created), thread.CreatedAt)
+	assert.True(t, thread.UpdatedAt.Equal(created.Add(time.Minute)), thread.UpdatedAt)
+
+	threads, err := s.ListThreads(ctx, store.ThreadQuery{})
+	require.NoError(t, err)
+	assert.Len(t, threads, 2)
+}
+
+func TestSqliteCheckpointStore_ListCheckpointIDs(t *testing.T) {
+	s, err := NewSqliteCheckpointStore(SqliteOptions{Path: ":memory:"})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+	for _, cp := range []*graph.Checkpoint{
+		{ID: "cp-1", State: "state", Metadata: map[string]any{"thread_id": "thread-1"}},
+		{ID: "cp-2", State: "state", Metadata: map[string]any{"thread_id": "thread-1|sub", "parent_thread_id": "thread-1"}},
+		{ID: "cp-3", State: "state", Metadata: map[string]any{"execution_id": "exec-1"}},
+	} {
+		require.NoError(t, s.Save(ctx, cp))
+	}
+
+	ids, err := s.ListCheckpointIDs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cp-1", "cp-2", "cp-3"}, ids)
+}
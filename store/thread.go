@@ -0,0 +1,178 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// ErrThreadNotFound is returned for threads that have neither checkpoints nor
+// metadata in a store
+var ErrThreadNotFound = errors.New("thread not found")
+
+// ErrThreadsNotSupported is returned by store wrappers implementing ThreadStore
+// when the store they wrap does not
+var ErrThreadsNotSupported = errors.New("store does not support threads")
+
+// Thread is a conversation or session: the checkpoints saved with the same
+// "thread_id" metadata. The checkpoints of the subgraphs of a thread, saved
+// with its ID as "parent_thread_id", belong to it as well.
+type Thread struct {
+	ID string `json:"id"`
+	// Metadata describes the thread, e.g. the user it belongs to, its title or status
+	Metadata map[string]any `json:"metadata"`
+	// CreatedAt is when the first checkpoint or metadata of the thread was saved
+	CreatedAt time.Time `json:"created_at"`
+	// UpdatedAt is when a checkpoint of the thread was last saved, or its
+	// metadata last updated
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ThreadQuery selects threads. Threads are listed by UpdatedAt, most recent first.
+type ThreadQuery struct {
+	// Metadata keeps the threads whose metadata holds every key/value pair
+	Metadata map[string]any
+	// UpdatedBefore keeps the threads last updated before this time, if not zero
+	UpdatedBefore time.Time
+	// Limit is the maximum number of threads returned, all when zero
+	Limit int
+	// Offset is the number of threads skipped, to get the next page
+	Offset int
+}
+
+// ThreadStore is implemented by checkpoint stores that keep track of their
+// threads, to list and search them, attach metadata and delete them.
+type ThreadStore interface {
+	// ListThreads returns the threads matching query
+	ListThreads(ctx context.Context, query ThreadQuery) ([]*Thread, error)
+
+	// GetThread returns a thread, or an error wrapping ErrThreadNotFound
+	GetThread(ctx context.Context, threadID string) (*Thread, error)
+
+	// UpdateThread merges metadata into the metadata of a thread, creating the
+	// thread if needed, and returns it. Keys set to nil are removed.
+	UpdateThread(ctx context.Context, threadID string, metadata map[string]any) (*Thread, error)
+
+	// DeleteThread removes a thread, its checkpoints and the checkpoints of
+	// its subgraphs
+	DeleteThread(ctx context.Context, threadID string) error
+}
+
+// DeleteExpiredThreads removes the threads of a store that were not updated
+// for ttl, with their checkpoints, and returns the number of threads removed.
+// Call it periodically to expire idle threads.
+func DeleteExpiredThreads(ctx context.Context, s ThreadStore, ttl time.Duration) (int, error) {
+	expired, err := s.ListThreads(ctx, ThreadQuery{UpdatedBefore: time.Now().Add(-ttl)})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired threads: %w", err)
+	}
+	for i, thread := range expired {
+		if err := s.DeleteThread(ctx, thread.ID); err != nil {
+			return i, fmt.Errorf("failed to delete thread %s: %w", thread.ID, err)
+		}
+	}
+	return len(expired), nil
+}
+
+// CheckpointThreadID returns the thread a checkpoint belongs to: the thread
+// of the graph that ran its subgraph, or its own thread, if any.
+func CheckpointThreadID(checkpoint *Checkpoint) string {
+	if id, ok := checkpoint.Metadata["parent_thread_id"].(string); ok && id != "" {
+		return id
+	}
+	id, _ := checkpoint.Metadata["thread_id"].(string)
+	return id
+}
+
+// Touch records that a checkpoint saved at updatedAt belongs to the thread
+func (t *Thread) Touch(updatedAt time.Time) {
+	if t.CreatedAt.IsZero() || updatedAt.Before(t.CreatedAt) {
+		t.CreatedAt = updatedAt
+	}
+	if updatedAt.After(t.UpdatedAt) {
+		t.UpdatedAt = updatedAt
+	}
+}
+
+// MergeMetadata merges metadata into the metadata of the thread, removing the
+// keys set to nil, and updates the thread at updatedAt
+func (t *Thread) MergeMetadata(metadata map[string]any, updatedAt time.Time) {
+	if t.Metadata == nil {
+		t.Metadata = make(map[string]any, len(metadata))
+	}
+	for key, value := range metadata {
+		if value == nil {
+			delete(t.Metadata, key)
+		} else {
+			t.Metadata[key] = value
+		}
+	}
+	t.Touch(updatedAt)
+}
+
+// Clone returns a copy of the thread, with its own metadata map
+func (t *Thread) Clone() *Thread {
+	clone := *t
+	clone.Metadata = maps.Clone(t.Metadata)
+	if clone.Metadata == nil {
+		clone.Metadata = map[string]any{}
+	}
+	return &clone
+}
+
+// Matches reports whether a thread is selected by the query, regardless of
+// Limit and Offset. Metadata values are compared as JSON values, so that
+// numbers match whether they were decoded from JSON or not.
+func (q ThreadQuery) Matches(thread *Thread) bool {
+	if !q.UpdatedBefore.IsZero() && !thread.UpdatedAt.Before(q.UpdatedBefore) {
+		return false
+	}
+	for key, want := range q.Metadata {
+		got, ok := thread.Metadata[key]
+		if !ok || !reflect.DeepEqual(jsonValue(got), jsonValue(want)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the threads selected by the query, most recently updated
+// first, within Limit and Offset
+func (q ThreadQuery) Apply(threads []*Thread) []*Thread {
+	selected := slices.DeleteFunc(slices.Clone(threads), func(t *Thread) bool {
+		return !q.Matches(t)
+	})
+	slices.SortStableFunc(selected, func(a, b *Thread) int {
+		if c := b.UpdatedAt.Compare(a.UpdatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	if q.Offset > 0 {
+		selected = selected[min(q.Offset, len(selected)):]
+	}
+	if q.Limit > 0 && len(selected) > q.Limit {
+		selected = selected[:q.Limit]
+	}
+	return selected
+}
+
+// jsonValue returns value as it decodes from JSON
+func jsonValue(value any) any {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var decoded any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return value
+	}
+	return decoded
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThread_MergeMetadata(t *testing.T) {
+	created := time.Now().Add(-time.Hour)
+	thread := &Thread{ID: "thread-1"}
+	thread.Touch(created)
+	assert.Equal(t, created, thread.CreatedAt)
+
+	thread.MergeMetadata(map[string]any{"user_id": "alice", "title": "Trip"}, created.Add(time.Minute))
+	thread.MergeMetadata(map[string]any{"title": nil, "status": "open"}, created.Add(2*time.Minute))
+	assert.Equal(t, map[string]any{"user_id": "alice", "status": "open"}, thread.Metadata)
+	assert.Equal(t, created, thread.CreatedAt)
+	assert.Equal(t, created.Add(2*time.Minute), thread.UpdatedAt)
+
+	// Older checkpoints do not move the thread back
+	thread.Touch(created.Add(time.Minute))
+	assert.Equal(t, created.Add(2*time.Minute), thread.UpdatedAt)
+
+	clone := thread.Clone()
+	clone.Metadata["status"] = "closed"
+	assert.Equal(t, "open", thread.Metadata["status"])
+}
+
+func TestThreadQuery_Apply(t *testing.T) {
+	now := time.Now()
+	threads := []*Thread{
+		{ID: "a", Metadata: map[string]any{"user_id": "alice", "turns": float64(3)}, UpdatedAt: now.Add(-3 * time.Hour)},
+		{ID: "b", Metadata: map[string]any{"user_id": "bob"}, UpdatedAt: now.Add(-time.Hour)},
+		{ID: "c", Metadata: map[string]any{"user_id": "alice"}, UpdatedAt: now.Add(-2 * time.Hour)},
+		{ID: "d", Metadata: map[string]any{"user_id": "alice"}, UpdatedAt: now},
+	}
+
+	ids := func(threads []*Thread) []string {
+		var ids []string
+		for _, thread := range threads {
+			ids = append(ids, thread.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"d", "b", "c", "a"}, ids(ThreadQuery{}.Apply(threads)))
+	assert.Equal(t, []string{"d", "c", "a"}, ids(ThreadQuery{Metadata: map[string]any{"user_id": "alice"}}.Apply(threads)))
+	assert.Equal(t, []string{"c", "a"}, ids(ThreadQuery{Metadata: map[string]any{"user_id": "alice"}, Offset: 1}.Apply(threads)))
+	assert.Equal(t, []string{"b", "c"}, ids(ThreadQuery{Limit: 2, Offset: 1}.Apply(threads)))
+	assert.Empty(t, ThreadQuery{Offset: 10}.Apply(threads))
+	assert.Equal(t, []string{"c", "a"}, ids(ThreadQuery{UpdatedBefore: now.Add(-90 * time.Minute)}.Apply(threads)))
+
+	// Numbers match whether they were decoded from JSON or not
+	assert.Equal(t, []string{"a"}, ids(ThreadQuery{Metadata: map[string]any{"turns": 3}}.Apply(threads)))
+}
@@ -16,15 +16,23 @@ type TypeRegistry struct {
 	typeCreators      map[string]func() any
 	jsonMarshallers   map[reflect.Type]func(any) ([]byte, error)
 	jsonUnmarshallers map[reflect.Type]func([]byte, any) (any, error)
+	migrations        map[string][]MigrationFunc
 }
 
 // globalTypeRegistry is the singleton instance of TypeRegistry
-var globalTypeRegistry = &TypeRegistry{
-	typeNameToType:    make(map[string]reflect.Type),
-	typeToName:        make(map[reflect.Type]string),
-	typeCreators:      make(map[string]func() any),
-	jsonMarshallers:   make(map[reflect.Type]func(any) ([]byte, error)),
-	jsonUnmarshallers: make(map[reflect.Type]func([]byte, any) (any, error)),
+var globalTypeRegistry = NewTypeRegistry()
+
+// NewTypeRegistry creates an empty type registry, for serializers that should
+// not use the global one.
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		typeNameToType:    make(map[string]reflect.Type),
+		typeToName:        make(map[reflect.Type]string),
+		typeCreators:      make(map[string]func() any),
+		jsonMarshallers:   make(map[reflect.Type]func(any) ([]byte, error)),
+		jsonUnmarshallers: make(map[reflect.Type]func([]byte, any) (any, error)),
+		migrations:        make(map[string][]MigrationFunc),
+	}
 }
 
 // GlobalTypeRegistry returns the global type registry instance
@@ -189,11 +197,15 @@ func (r *TypeRegistry) Marshal(value any) ([]byte, error) {
 		return nil, err
 	}
 
-	// Wrap with type information
+	// Wrap with type information, and the schema version of types that have
+	// migrations
 	wrapped := map[string]any{
 		"_type":  typeName,
 		"_value": json.RawMessage(jsonData),
 	}
+	if version := r.SchemaVersion(typeName); version > 1 {
+		wrapped["_version"] = version
+	}
 
 	return json.Marshal(wrapped)
 }
@@ -230,6 +242,21 @@ func (r *TypeRegistry) Unmarshal(data []byte) (any, error) {
 			return nil, err
 		}
 
+		// Values saved with an older schema version are migrated first
+		version := 1
+		if versionBytes, ok := wrapped["_version"]; ok {
+			if err := json.Unmarshal(versionBytes, &version); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal schema version: %w", err)
+			}
+		}
+		if version < r.SchemaVersion(typeName) {
+			var state map[string]any
+			if err := json.Unmarshal(wrapped["_value"], &state); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal value: %w", err)
+			}
+			return r.migrateValue(typeName, version, state)
+		}
+
 		r.mu.RLock()
 		unmarshalFunc, hasCustomUnmarshaler := r.jsonUnmarshallers[t]
 		r.mu.RUnlock()
@@ -249,23 +276,15 @@ func (r *TypeRegistry) Unmarshal(data []byte) (any, error) {
 			return nil, fmt.Errorf("missing _value in wrapped data")
 		}
 
-		// For struct types, we need to unmarshal into a pointer
-		// CreateInstance returns a value, so we need to get a pointer to it
-		if t.Kind() == reflect.Struct {
-			// Create a pointer to the value
-			ptr := reflect.New(t).Interface()
-			if err := json.Unmarshal(valueBytes, ptr); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal value: %w", err)
-			}
-			// Dereference to get the value
-			return reflect.ValueOf(ptr).Elem().Interface(), nil
-		}
-
-		if err := json.Unmarshal(valueBytes, instance); err != nil {
+		// Unmarshal into a pointer to a new value of the type
+		value, err := decodeValue(t, func(ptr any) error {
+			return json.Unmarshal(valueBytes, ptr)
+		})
+		if err != nil {
 			return nil, fmt.Errorf("failed to unmarshal value: %w", err)
 		}
 
-		return instance, nil
+		return value, nil
 	}
 
 	// Not a typed wrapper, return as-is
@@ -383,21 +402,13 @@ func (cd *CheckpointData) ToValue() (any, error) {
 		return unmarshalFunc(cd.Data, instance)
 	}
 
-	// Use standard JSON unmarshaling
-	// For struct types, we need to unmarshal into a pointer
-	if t.Kind() == reflect.Struct {
-		// Create a pointer to the value
-		ptr := reflect.New(t).Interface()
-		if err := json.Unmarshal(cd.Data, ptr); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal value: %w", err)
-		}
-		// Dereference to get the value
-		return reflect.ValueOf(ptr).Elem().Interface(), nil
-	}
-
-	if err := json.Unmarshal(cd.Data, instance); err != nil {
+	// Use standard JSON unmarshaling into a pointer to a new value of the type
+	value, err := decodeValue(t, func(ptr any) error {
+		return json.Unmarshal(cd.Data, ptr)
+	})
+	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal value: %w", err)
 	}
 
-	return instance, nil
+	return value, nil
 }